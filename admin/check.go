@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// checkIssue is an inconsistency found in an inventory-item.
+type checkIssue struct {
+	ItemID  string
+	Message string
+}
+
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	fs.Parse(args)
+
+	coll, err := loadAggCollection()
+	if err != nil {
+		err = errors.Wrap(err, "Check")
+		return err
+	}
+	findResults, err := coll.Find(map[string]interface{}{
+		"itemID": map[string]interface{}{
+			"$exists": true,
+		},
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Check: Error getting items from database")
+		return err
+	}
+
+	issues := []checkIssue{}
+	seenItems := map[uuuid.UUID]bool{}
+	for _, r := range findResults {
+		inv, assertOK := r.(*inventory.Inventory)
+		if !assertOK {
+			err = errors.New("error asserting database-result to Inventory-Item")
+			err = errors.Wrap(err, "Check")
+			return err
+		}
+		if seenItems[inv.ItemID] {
+			issues = append(issues, checkIssue{
+				ItemID:  inv.ItemID.String(),
+				Message: "duplicate itemID",
+			})
+		}
+		seenItems[inv.ItemID] = true
+		issues = append(issues, checkItem(inv)...)
+	}
+
+	for _, i := range issues {
+		fmt.Printf("%s: %s\n", i.ItemID, i.Message)
+	}
+	fmt.Printf("Checked %d items, found %d issues\n", len(findResults), len(issues))
+	if len(issues) > 0 {
+		return errors.New("consistency-check failed")
+	}
+	return nil
+}
+
+// checkItem returns the consistency-issues in the inventory-item.
+func checkItem(inv *inventory.Inventory) []checkIssue {
+	itemID := inv.ItemID.String()
+	issues := []checkIssue{}
+	addIssue := func(format string, args ...interface{}) {
+		issues = append(issues, checkIssue{
+			ItemID:  itemID,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if inv.ItemID == (uuuid.UUID{}) {
+		addIssue("missing itemID (ObjectID: %s)", inv.ID.Hex())
+	}
+	if inv.RSCustomerID == (uuuid.UUID{}) {
+		addIssue("missing rsCustomerID")
+	}
+
+	weights := []struct {
+		Field  string
+		Weight inventory.Decimal
+	}{
+		{"totalWeight", inv.TotalWeight},
+		{"soldWeight", inv.SoldWeight},
+		{"wasteWeight", inv.WasteWeight},
+		{"donateWeight", inv.DonateWeight},
+		{"flashSaleWeight", inv.FlashSaleWeight},
+	}
+	for _, w := range weights {
+		if w.Weight.IsNegative() {
+			addIssue("negative %s: %s", w.Field, w.Weight)
+		}
+	}
+
+	usedWeight, err := inv.SoldWeight.Add(inv.WasteWeight)
+	if err == nil {
+		usedWeight, err = usedWeight.Add(inv.DonateWeight)
+	}
+	if err != nil {
+		addIssue("sold, waste, and donate weights: %s", err)
+	} else if usedWeight.GreaterThan(inv.TotalWeight) {
+		addIssue(
+			"sold, waste, and donate weights (%s) exceed totalWeight (%s)",
+			usedWeight, inv.TotalWeight,
+		)
+	}
+	if inv.FlashSaleWeight.GreaterThan(inv.TotalWeight) {
+		addIssue(
+			"flashSaleWeight (%s) exceeds totalWeight (%s)",
+			inv.FlashSaleWeight, inv.TotalWeight,
+		)
+	}
+	if inv.DateSold != 0 && inv.DateSold < inv.DateArrived {
+		addIssue("dateSold is before dateArrived")
+	}
+	return issues
+}
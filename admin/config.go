@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+
+	"github.com/TerrexTech/agg-inventory-cmd/config"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+func loadAggCollection() (*mongo.Collection, error) {
+	conn, err := config.MongoConnection()
+	if err != nil {
+		err = errors.Wrap(err, "Error creating MongoConnection")
+		return nil, err
+	}
+	return config.AggCollection(
+		conn, os.Getenv("MONGO_DATABASE"), os.Getenv("MONGO_AGG_COLLECTION"), nil,
+	)
+}
+
+// loadRedriveLog ensures the collection recording re-driven dead-letters,
+// with a unique index on the dead-lettered event's UUID.
+func loadRedriveLog() (*mongo.Collection, error) {
+	conn, err := config.MongoConnection()
+	if err != nil {
+		err = errors.Wrap(err, "Error creating MongoConnection")
+		return nil, err
+	}
+	collName := os.Getenv("MONGO_REDRIVE_COLLECTION")
+	if collName == "" {
+		collName = os.Getenv("MONGO_AGG_COLLECTION") + "_redrives"
+	}
+	c := &mongo.Collection{
+		Connection:   conn,
+		Database:     os.Getenv("MONGO_DATABASE"),
+		Name:         collName,
+		SchemaStruct: &redriveRecord{},
+		Indexes: []mongo.IndexConfig{
+			mongo.IndexConfig{
+				ColumnConfig: []mongo.IndexColumnConfig{
+					mongo.IndexColumnConfig{
+						Name: "eventUUID",
+					},
+				},
+				IsUnique: true,
+				Name:     "eventUUID_index",
+			},
+		},
+	}
+	collection, err := mongo.EnsureCollection(c)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating redrive-log collection")
+		return nil, err
+	}
+	return collection, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TerrexTech/agg-inventory-cmd/config"
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// itemLock is an etcd-lock held on an inventory-item.
+type itemLock struct {
+	ItemID string
+	Key    string
+	Lease  clientv3.LeaseID
+	TTL    int64
+}
+
+func runLocks(args []string) error {
+	fs := flag.NewFlagSet("locks", flag.ExitOnError)
+	release := fs.String("release", "", "Force-release all locks on specified ItemID")
+	fs.Parse(args)
+
+	etcd, err := config.EtcdClient()
+	if err != nil {
+		err = errors.Wrap(err, "Locks")
+		return err
+	}
+	defer etcd.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if *release != "" {
+		itemID, err := uuuid.FromString(*release)
+		if err != nil {
+			err = errors.Wrap(err, "Locks: Error parsing ItemID")
+			return err
+		}
+		return releaseLocks(ctx, etcd, itemID.String())
+	}
+	return listLocks(ctx, etcd)
+}
+
+// findLocks returns locks on inventory-items. Lock-keys are formed as
+// "/<itemID>/<leaseID>" by etcd's concurrency.Mutex.
+func findLocks(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	prefix string,
+) ([]itemLock, error) {
+	resp, err := etcd.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		err = errors.Wrap(err, "Error getting lock-keys")
+		return nil, err
+	}
+
+	locks := []itemLock{}
+	for _, kv := range resp.Kvs {
+		key := string(kv.Key)
+		parts := strings.SplitN(strings.TrimPrefix(key, "/"), "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		_, err := uuuid.FromString(parts[0])
+		if err != nil {
+			continue
+		}
+
+		lock := itemLock{
+			ItemID: parts[0],
+			Key:    key,
+			Lease:  clientv3.LeaseID(kv.Lease),
+		}
+		if kv.Lease != 0 {
+			ttlResp, err := etcd.TimeToLive(ctx, lock.Lease)
+			if err == nil {
+				lock.TTL = ttlResp.TTL
+			}
+		}
+		locks = append(locks, lock)
+	}
+	return locks, nil
+}
+
+func listLocks(ctx context.Context, etcd *clientv3.Client) error {
+	locks, err := findLocks(ctx, etcd, "/")
+	if err != nil {
+		err = errors.Wrap(err, "Locks")
+		return err
+	}
+	if len(locks) == 0 {
+		fmt.Println("No item-locks held")
+		return nil
+	}
+
+	fmt.Printf("%-36s  %-16s  %s\n", "ITEM-ID", "LEASE", "TTL")
+	for _, l := range locks {
+		fmt.Printf("%-36s  %-16x  %ds\n", l.ItemID, l.Lease, l.TTL)
+	}
+	return nil
+}
+
+func releaseLocks(ctx context.Context, etcd *clientv3.Client, itemID string) error {
+	prefix := inventory.ItemLockPrefix(itemID)
+	locks, err := findLocks(ctx, etcd, prefix)
+	if err != nil {
+		err = errors.Wrap(err, "Locks")
+		return err
+	}
+	if len(locks) == 0 {
+		fmt.Printf("No locks held on ItemID: %s\n", itemID)
+		return nil
+	}
+
+	for _, l := range locks {
+		// Revoking the lease also ends the lock-holder's session
+		if l.Lease != 0 {
+			_, err = etcd.Revoke(ctx, l.Lease)
+			if err != nil {
+				err = errors.Wrapf(err, "Locks: Error revoking lease %x", l.Lease)
+				return err
+			}
+		}
+		_, err = etcd.Delete(ctx, l.Key)
+		if err != nil {
+			err = errors.Wrapf(err, "Locks: Error deleting lock-key %s", l.Key)
+			return err
+		}
+		fmt.Printf("Released lock %s\n", l.Key)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/pkg/errors"
+)
+
+// command is an admin-subcommand.
+type command struct {
+	Name  string
+	Usage string
+	Run   func(args []string) error
+}
+
+var commands = []command{
+	command{
+		Name:  "show",
+		Usage: "Show an inventory-item by itemID",
+		Run:   runShow,
+	},
+	command{
+		Name:  "publish",
+		Usage: "Publish an insert, update, sale, or delete command-event",
+		Run:   runPublish,
+	},
+	command{
+		Name:  "locks",
+		Usage: "List or force-release etcd item-locks",
+		Run:   runLocks,
+	},
+	command{
+		Name:  "check",
+		Usage: "Run consistency-checks on inventory-items",
+		Run:   runCheck,
+	},
+	command{
+		Name:  "redrive",
+		Usage: "Re-drive events from dead-letter topic",
+		Run:   runRedrive,
+	},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: admin <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.Name, c.Usage)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, `Use "admin <command> -h" for more information about a command.`)
+}
+
+func main() {
+	log.SetOutput(os.Stderr)
+	err := godotenv.Load("./.env")
+	if err != nil {
+		err = errors.Wrap(err,
+			".env file not found, env-vars will be read as set in environment",
+		)
+		log.Println(err)
+	}
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	for _, c := range commands {
+		if c.Name == name {
+			err = c.Run(os.Args[2:])
+			if err != nil {
+				log.Fatalln(err)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", name)
+	usage()
+	os.Exit(2)
+}
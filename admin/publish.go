@@ -0,0 +1,631 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-kafkautils/kafka"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// stringList is a flag that can be specified multiple times.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// publishArgs are the arguments for building a command-event.
+type publishArgs struct {
+	Action   string
+	Data     string
+	Filter   string
+	Update   string
+	ItemID   string
+	Lines    stringList
+	UserUUID string
+
+	ToLocation string
+	Weight     string
+	Unit       string
+
+	SaleID  string
+	ToWaste bool
+
+	ReservationID string
+	TTLSec        int64
+	Partial       bool
+}
+
+func runPublish(args []string) error {
+	pa := &publishArgs{}
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	fs.StringVar(
+		&pa.Action, "action", "",
+		"Command: insert, update, sale, flashsale, waste, donation, "+
+			"cancel, return, reserve, release, transfer, or delete",
+	)
+	fs.StringVar(&pa.Data, "data", "", "Inventory JSON-file for insert (- for stdin)")
+	fs.StringVar(&pa.Filter, "filter", "", "JSON filter for update or delete")
+	fs.StringVar(&pa.Update, "update", "", "JSON update for update")
+	fs.StringVar(&pa.ItemID, "item", "", "ItemID for delete or transfer")
+	fs.Var(
+		&pa.Lines, "line",
+		"Item-line as itemID:weight[:unit] for sale, waste, donation, "+
+			"return, reserve, release (repeatable)",
+	)
+	fs.StringVar(&pa.ToLocation, "to", "", "Location to transfer the item to")
+	fs.StringVar(
+		&pa.Weight, "weight", "",
+		"Weight to transfer (default: all remaining weight)",
+	)
+	fs.StringVar(&pa.Unit, "unit", "", "Unit of transfer-weight (default: item's unit)")
+	fs.StringVar(&pa.SaleID, "sale", "", "CorrelationID of the sale to cancel or return")
+	fs.BoolVar(
+		&pa.ToWaste, "to-waste", false, "Add cancelled or returned weight to waste",
+	)
+	fs.StringVar(
+		&pa.ReservationID, "reservation", "",
+		"ReservationID to reserve, release, or consume by sale "+
+			"(default for reserve: CorrelationID)",
+	)
+	fs.Int64Var(
+		&pa.TTLSec, "ttl", 0,
+		"Seconds to hold the reservation (default: RESERVATION_TTL_SEC)",
+	)
+	fs.BoolVar(
+		&pa.Partial, "partial", false,
+		"Sell the available weight of sale-lines that exceed it",
+	)
+	fs.StringVar(&pa.UserUUID, "user", "", "UserUUID to publish the event as")
+	wait := fs.Bool("wait", true, "Wait for the response-Document")
+	timeout := fs.Duration("timeout", 30*time.Second, "Time to wait for response")
+	fs.Parse(args)
+
+	event, err := buildEvent(pa)
+	if err != nil {
+		err = errors.Wrap(err, "Publish")
+		return err
+	}
+
+	kafkaBrokers := *commonutil.ParseHosts(
+		os.Getenv("KAFKA_BROKERS"),
+	)
+	eventTopic := os.Getenv("KAFKA_PRODUCER_EVENT_TOPIC")
+	responseTopic := os.Getenv("KAFKA_PRODUCER_RESPONSE_TOPIC")
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	docChan := make(chan *model.Document, 1)
+	if *wait {
+		err = waitForResponse(ctx, kafkaBrokers, responseTopic, event.UUID, docChan)
+		if err != nil {
+			err = errors.Wrap(err, "Publish")
+			return err
+		}
+	}
+
+	producer, err := kafka.NewProducer(&kafka.ProducerConfig{
+		KafkaBrokers: kafkaBrokers,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Publish: Error creating producer")
+		return err
+	}
+	defer producer.Close()
+
+	marshalEvent, err := json.Marshal(event)
+	if err != nil {
+		err = errors.Wrap(err, "Publish: Error marshalling Event")
+		return err
+	}
+	producer.Input() <- kafka.CreateMessage(eventTopic, marshalEvent)
+	log.Printf(
+		"Published %s/%s event with UUID: %s, CorrelationID: %s",
+		event.EventAction, event.ServiceAction, event.UUID, event.CorrelationID,
+	)
+
+	if !*wait {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		err = errors.New("timed out waiting for response-Document")
+		err = errors.Wrap(err, "Publish")
+		return err
+	case doc := <-docChan:
+		return printDocument(doc)
+	}
+}
+
+// buildEvent creates a command-event from the provided arguments.
+func buildEvent(pa *publishArgs) (*model.Event, error) {
+	var (
+		data          []byte
+		err           error
+		eventAction   string
+		serviceAction string
+	)
+
+	switch pa.Action {
+	case "insert":
+		eventAction = "insert"
+		data, err = buildInsertData(pa)
+	case "update":
+		eventAction = "update"
+		data, err = buildUpdateData(pa)
+	case "sale", "flashsale":
+		eventAction = "update"
+		serviceAction = "createSale"
+		if pa.Action == "flashsale" {
+			serviceAction = "createFlashSale"
+		}
+		data, err = buildItemLinesData(pa)
+	case "waste", "donation":
+		eventAction = "update"
+		serviceAction = "createWaste"
+		if pa.Action == "donation" {
+			serviceAction = "createDonation"
+		}
+		data, err = buildItemLinesData(pa)
+	case "cancel", "return":
+		eventAction = "update"
+		serviceAction = "cancelSale"
+		if pa.Action == "return" {
+			serviceAction = "returnItems"
+		}
+		data, err = buildReversalData(pa)
+	case "reserve":
+		eventAction = "update"
+		serviceAction = "reserveStock"
+		data, err = buildReservationData(pa)
+	case "release":
+		eventAction = "update"
+		serviceAction = "releaseReservation"
+		data, err = buildReservationData(pa)
+	case "transfer":
+		eventAction = "update"
+		serviceAction = "transferInventory"
+		data, err = buildTransferData(pa)
+	case "delete":
+		eventAction = "delete"
+		data, err = buildDeleteData(pa)
+	default:
+		err = fmt.Errorf("unknown action: %s", pa.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Data is checked against the command-schema before it's sent, as the
+	// service would reject it otherwise
+	err = inventory.CheckCommandSchemas()
+	if err != nil {
+		return nil, err
+	}
+	s := inventory.FindCommandSchema(eventAction, serviceAction)
+	if s != nil {
+		fieldErrs := s.Validate(data)
+		if fieldErrs != nil {
+			err = errors.Wrapf(
+				fieldErrs, "Event-data does not match schema %s", s.FileName(),
+			)
+			return nil, err
+		}
+	}
+
+	cid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating CorrelationID")
+		return nil, err
+	}
+	uuid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating UUID")
+		return nil, err
+	}
+	userUUID := uuuid.UUID{}
+	if pa.UserUUID != "" {
+		userUUID, err = uuuid.FromString(pa.UserUUID)
+		if err != nil {
+			err = errors.Wrap(err, "Error parsing UserUUID")
+			return nil, err
+		}
+	}
+
+	return &model.Event{
+		AggregateID:   inventory.AggregateID,
+		CorrelationID: cid,
+		Data:          data,
+		EventAction:   eventAction,
+		NanoTime:      time.Now().UnixNano(),
+		ServiceAction: serviceAction,
+		UserUUID:      userUUID,
+		UUID:          uuid,
+		YearBucket:    2018,
+	}, nil
+}
+
+func buildInsertData(pa *publishArgs) ([]byte, error) {
+	var (
+		in  []byte
+		err error
+	)
+	switch pa.Data {
+	case "":
+		return nil, errors.New("insert requires -data")
+	case "-":
+		in, err = ioutil.ReadAll(os.Stdin)
+	default:
+		in, err = ioutil.ReadFile(pa.Data)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "Error reading Inventory data")
+		return nil, err
+	}
+
+	// Unmarshal so the data is type-checked before it's sent
+	inv := &inventory.Inventory{}
+	err = json.Unmarshal(in, inv)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling Inventory data")
+		return nil, err
+	}
+	if inv.ItemID == (uuuid.UUID{}) {
+		return nil, errors.New("missing ItemID in Inventory data")
+	}
+	return json.Marshal(inv)
+}
+
+func buildUpdateData(pa *publishArgs) ([]byte, error) {
+	filter := map[string]interface{}{}
+	err := json.Unmarshal([]byte(pa.Filter), &filter)
+	if err != nil {
+		err = errors.Wrap(err, "Error parsing -filter")
+		return nil, err
+	}
+	update := map[string]interface{}{}
+	err = json.Unmarshal([]byte(pa.Update), &update)
+	if err != nil {
+		err = errors.Wrap(err, "Error parsing -update")
+		return nil, err
+	}
+	if len(filter) == 0 || len(update) == 0 {
+		return nil, errors.New("update requires non-empty -filter and -update")
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"filter": filter,
+		"update": update,
+	})
+}
+
+func buildItemLinesData(pa *publishArgs) ([]byte, error) {
+	if len(pa.Lines) == 0 {
+		return nil, errors.New("at least one -line is required")
+	}
+
+	items := []map[string]interface{}{}
+	for _, line := range pa.Lines {
+		parts := strings.Split(line, ":")
+		if len(parts) != 2 && len(parts) != 3 {
+			return nil, fmt.Errorf(
+				"invalid item-line %s, expected itemID:weight[:unit]", line,
+			)
+		}
+		itemID, err := uuuid.FromString(parts[0])
+		if err != nil {
+			err = errors.Wrapf(err, "Error parsing ItemID in item-line %s", line)
+			return nil, err
+		}
+		weight, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			err = errors.Wrapf(err, "Error parsing weight in item-line %s", line)
+			return nil, err
+		}
+		item := map[string]interface{}{
+			"itemID": itemID.String(),
+			"weight": weight,
+		}
+		if len(parts) == 3 {
+			unit, err := inventory.ParseUnit(parts[2])
+			if err != nil {
+				err = errors.Wrapf(err, "Error parsing unit in item-line %s", line)
+				return nil, err
+			}
+			item["unit"] = string(unit)
+		}
+		items = append(items, item)
+	}
+
+	saleID, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating SaleID")
+		return nil, err
+	}
+	linesData := map[string]interface{}{
+		"items":     items,
+		"saleID":    saleID.String(),
+		"timestamp": time.Now().UnixNano(),
+	}
+	if pa.ReservationID != "" {
+		reservationID, err := uuuid.FromString(pa.ReservationID)
+		if err != nil {
+			err = errors.Wrap(err, "Error parsing -reservation")
+			return nil, err
+		}
+		linesData["reservationID"] = reservationID.String()
+	}
+	if pa.Partial {
+		linesData["partialFulfillment"] = true
+	}
+	return json.Marshal(linesData)
+}
+
+func buildReversalData(pa *publishArgs) ([]byte, error) {
+	if pa.SaleID == "" {
+		return nil, errors.New("cancel and return require -sale")
+	}
+	saleCID, err := uuuid.FromString(pa.SaleID)
+	if err != nil {
+		err = errors.Wrap(err, "Error parsing -sale")
+		return nil, err
+	}
+	reversal := map[string]interface{}{
+		"saleCorrelationID": saleCID.String(),
+		"toWaste":           pa.ToWaste,
+	}
+	if pa.Action == "cancel" {
+		return json.Marshal(reversal)
+	}
+
+	// Return-lines have same format as sale-lines
+	linesData, err := buildItemLinesData(pa)
+	if err != nil {
+		return nil, err
+	}
+	lines := map[string]interface{}{}
+	err = json.Unmarshal(linesData, &lines)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling return-lines")
+		return nil, err
+	}
+	reversal["items"] = lines["items"]
+	return json.Marshal(reversal)
+}
+
+func buildReservationData(pa *publishArgs) ([]byte, error) {
+	reservation := map[string]interface{}{}
+	if pa.ReservationID != "" {
+		reservationID, err := uuuid.FromString(pa.ReservationID)
+		if err != nil {
+			err = errors.Wrap(err, "Error parsing -reservation")
+			return nil, err
+		}
+		reservation["reservationID"] = reservationID.String()
+	}
+	if pa.Action == "release" {
+		if pa.ReservationID == "" {
+			return nil, errors.New("release requires -reservation")
+		}
+		// Releases all items of reservation if no lines are specified
+		items := []map[string]interface{}{}
+		for _, line := range pa.Lines {
+			itemID, err := uuuid.FromString(strings.Split(line, ":")[0])
+			if err != nil {
+				err = errors.Wrapf(err, "Error parsing ItemID in item-line %s", line)
+				return nil, err
+			}
+			items = append(items, map[string]interface{}{
+				"itemID": itemID.String(),
+			})
+		}
+		if len(items) > 0 {
+			reservation["items"] = items
+		}
+		return json.Marshal(reservation)
+	}
+
+	// Reserve-lines have same format as sale-lines
+	linesData, err := buildItemLinesData(pa)
+	if err != nil {
+		return nil, err
+	}
+	lines := map[string]interface{}{}
+	err = json.Unmarshal(linesData, &lines)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling reserve-lines")
+		return nil, err
+	}
+	reservation["items"] = lines["items"]
+	if pa.TTLSec != 0 {
+		reservation["ttlSec"] = pa.TTLSec
+	}
+	return json.Marshal(reservation)
+}
+
+func buildTransferData(pa *publishArgs) ([]byte, error) {
+	if pa.ItemID == "" || pa.ToLocation == "" {
+		return nil, errors.New("transfer requires -item and -to")
+	}
+	itemID, err := uuuid.FromString(pa.ItemID)
+	if err != nil {
+		err = errors.Wrap(err, "Error parsing ItemID")
+		return nil, err
+	}
+
+	transfer := map[string]interface{}{
+		"itemID":     itemID.String(),
+		"toLocation": pa.ToLocation,
+	}
+	if pa.Weight != "" {
+		weight, err := inventory.ParseDecimal(pa.Weight)
+		if err != nil {
+			err = errors.Wrap(err, "Error parsing -weight")
+			return nil, err
+		}
+		transfer["weight"] = weight
+	}
+	if pa.Unit != "" {
+		unit, err := inventory.ParseUnit(pa.Unit)
+		if err != nil {
+			err = errors.Wrap(err, "Error parsing -unit")
+			return nil, err
+		}
+		transfer["unit"] = string(unit)
+	}
+	return json.Marshal(transfer)
+}
+
+func buildDeleteData(pa *publishArgs) ([]byte, error) {
+	if pa.ItemID != "" {
+		itemID, err := uuuid.FromString(pa.ItemID)
+		if err != nil {
+			err = errors.Wrap(err, "Error parsing ItemID")
+			return nil, err
+		}
+		return json.Marshal(map[string]interface{}{
+			"itemID": itemID.String(),
+		})
+	}
+
+	filter := map[string]interface{}{}
+	err := json.Unmarshal([]byte(pa.Filter), &filter)
+	if err != nil {
+		err = errors.Wrap(err, "Error parsing -filter")
+		return nil, err
+	}
+	if len(filter) == 0 {
+		return nil, errors.New("delete requires -item or a non-empty -filter")
+	}
+	return json.Marshal(filter)
+}
+
+// responseHandler looks for the response-Document for the published event.
+type responseHandler struct {
+	eventUUID uuuid.UUID
+	docChan   chan<- *model.Document
+	readyChan chan<- struct{}
+}
+
+func (h *responseHandler) Setup(sarama.ConsumerGroupSession) error {
+	select {
+	case h.readyChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (*responseHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *responseHandler) ConsumeClaim(
+	session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim,
+) error {
+	for msg := range claim.Messages() {
+		session.MarkMessage(msg, "")
+
+		doc := &model.Document{}
+		err := json.Unmarshal(msg.Value, doc)
+		if err != nil {
+			continue
+		}
+		if doc.UUID == h.eventUUID {
+			h.docChan <- doc
+			return nil
+		}
+	}
+	return nil
+}
+
+// waitForResponse starts consuming the response-topic, and returns once the
+// consumer is ready. The matching response-Document is sent on docChan.
+func waitForResponse(
+	ctx context.Context,
+	kafkaBrokers []string,
+	topic string,
+	eventUUID uuuid.UUID,
+	docChan chan<- *model.Document,
+) error {
+	groupID, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating consumer-group ID")
+		return err
+	}
+	consumer, err := kafka.NewConsumer(&kafka.ConsumerConfig{
+		KafkaBrokers: kafkaBrokers,
+		GroupName:    fmt.Sprintf("agg.inventory.admin.%s", groupID),
+		Topics:       []string{topic},
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error creating response-consumer")
+		return err
+	}
+
+	readyChan := make(chan struct{}, 1)
+	handler := &responseHandler{
+		eventUUID: eventUUID,
+		docChan:   docChan,
+		readyChan: readyChan,
+	}
+	go func() {
+		defer consumer.Close()
+		err := consumer.Consume(ctx, handler)
+		if err != nil && ctx.Err() == nil {
+			err = errors.Wrap(err, "Error consuming response-Documents")
+			log.Println(err)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return errors.New("timed out waiting for response-consumer")
+	case <-readyChan:
+		return nil
+	}
+}
+
+func printDocument(doc *model.Document) error {
+	out := map[string]interface{}{
+		"aggregateID":   doc.AggregateID,
+		"correlationID": doc.CorrelationID.String(),
+		"error":         doc.Error,
+		"errorCode":     doc.ErrorCode,
+		"eventAction":   doc.EventAction,
+		"serviceAction": doc.ServiceAction,
+		"uuid":          doc.UUID.String(),
+	}
+	if len(doc.Result) > 0 {
+		out["result"] = json.RawMessage(doc.Result)
+	}
+
+	marshalOut, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling response-Document")
+		return err
+	}
+	fmt.Println(string(marshalOut))
+	if doc.Error != "" {
+		return fmt.Errorf("command failed with error-code %d", doc.ErrorCode)
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-kafkautils/kafka"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// redriveArgs are the arguments for re-driving dead-lettered events.
+type redriveArgs struct {
+	CorrelationID string
+	Reason        string
+	DryRun        bool
+	Max           int
+}
+
+func runRedrive(args []string) error {
+	ra := &redriveArgs{}
+	fs := flag.NewFlagSet("redrive", flag.ExitOnError)
+	topic := fs.String(
+		"topic", os.Getenv("KAFKA_PRODUCER_DEAD_LETTER_TOPIC"), "Dead-letter topic",
+	)
+	group := fs.String(
+		"group", "agg.inventory.admin.redrive",
+		"Consumer-group committing the progress of re-drives "+
+			"(not used with -correlation, -reason, or -dry-run)",
+	)
+	fs.StringVar(
+		&ra.CorrelationID, "correlation", "",
+		"Only re-drive events with this CorrelationID",
+	)
+	fs.StringVar(
+		&ra.Reason, "reason", "",
+		"Only re-drive events with this reason: retriesExhausted or malformed",
+	)
+	fs.BoolVar(&ra.DryRun, "dry-run", false, "Print dead-letters without re-driving")
+	fs.IntVar(&ra.Max, "max", 0, "Maximum events to re-drive (default: no limit)")
+	timeout := fs.Duration(
+		"timeout", 30*time.Second, "Time to read dead-letters before exiting",
+	)
+	fs.Parse(args)
+
+	if *topic == "" {
+		return errors.New("Redrive: -topic or KAFKA_PRODUCER_DEAD_LETTER_TOPIC is required")
+	}
+	kafkaBrokers := *commonutil.ParseHosts(
+		os.Getenv("KAFKA_BROKERS"),
+	)
+	groupName := *group
+	if ra.DryRun || ra.filtered() {
+		// Separate group, so dry-runs don't affect offsets of re-drives.
+		// Filtered re-drives also use a separate group, since offsets are
+		// committed by position, and committing a re-driven dead-letter
+		// would also commit the dead-letters skipped before it. These read
+		// the dead-letters from the start, so the redrive-log is used to
+		// skip the ones already re-driven.
+		groupID, err := uuuid.NewV4()
+		if err != nil {
+			err = errors.Wrap(err, "Redrive: Error generating consumer-group ID")
+			return err
+		}
+		groupName = fmt.Sprintf("%s.%s", *group, groupID)
+	}
+
+	redriveLog, err := loadRedriveLog()
+	if err != nil {
+		err = errors.Wrap(err, "Redrive")
+		return err
+	}
+	producer, err := kafka.NewProducer(&kafka.ProducerConfig{
+		KafkaBrokers: kafkaBrokers,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Redrive: Error creating producer")
+		return err
+	}
+	defer producer.Close()
+	consumer, err := kafka.NewConsumer(&kafka.ConsumerConfig{
+		KafkaBrokers: kafkaBrokers,
+		GroupName:    groupName,
+		Topics:       []string{*topic},
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Redrive: Error creating dead-letter consumer")
+		return err
+	}
+	defer consumer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	handler := &redriveHandler{
+		args:       ra,
+		cancel:     cancel,
+		eventTopic: os.Getenv("KAFKA_PRODUCER_EVENT_TOPIC"),
+		producer:   producer,
+		redriveLog: redriveLog,
+	}
+	err = consumer.Consume(ctx, handler)
+	if err != nil && ctx.Err() == nil {
+		err = errors.Wrap(err, "Redrive: Error consuming dead-letters")
+		return err
+	}
+	log.Printf("Re-drove %d events, skipped %d", handler.redriven, handler.skipped)
+	if handler.err != nil {
+		return handler.err
+	}
+	return nil
+}
+
+// redriveRecord records a re-driven dead-letter by the UUID of the
+// dead-lettered event, so it's not re-driven again.
+type redriveRecord struct {
+	EventUUID     string `bson:"eventUUID" json:"eventUUID"`
+	CorrelationID string `bson:"correlationID" json:"correlationID"`
+	RedrivenAs    string `bson:"redrivenAs" json:"redrivenAs"`
+	Timestamp     int64  `bson:"timestamp" json:"timestamp"`
+}
+
+// redriveHandler republishes the events from dead-letters as new command-events.
+type redriveHandler struct {
+	args       *redriveArgs
+	cancel     context.CancelFunc
+	eventTopic string
+	producer   *kafka.Producer
+	redriveLog *mongo.Collection
+
+	err      error
+	redriven int
+	skipped  int
+}
+
+func (*redriveHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (*redriveHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *redriveHandler) ConsumeClaim(
+	session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim,
+) error {
+	for msg := range claim.Messages() {
+		if h.args.Max > 0 && h.redriven >= h.args.Max {
+			h.cancel()
+			return nil
+		}
+
+		dl := &inventory.DeadLetter{}
+		err := json.Unmarshal(msg.Value, dl)
+		if err != nil {
+			// Malformed dead-letters can never be re-driven. Offsets are
+			// committed by position, so these are committed along with
+			// the next re-driven dead-letter.
+			err = errors.Wrap(err, "Redrive: Error unmarshalling dead-letter")
+			log.Println(err)
+			h.skipped++
+			continue
+		}
+		if !h.matches(dl) {
+			h.skipped++
+			continue
+		}
+
+		redriven, err := h.isRedriven(dl)
+		if err != nil {
+			h.stop(err)
+			return nil
+		}
+		if redriven {
+			session.MarkMessage(msg, "")
+			h.skipped++
+			continue
+		}
+		if h.args.DryRun {
+			fmt.Println(string(msg.Value))
+			h.redriven++
+			continue
+		}
+		err = h.redrive(dl)
+		if err != nil {
+			h.stop(err)
+			return nil
+		}
+		session.MarkMessage(msg, "")
+		h.redriven++
+	}
+	return nil
+}
+
+// stop ends the re-drive at a failed dead-letter. Nothing after it is
+// marked, since marking a later offset would also commit the failed
+// dead-letter. It's read again in the next re-drive.
+func (h *redriveHandler) stop(err error) {
+	err = errors.Wrap(err, "Redrive")
+	log.Println(err)
+	if h.err == nil {
+		h.err = err
+	}
+	h.cancel()
+}
+
+// isRedriven checks if the dead-lettered event is in the redrive-log.
+func (h *redriveHandler) isRedriven(dl *inventory.DeadLetter) (bool, error) {
+	findResults, err := h.redriveLog.Find(map[string]interface{}{
+		"eventUUID": dl.Event.UUID.String(),
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error finding redrive-record")
+		return false, err
+	}
+	return len(findResults) > 0, nil
+}
+
+// filtered checks if only some dead-letters are re-driven.
+func (ra *redriveArgs) filtered() bool {
+	return ra.CorrelationID != "" || ra.Reason != ""
+}
+
+func (h *redriveHandler) matches(dl *inventory.DeadLetter) bool {
+	if h.args.Reason != "" && dl.Reason != h.args.Reason {
+		return false
+	}
+	if h.args.CorrelationID != "" &&
+		dl.Event.CorrelationID.String() != h.args.CorrelationID {
+		return false
+	}
+	return true
+}
+
+// redrive publishes the dead-lettered event as a new event, with a new UUID
+// so it's not rejected as duplicate by EventStore. CorrelationID is kept, so
+// the response-Document is matched to the original command.
+func (h *redriveHandler) redrive(dl *inventory.DeadLetter) error {
+	event := dl.Event
+	originalUUID := event.UUID
+	uuid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating UUID")
+		return err
+	}
+	event.UUID = uuid
+	event.NanoTime = time.Now().UnixNano()
+
+	marshalEvent, err := json.Marshal(event)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling Event")
+		return err
+	}
+	// Recorded before publishing, so the event is never published without
+	// its record. The unique eventUUID-index also fails concurrent re-drives
+	// of the same dead-letter.
+	_, err = h.redriveLog.InsertOne(&redriveRecord{
+		EventUUID:     originalUUID.String(),
+		CorrelationID: event.CorrelationID.String(),
+		RedrivenAs:    uuid.String(),
+		Timestamp:     time.Now().Unix(),
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error inserting redrive-record")
+		return err
+	}
+	h.producer.Input() <- kafka.CreateMessage(h.eventTopic, marshalEvent)
+	log.Printf(
+		"Re-drove %s/%s event %s as %s, CorrelationID: %s",
+		event.EventAction, event.ServiceAction, originalUUID, uuid, event.CorrelationID,
+	)
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+func runShow(args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	itemIDStr := fs.String("item", "", "ItemID of inventory-item to show")
+	fs.Parse(args)
+
+	itemID, err := uuuid.FromString(*itemIDStr)
+	if err != nil {
+		err = errors.Wrap(err, "Show: Error parsing ItemID")
+		return err
+	}
+
+	coll, err := loadAggCollection()
+	if err != nil {
+		err = errors.Wrap(err, "Show")
+		return err
+	}
+	findResult, err := coll.FindOne(map[string]interface{}{
+		"itemID": itemID.String(),
+	})
+	if err != nil {
+		err = errors.Wrapf(err, "Show: Error finding ItemID: %s", itemID)
+		return err
+	}
+	inv, assertOK := findResult.(*inventory.Inventory)
+	if !assertOK {
+		err = errors.New("error asserting database-result to Inventory-Item")
+		err = errors.Wrap(err, "Show")
+		return err
+	}
+
+	out, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		err = errors.Wrap(err, "Show: Error marshalling Inventory")
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
@@ -0,0 +1,35 @@
+// Package config loads the clients and collections shared by the service
+// and admin CLI, as configured by env-vars.
+package config
+
+import (
+	"os"
+	"time"
+
+	"github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdClient connects to etcd as configured by ETCD_* env-vars.
+func EtcdClient() (*clientv3.Client, error) {
+	etcdHostsStr := os.Getenv("ETCD_HOSTS")
+	etcdConfig := clientv3.Config{
+		DialTimeout: 5 * time.Second,
+		Endpoints:   *commonutil.ParseHosts(etcdHostsStr),
+	}
+	etcdUsername := os.Getenv("ETCD_USERNAME")
+	etcdPassword := os.Getenv("ETCD_PASSWORD")
+	if etcdUsername != "" {
+		etcdConfig.Username = etcdUsername
+	}
+	if etcdPassword != "" {
+		etcdConfig.Password = etcdPassword
+	}
+	etcd, err := clientv3.New(etcdConfig)
+	if err != nil {
+		err = errors.Wrap(err, "Failed to connect to ETCD")
+		return nil, err
+	}
+	return etcd, nil
+}
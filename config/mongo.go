@@ -0,0 +1,78 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// MongoConnection creates the MongoDB client as configured by MONGO_* env-vars.
+func MongoConnection() (*mongo.ConnectionConfig, error) {
+	hosts := *commonutil.ParseHosts(
+		os.Getenv("MONGO_HOSTS"),
+	)
+	username := os.Getenv("MONGO_USERNAME")
+	password := os.Getenv("MONGO_PASSWORD")
+	connTimeoutStr := os.Getenv("MONGO_CONNECTION_TIMEOUT_MS")
+	connTimeout, err := strconv.Atoi(connTimeoutStr)
+	if err != nil {
+		err = errors.Wrap(err, "Error converting MONGO_CONNECTION_TIMEOUT_MS to integer")
+		log.Println(err)
+		log.Println("A defalt value of 3000 will be used for MONGO_CONNECTION_TIMEOUT_MS")
+		connTimeout = 3000
+	}
+
+	mongoConfig := mongo.ClientConfig{
+		Hosts:               hosts,
+		Username:            username,
+		Password:            password,
+		TimeoutMilliseconds: uint32(connTimeout),
+	}
+	client, err := mongo.NewClient(mongoConfig)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating MongoClient")
+		return nil, err
+	}
+
+	resTimeoutStr := os.Getenv("MONGO_RESOURCE_TIMEOUT_MS")
+	resTimeout, err := strconv.Atoi(resTimeoutStr)
+	if err != nil {
+		err = errors.Wrap(err, "Error converting MONGO_RESOURCE_TIMEOUT_MS to integer")
+		log.Println(err)
+		log.Println("A defalt value of 5000 will be used for MONGO_RESOURCE_TIMEOUT_MS")
+		resTimeout = 5000
+	}
+	return &mongo.ConnectionConfig{
+		Client:  client,
+		Timeout: uint32(resTimeout),
+	}, nil
+}
+
+// AggCollection ensures the Inventory aggregate-collection, with the unique
+// itemID-index from the specified indexes. Other indexes are not created
+// here, so they can be reconciled separately (see inventory.ReconcileIndexes).
+func AggCollection(
+	conn *mongo.ConnectionConfig,
+	db string,
+	coll string,
+	indexes []inventory.IndexSpec,
+) (*mongo.Collection, error) {
+	c := &mongo.Collection{
+		Connection:   conn,
+		Database:     db,
+		Name:         coll,
+		SchemaStruct: &inventory.Inventory{},
+		Indexes:      inventory.IndexConfigs(inventory.RequiredIndexes(indexes)),
+	}
+	collection, err := mongo.EnsureCollection(c)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating MongoCollection")
+		return nil, err
+	}
+	return collection, nil
+}
@@ -0,0 +1,75 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// PublishAuditEvents controls if audit-records are published. This is disabled
+// when replaying events, since the records were already published when the
+// events were first processed.
+var PublishAuditEvents = true
+
+// AuditRecord describes a rejected command. Audit-records are logged, and
+// published on KAFKA_PRODUCER_AUDIT_TOPIC if set.
+type AuditRecord struct {
+	Type          string     `json:"type"`
+	Reason        string     `json:"reason"`
+	Tenant        string     `json:"tenant,omitempty"`
+	CorrelationID uuuid.UUID `json:"correlationID"`
+	EventAction   string     `json:"eventAction"`
+	ServiceAction string     `json:"serviceAction,omitempty"`
+	EventUUID     uuuid.UUID `json:"eventUUID"`
+	UserUUID      uuuid.UUID `json:"userUUID"`
+	Timestamp     int64      `json:"timestamp"`
+}
+
+// Audit-record types.
+const (
+	auditCrossTenant  = "crossTenant"
+	auditNoTenant     = "noTenant"
+	auditUnauthorized = "unauthorized"
+)
+
+// audit logs and publishes the audit-record for the rejected event.
+func audit(event *model.Event, auditType string, tenant uuuid.UUID, reason string) {
+	record := AuditRecord{
+		Type:          auditType,
+		Reason:        reason,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		ServiceAction: event.ServiceAction,
+		EventUUID:     event.UUID,
+		UserUUID:      event.UserUUID,
+		Timestamp:     time.Now().Unix(),
+	}
+	if tenant != (uuuid.UUID{}) {
+		record.Tenant = tenant.String()
+	}
+
+	marshalRecord, err := json.Marshal(record)
+	if err != nil {
+		err = errors.Wrap(err, "Audit: Error marshalling audit-record")
+		log.Println(err)
+		return
+	}
+	log.Printf("Audit: %s", marshalRecord)
+
+	topic := os.Getenv("KAFKA_PRODUCER_AUDIT_TOPIC")
+	if topic == "" || !PublishAuditEvents {
+		return
+	}
+	// Audit-records are published even if the command was cancelled
+	err = publishMessage(context.Background(), topic, marshalRecord)
+	if err != nil {
+		err = errors.Wrap(err, "Audit: Error publishing audit-record")
+		log.Println(err)
+	}
+}
@@ -0,0 +1,236 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// Permissions maps users to roles, and roles to their allowed commands.
+// Commands are specified as "EventAction:ServiceAction". Either action can
+// be "*" to allow any action, and just "EventAction" allows all of its
+// ServiceActions.
+type Permissions struct {
+	// UserRoles maps UserUUIDs to their roles.
+	UserRoles map[string][]string `json:"userRoles"`
+	// RolePermissions maps roles to their allowed commands.
+	RolePermissions map[string][]string `json:"rolePermissions"`
+}
+
+// PermissionStore provides the Permissions for authorizing commands.
+type PermissionStore interface {
+	Permissions() (*Permissions, error)
+}
+
+// Authz is the PermissionStore used for authorizing commands.
+// Commands are not authorized if this is nil.
+var Authz PermissionStore
+
+// Allows checks if any of the user's roles allows the command.
+func (p *Permissions) Allows(
+	userUUID uuuid.UUID,
+	eventAction string,
+	serviceAction string,
+) bool {
+	for _, role := range p.UserRoles[userUUID.String()] {
+		for _, perm := range p.RolePermissions[role] {
+			if matchPermission(perm, eventAction, serviceAction) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchPermission checks if the permission allows the command.
+func matchPermission(perm string, eventAction, serviceAction string) bool {
+	parts := strings.SplitN(perm, ":", 2)
+	if parts[0] != "*" && parts[0] != eventAction {
+		return false
+	}
+	if len(parts) == 1 || parts[1] == "*" {
+		return true
+	}
+	return parts[1] == serviceAction
+}
+
+// Authorize checks if the event's user is allowed to run the command, as per
+// the permissions in Authz. Nil is returned if the command is allowed.
+// Otherwise, the returned Document has the Unauthorized error, and the
+// rejection is audited. The retryable Unavailable error is returned if
+// permissions cannot be loaded, so Authorize should run within HandleWithRetry.
+func Authorize(event *model.Event) *model.Document {
+	if Authz == nil {
+		return nil
+	}
+
+	perms, err := Authz.Permissions()
+	if err != nil {
+		// Commands are not run if permissions cannot be loaded, and are retried
+		// since the permission-store is usually only briefly unavailable
+		err = errors.Wrap(err, "Authorize: Error loading permissions")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     Unavailable,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	if perms.Allows(event.UserUUID, event.EventAction, event.ServiceAction) {
+		return nil
+	}
+
+	reason := fmt.Sprintf(
+		"user %s is not allowed to run %s:%s",
+		event.UserUUID, event.EventAction, event.ServiceAction,
+	)
+	audit(event, auditUnauthorized, uuuid.UUID{}, reason)
+	err = errors.Wrap(errors.New(reason), "Authorize")
+	log.Println(err)
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		Error:         err.Error(),
+		ErrorCode:     Unauthorized,
+		EventAction:   event.EventAction,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+// FilePermissionStore reads Permissions from a JSON-file.
+// The file is read again when it's modified.
+type FilePermissionStore struct {
+	path string
+
+	lock    sync.Mutex
+	modTime time.Time
+	perms   *Permissions
+}
+
+// NewFilePermissionStore creates a FilePermissionStore,
+// and checks that the file can be read.
+func NewFilePermissionStore(path string) (*FilePermissionStore, error) {
+	store := &FilePermissionStore{path: path}
+	_, err := store.Permissions()
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Permissions returns the Permissions from file.
+func (s *FilePermissionStore) Permissions() (*Permissions, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		err = errors.Wrap(err, "Error reading permissions-file")
+		return nil, err
+	}
+	if s.perms != nil && info.ModTime().Equal(s.modTime) {
+		return s.perms, nil
+	}
+
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		err = errors.Wrap(err, "Error reading permissions-file")
+		return nil, err
+	}
+	perms := &Permissions{}
+	err = json.Unmarshal(data, perms)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling permissions-file")
+		return nil, err
+	}
+	s.perms = perms
+	s.modTime = info.ModTime()
+	return s.perms, nil
+}
+
+// PermissionDoc is a document in the permissions-collection for
+// MongoPermissionStore. User-documents have UserUUID and Roles,
+// and role-documents have Role and Permissions.
+type PermissionDoc struct {
+	UserUUID    string   `bson:"userUUID,omitempty"`
+	Roles       []string `bson:"roles,omitempty"`
+	Role        string   `bson:"role,omitempty"`
+	Permissions []string `bson:"permissions,omitempty"`
+}
+
+// MongoPermissionStore reads Permissions from a collection of PermissionDocs.
+// The Permissions are cached for the specified duration.
+type MongoPermissionStore struct {
+	collection *mongo.Collection
+	cacheTTL   time.Duration
+
+	lock     sync.Mutex
+	loadedAt time.Time
+	perms    *Permissions
+}
+
+// NewMongoPermissionStore creates a MongoPermissionStore. The collection's
+// SchemaStruct must be PermissionDoc.
+func NewMongoPermissionStore(
+	collection *mongo.Collection,
+	cacheTTL time.Duration,
+) *MongoPermissionStore {
+	return &MongoPermissionStore{
+		collection: collection,
+		cacheTTL:   cacheTTL,
+	}
+}
+
+// Permissions returns the Permissions from collection.
+func (s *MongoPermissionStore) Permissions() (*Permissions, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.perms != nil && time.Since(s.loadedAt) < s.cacheTTL {
+		return s.perms, nil
+	}
+
+	findResults, err := s.collection.Find(map[string]interface{}{})
+	if err != nil {
+		err = errors.Wrap(err, "Error finding permissions")
+		return nil, err
+	}
+	perms := &Permissions{
+		UserRoles:       map[string][]string{},
+		RolePermissions: map[string][]string{},
+	}
+	for _, r := range findResults {
+		doc, assertOK := r.(*PermissionDoc)
+		if !assertOK {
+			continue
+		}
+		if doc.UserUUID != "" {
+			perms.UserRoles[doc.UserUUID] = append(
+				perms.UserRoles[doc.UserUUID], doc.Roles...,
+			)
+		}
+		if doc.Role != "" {
+			perms.RolePermissions[doc.Role] = append(
+				perms.RolePermissions[doc.Role], doc.Permissions...,
+			)
+		}
+	}
+	s.perms = perms
+	s.loadedAt = time.Now()
+	return s.perms, nil
+}
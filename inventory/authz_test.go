@@ -0,0 +1,97 @@
+package inventory
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Authz", func() {
+	var (
+		userUUID  uuuid.UUID
+		permsFile string
+	)
+
+	BeforeEach(func() {
+		var err error
+		userUUID, err = uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+
+		f, err := ioutil.TempFile("", "authz")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.WriteString(`{
+			"userRoles": {"` + userUUID.String() + `": ["clerk"]},
+			"rolePermissions": {
+				"clerk": ["update:createSale", "insert"],
+				"admin": ["*"]
+			}
+		}`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		permsFile = f.Name()
+	})
+
+	AfterEach(func() {
+		Authz = nil
+		os.Remove(permsFile)
+	})
+
+	It("should match permissions", func() {
+		Expect(matchPermission("*", "delete", "")).To(BeTrue())
+		Expect(matchPermission("update", "update", "createSale")).To(BeTrue())
+		Expect(matchPermission("update:*", "update", "createSale")).To(BeTrue())
+		Expect(matchPermission("update:createSale", "update", "createSale")).To(BeTrue())
+		Expect(matchPermission("update:createSale", "update", "createWaste")).
+			To(BeFalse())
+		Expect(matchPermission("insert", "delete", "")).To(BeFalse())
+	})
+
+	It("should authorize commands allowed by user's roles", func() {
+		store, err := NewFilePermissionStore(permsFile)
+		Expect(err).ToNot(HaveOccurred())
+		Authz = store
+
+		event := &model.Event{
+			EventAction:   "update",
+			ServiceAction: "createSale",
+			UserUUID:      userUUID,
+		}
+		Expect(Authorize(event)).To(BeNil())
+
+		event.EventAction = "delete"
+		event.ServiceAction = ""
+		doc := Authorize(event)
+		Expect(doc).ToNot(BeNil())
+		Expect(doc.ErrorCode).To(BeEquivalentTo(Unauthorized))
+	})
+
+	It("should reject unknown users", func() {
+		store, err := NewFilePermissionStore(permsFile)
+		Expect(err).ToNot(HaveOccurred())
+		Authz = store
+
+		doc := Authorize(&model.Event{EventAction: "insert"})
+		Expect(doc).ToNot(BeNil())
+		Expect(doc.ErrorCode).To(BeEquivalentTo(Unauthorized))
+	})
+
+	It("should return retryable error if permissions cannot be loaded", func() {
+		Authz = failingPermissionStore{}
+
+		doc := Authorize(&model.Event{EventAction: "insert", UserUUID: userUUID})
+		Expect(doc).ToNot(BeNil())
+		Expect(doc.ErrorCode).To(BeEquivalentTo(Unavailable))
+		Expect(IsRetryable(doc.ErrorCode)).To(BeTrue())
+	})
+})
+
+type failingPermissionStore struct{}
+
+func (failingPermissionStore) Permissions() (*Permissions, error) {
+	return nil, errors.New("store unavailable")
+}
@@ -0,0 +1,244 @@
+package inventory
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// BarcodeRule defines how a variable-measure (random-weight) barcode is decoded.
+// Positions are zero-based indexes into the barcode-digits.
+type BarcodeRule struct {
+	// Prefix is the leading digits that identify barcodes for this rule.
+	Prefix string `json:"prefix"`
+	// Length is the number of digits in barcode, including the check-digit.
+	Length int `json:"length"`
+
+	ItemCodeStart  int `json:"itemCodeStart"`
+	ItemCodeLength int `json:"itemCodeLength"`
+
+	ValueStart  int `json:"valueStart"`
+	ValueLength int `json:"valueLength"`
+	// ValueType is either "weight" or "price".
+	ValueType string `json:"valueType"`
+	// ValueDecimals is the number of implied decimal-places in value.
+	ValueDecimals int `json:"valueDecimals"`
+	// Unit is the unit for embedded weights. Defaults to kg.
+	Unit Unit `json:"unit,omitempty"`
+}
+
+// DecodedBarcode is the data embedded in a variable-measure barcode.
+type DecodedBarcode struct {
+	ItemCode string
+	// Only one of Weight or Price is set, depending on the matched rule.
+	Weight Decimal
+	Price  Decimal
+	Unit   Unit
+}
+
+// DefaultBarcodeRules returns rules for the common variable-measure formats:
+// UPC-A with prefix 2 and embedded price (2 IIIII P VVVV C), and EAN-13
+// with prefix 2 and embedded weight in grams-precision (2 IIIIII VVVVV C).
+func DefaultBarcodeRules() []BarcodeRule {
+	return []BarcodeRule{
+		BarcodeRule{
+			Prefix:         "2",
+			Length:         12,
+			ItemCodeStart:  1,
+			ItemCodeLength: 5,
+			ValueStart:     7,
+			ValueLength:    4,
+			ValueType:      "price",
+			ValueDecimals:  2,
+		},
+		BarcodeRule{
+			Prefix:         "2",
+			Length:         13,
+			ItemCodeStart:  1,
+			ItemCodeLength: 6,
+			ValueStart:     7,
+			ValueLength:    5,
+			ValueType:      "weight",
+			ValueDecimals:  3,
+		},
+	}
+}
+
+// BarcodeRules are the rules used for decoding variable-measure barcodes
+// in sale-lines.
+var BarcodeRules = DefaultBarcodeRules()
+
+// Validate checks if the rule is well-formed.
+func (r *BarcodeRule) Validate() error {
+	if r.Prefix == "" {
+		return errors.New("prefix is required")
+	}
+	if r.Length != 12 && r.Length != 13 {
+		return fmt.Errorf("length must be 12 or 13, got %d", r.Length)
+	}
+	if r.ValueType != "weight" && r.ValueType != "price" {
+		return fmt.Errorf("valueType must be weight or price, got %s", r.ValueType)
+	}
+	// Check-digit is the last digit, and cannot be part of item-code or value
+	if r.ItemCodeStart < 0 || r.ItemCodeLength < 1 ||
+		r.ItemCodeStart+r.ItemCodeLength > r.Length-1 {
+		return errors.New("item-code is out of barcode bounds")
+	}
+	if r.ValueStart < 0 || r.ValueLength < 1 ||
+		r.ValueStart+r.ValueLength > r.Length-1 {
+		return errors.New("value is out of barcode bounds")
+	}
+	if r.ValueDecimals < 0 || r.ValueDecimals > r.ValueLength {
+		return errors.New("valueDecimals must be between zero and valueLength")
+	}
+	_, err := ParseUnit(string(r.Unit))
+	return err
+}
+
+// DecodeBarcode decodes the item-code and weight or price from the
+// variable-measure barcode, using the first matching rule.
+func DecodeBarcode(barcode string, rules []BarcodeRule) (*DecodedBarcode, error) {
+	barcode = stripCodeSeparators(barcode)
+	_, err := ParseGTIN(barcode)
+	if err != nil {
+		err = errors.Wrap(err, "invalid barcode")
+		return nil, err
+	}
+
+	for _, r := range rules {
+		if len(barcode) != r.Length || !strings.HasPrefix(barcode, r.Prefix) {
+			continue
+		}
+
+		itemCode := barcode[r.ItemCodeStart : r.ItemCodeStart+r.ItemCodeLength]
+		valueStr := barcode[r.ValueStart : r.ValueStart+r.ValueLength]
+		// Digits are already checked when parsing GTIN
+		value, _ := strconv.ParseInt(valueStr, 10, 64)
+		decodedValue, err := decimalFromRat(
+			new(big.Rat).SetFrac(big.NewInt(value), pow10(r.ValueDecimals)),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid barcode")
+		}
+		if !decodedValue.GreaterThan(Decimal{}) {
+			return nil, errors.New("barcode has zero weight or price")
+		}
+
+		// Rule-units are checked when validating rules
+		unit, _ := ParseUnit(string(r.Unit))
+		decoded := &DecodedBarcode{
+			ItemCode: itemCode,
+			Unit:     unit,
+		}
+		if r.ValueType == "price" {
+			decoded.Price = decodedValue
+		} else {
+			decoded.Weight = decodedValue
+		}
+		return decoded, nil
+	}
+
+	return nil, errors.New("barcode does not match any variable-measure rule")
+}
+
+// resolveBarcodeLine decodes the barcode in sale-line, and sets the itemID,
+// weight, and unit in sale-line from the matching inventory-item. Items are matched
+// by their SKU, which must be the item-code embedded in barcode, and are
+// limited to the tenant's items if tenant is specified.
+func resolveBarcodeLine(
+	collection *mongo.Collection,
+	itemMap map[string]interface{},
+	tenant uuuid.UUID,
+) (int, error) {
+	barcode, assertOK := itemMap["barcode"].(string)
+	if !assertOK {
+		return UserError, errors.New("error asserting barcode to string")
+	}
+	decoded, err := DecodeBarcode(barcode, BarcodeRules)
+	if err != nil {
+		return UserError, err
+	}
+
+	filter := map[string]interface{}{
+		"sku": decoded.ItemCode,
+	}
+	if tenant != (uuuid.UUID{}) {
+		filter["rsCustomerID"] = tenant.String()
+	}
+	findResults, err := collection.Find(filter)
+	if err != nil {
+		err = errors.Wrapf(
+			err, "error finding items for item-code %s", decoded.ItemCode,
+		)
+		return DatabaseError, err
+	}
+	items := []*Inventory{}
+	for _, r := range findResults {
+		inv, assertOK := r.(*Inventory)
+		if assertOK {
+			items = append(items, inv)
+		}
+	}
+	if len(items) == 0 {
+		err = fmt.Errorf("no items found for item-code %s", decoded.ItemCode)
+		return UserError, err
+	}
+
+	// Oldest stock is sold first
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DateArrived < items[j].DateArrived
+	})
+
+	var (
+		match  *Inventory
+		weight Decimal
+	)
+	for _, inv := range items {
+		var w Decimal
+		if decoded.Price.GreaterThan(Decimal{}) {
+			if !inv.Price.GreaterThan(Decimal{}) {
+				continue
+			}
+			// Price is per unit-weight of item
+			w, err = decoded.Price.Div(inv.Price)
+		} else {
+			w, err = ConvertWeight(decoded.Weight, decoded.Unit, inv.WeightUnit())
+		}
+		if err != nil {
+			return UserError, err
+		}
+
+		available, err := inv.RemainingWeight()
+		if err != nil {
+			return UserError, err
+		}
+		hasAvailable := !available.LessThan(w)
+		if match == nil || hasAvailable {
+			match = inv
+			weight = w
+		}
+		if hasAvailable {
+			break
+		}
+	}
+	if match == nil {
+		err = fmt.Errorf("no priced items found for item-code %s", decoded.ItemCode)
+		return UserError, err
+	}
+
+	itemMap["itemID"] = match.ItemID.String()
+	itemMap["weight"] = weight.String()
+	itemMap["unit"] = string(match.WeightUnit())
+	return 0, nil
+}
+
+// pow10 returns 10^n as big.Int.
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,34 @@
+package inventory
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Barcode", func() {
+	It("should decode price from UPC-A variable-measure barcode", func() {
+		decoded, err := DecodeBarcode("212345005996", DefaultBarcodeRules())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.ItemCode).To(Equal("12345"))
+		Expect(decoded.Price.String()).To(Equal("5.99"))
+		Expect(decoded.Weight.IsZero()).To(BeTrue())
+	})
+
+	It("should decode weight from EAN-13 variable-measure barcode", func() {
+		decoded, err := DecodeBarcode("2123450123452", DefaultBarcodeRules())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.ItemCode).To(Equal("123450"))
+		Expect(decoded.Weight.String()).To(Equal("12.345"))
+		Expect(decoded.Price.IsZero()).To(BeTrue())
+	})
+
+	It("should return error for invalid check-digit", func() {
+		_, err := DecodeBarcode("212345005997", DefaultBarcodeRules())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return error if no rule matches", func() {
+		_, err := DecodeBarcode("036000291452", DefaultBarcodeRules())
+		Expect(err).To(HaveOccurred())
+	})
+})
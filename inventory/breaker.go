@@ -0,0 +1,210 @@
+package inventory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a circuit-breaker.
+type BreakerState int
+
+// Circuit-breaker states.
+const (
+	// BreakerClosed allows all calls.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects all calls until its open-timeout passes.
+	BreakerOpen
+	// BreakerHalfOpen allows limited probe-calls, and closes the breaker if
+	// a probe succeeds, or opens it again if a probe fails.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "halfOpen"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig configures when circuit-breakers open and recover.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that open
+	// the breaker.
+	FailureThreshold int
+	// OpenTimeout is the time the breaker stays open before allowing probes.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of concurrent probe-calls allowed
+	// when half-open.
+	HalfOpenProbes int
+}
+
+// DefaultBreakerConfig returns the default breaker-config, opening after five
+// consecutive failures, and probing with a single call after 10 seconds.
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      10 * time.Second,
+		HalfOpenProbes:   1,
+	}
+}
+
+// Breaker is a circuit-breaker for a backend, such as Mongo or etcd. Calls
+// fail fast while the backend is unhealthy, instead of waiting for timeouts.
+type Breaker struct {
+	Name   string
+	config BreakerConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	probes   int
+	openedAt time.Time
+	stats    BreakerStats
+}
+
+// BreakerStats are the state and counters of a Breaker, for health and metrics.
+type BreakerStats struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// ConsecutiveFailures is reset on each success.
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	Successes           uint64 `json:"successes"`
+	Failures            uint64 `json:"failures"`
+	// Rejected is the number of calls failed fast by the breaker.
+	Rejected uint64 `json:"rejected"`
+	// Opens is the number of times the breaker opened.
+	Opens uint64 `json:"opens"`
+	// StateSince is the Unix-time (seconds) of last state-change.
+	StateSince int64 `json:"stateSince"`
+}
+
+// MongoBreaker is the circuit-breaker for Mongo.
+var MongoBreaker = NewBreaker("mongo", DefaultBreakerConfig())
+
+// EtcdBreaker is the circuit-breaker for etcd item-locks.
+var EtcdBreaker = NewBreaker("etcd", DefaultBreakerConfig())
+
+// NewBreaker creates a closed Breaker.
+func NewBreaker(name string, config BreakerConfig) *Breaker {
+	b := &Breaker{
+		Name:   name,
+		config: config,
+		now:    time.Now,
+	}
+	b.stats.StateSince = b.now().Unix()
+	return b
+}
+
+// Allow checks if a call is allowed. Returns an error if the breaker is open,
+// or if it's half-open and all probes are in progress. Allowed calls must
+// report their result using Success or Failure.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.setState(BreakerHalfOpen)
+		b.probes = 0
+	}
+	switch b.state {
+	case BreakerOpen:
+		b.stats.Rejected++
+		return fmt.Errorf("%s circuit-breaker is open", b.Name)
+	case BreakerHalfOpen:
+		if b.probes >= b.config.HalfOpenProbes {
+			b.stats.Rejected++
+			return fmt.Errorf("%s circuit-breaker is half-open, probe in progress", b.Name)
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Success reports a successful call. A successful probe closes the breaker.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stats.Successes++
+	b.failures = 0
+	if b.state == BreakerHalfOpen {
+		b.setState(BreakerClosed)
+		b.probes = 0
+	}
+}
+
+// Failure reports a failed call. The breaker opens if the failure-threshold
+// is reached, or if a probe fails.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stats.Failures++
+	b.failures++
+	switch b.state {
+	case BreakerHalfOpen:
+		b.open()
+	case BreakerClosed:
+		if b.failures >= b.config.FailureThreshold {
+			b.open()
+		}
+	}
+}
+
+// Cancel reports a call that was cancelled before its result was known, such
+// as when the command passed its deadline. The breaker-state is not changed,
+// but a half-open breaker allows another probe.
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// State returns the current state of breaker.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Stats returns the current state and counters of breaker.
+func (b *Breaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.stats
+	stats.Name = b.Name
+	stats.State = b.state.String()
+	stats.ConsecutiveFailures = b.failures
+	return stats
+}
+
+func (b *Breaker) open() {
+	b.setState(BreakerOpen)
+	b.openedAt = b.now()
+	b.probes = 0
+	b.stats.Opens++
+}
+
+func (b *Breaker) setState(state BreakerState) {
+	if b.state != state {
+		b.state = state
+		b.stats.StateSince = b.now().Unix()
+	}
+}
+
+// Breakers returns the circuit-breakers for all backends.
+func Breakers() []*Breaker {
+	return []*Breaker{MongoBreaker, EtcdBreaker}
+}
@@ -0,0 +1,112 @@
+package inventory
+
+import (
+	"context"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Breaker", func() {
+	var (
+		b   *Breaker
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Unix(1540000000, 0)
+		b = NewBreaker("test", BreakerConfig{
+			FailureThreshold: 2,
+			OpenTimeout:      10 * time.Second,
+			HalfOpenProbes:   1,
+		})
+		b.now = func() time.Time {
+			return now
+		}
+	})
+
+	It("should open after consecutive failures, and fail fast", func() {
+		Expect(b.Allow()).To(Succeed())
+		b.Failure()
+		Expect(b.Allow()).To(Succeed())
+		b.Success()
+		Expect(b.Allow()).To(Succeed())
+		b.Failure()
+		Expect(b.State()).To(Equal(BreakerClosed))
+
+		Expect(b.Allow()).To(Succeed())
+		b.Failure()
+		Expect(b.State()).To(Equal(BreakerOpen))
+		Expect(b.Allow()).To(HaveOccurred())
+
+		stats := b.Stats()
+		Expect(stats.State).To(Equal("open"))
+		Expect(stats.Opens).To(Equal(uint64(1)))
+		Expect(stats.Rejected).To(Equal(uint64(1)))
+	})
+
+	It("should recover through half-open probes", func() {
+		b.Failure()
+		b.Failure()
+		Expect(b.State()).To(Equal(BreakerOpen))
+
+		now = now.Add(10 * time.Second)
+		Expect(b.Allow()).To(Succeed())
+		Expect(b.State()).To(Equal(BreakerHalfOpen))
+		// Only one probe at a time
+		Expect(b.Allow()).To(HaveOccurred())
+
+		b.Failure()
+		Expect(b.State()).To(Equal(BreakerOpen))
+
+		now = now.Add(10 * time.Second)
+		Expect(b.Allow()).To(Succeed())
+		b.Success()
+		Expect(b.State()).To(Equal(BreakerClosed))
+		Expect(b.Allow()).To(Succeed())
+	})
+
+	It("should fail commands fast while Mongo breaker is open", func() {
+		defaultBreaker := MongoBreaker
+		defer func() {
+			MongoBreaker = defaultBreaker
+		}()
+		MongoBreaker = b
+		b.Failure()
+		b.Failure()
+
+		called := false
+		ctx := context.Background()
+		doc := runWithBreaker(ctx, &model.Event{}, func(context.Context) *model.Document {
+			called = true
+			return &model.Document{}
+		})
+		Expect(called).To(BeFalse())
+		Expect(doc.ErrorCode).To(Equal(int16(Unavailable)))
+	})
+
+	It("should open Mongo breaker when lines fail with Mongo-errors", func() {
+		defaultBreaker := MongoBreaker
+		defer func() {
+			MongoBreaker = defaultBreaker
+		}()
+		MongoBreaker = b
+
+		ctx := context.Background()
+		handleLine := func(errCode int) func(context.Context) *model.Document {
+			return func(ctx context.Context) *model.Document {
+				reportLineError(ctx, errCode)
+				return &model.Document{}
+			}
+		}
+		runWithBreaker(ctx, &model.Event{}, handleLine(UserError))
+		runWithBreaker(ctx, &model.Event{}, handleLine(UserError))
+		Expect(b.State()).To(Equal(BreakerClosed))
+
+		runWithBreaker(ctx, &model.Event{}, handleLine(DatabaseError))
+		runWithBreaker(ctx, &model.Event{}, handleLine(DatabaseError))
+		Expect(b.State()).To(Equal(BreakerOpen))
+	})
+})
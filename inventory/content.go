@@ -0,0 +1,689 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/TerrexTech/agg-inventory-cmd/pb"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	"github.com/golang/protobuf/proto"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	"github.com/pkg/errors"
+)
+
+// ContentType is the encoding of event-data, and of the result of its Document.
+type ContentType string
+
+const (
+	// ContentTypeJSON is the default content-type, used for unmarked data.
+	ContentTypeJSON ContentType = "application/json"
+	// ContentTypeProtobuf is the content-type for messages in pb/inventory.proto.
+	ContentTypeProtobuf ContentType = "application/x-protobuf"
+)
+
+// contentMarker starts the data that's marked with its content-type. It's
+// followed by the content-type and a newline, and then the payload. JSON can't
+// start with a NUL-byte, so unmarked JSON is never mistaken for marked data.
+const contentMarker = byte(0)
+
+// ParseContent returns the content-type and payload of data. Data not marked
+// with a content-type is JSON.
+func ParseContent(data []byte) (ContentType, []byte, error) {
+	if len(data) == 0 || data[0] != contentMarker {
+		return ContentTypeJSON, data, nil
+	}
+	end := bytes.IndexByte(data, '\n')
+	if end < 0 {
+		return "", nil, errors.New("Content Error: content-type is not terminated")
+	}
+	return ContentType(data[1:end]), data[end+1:], nil
+}
+
+// MarkContent returns the payload marked with its content-type. JSON-payloads
+// are not marked, so they remain readable by JSON-consumers.
+func MarkContent(contentType ContentType, payload []byte) []byte {
+	if contentType == ContentTypeJSON {
+		return payload
+	}
+	data := make([]byte, 0, len(contentType)+len(payload)+2)
+	data = append(data, contentMarker)
+	data = append(data, contentType...)
+	data = append(data, '\n')
+	return append(data, payload...)
+}
+
+// protoCodec decodes the Protobuf event-data of a command, and encodes the
+// handler's JSON Document-result to Protobuf. Commands with decodeMessage are
+// handled from the decoded message in ctx (see protoRequest), and their
+// event-data is left as is. Inserts are converted to JSON by decodeData
+// instead, since bulk-insert items are decoded and validated one by one.
+type protoCodec struct {
+	decodeMessage func(payload []byte) (proto.Message, error)
+	decodeData    func(payload []byte) ([]byte, error)
+	encodeResult  func(result []byte) (proto.Message, error)
+}
+
+// protoCodecs are the commands that accept Protobuf event-data. Other commands,
+// such as deletes by filter and sale-reversals, only accept JSON.
+var protoCodecs = map[schemaKey]*protoCodec{
+	schemaKey{"insert", anyServiceAction}: &protoCodec{
+		decodeData:   decodeInsertCommand,
+		encodeResult: encodeInsertResult,
+	},
+	schemaKey{"update", anyServiceAction}: &protoCodec{
+		decodeMessage: decodeUpdateCommand,
+		encodeResult:  encodeUpdateResult,
+	},
+	schemaKey{"update", "createSale"}:      saleCodec,
+	schemaKey{"update", "createFlashSale"}: saleCodec,
+	schemaKey{"update", "createWaste"}:     saleCodec,
+	schemaKey{"update", "createDonation"}:  saleCodec,
+}
+
+var saleCodec = &protoCodec{
+	decodeMessage: decodeSaleCommand,
+	encodeResult:  encodeSaleResult,
+}
+
+// findProtoCodec returns the codec for the command. The codec for
+// ServiceAction "*" is only used for commands without a schema of their own,
+// since those are handled as ServiceAction "*".
+func findProtoCodec(eventAction string, serviceAction string) *protoCodec {
+	key := schemaKey{eventAction, serviceAction}
+	c := protoCodecs[key]
+	if c == nil && commandSchemaSources[key] == "" {
+		c = protoCodecs[schemaKey{eventAction, anyServiceAction}]
+	}
+	return c
+}
+
+type protoRequestKey struct{}
+
+// protoRequest returns the Protobuf event-data decoded by DecodeEventContent,
+// or nil if event-data isn't Protobuf or is converted to JSON.
+func protoRequest(ctx context.Context) proto.Message {
+	msg, _ := ctx.Value(protoRequestKey{}).(proto.Message)
+	return msg
+}
+
+// protoRequestValue returns the Protobuf event-data in ctx as the values its
+// JSON-form decodes to, for checking it against the command-schema and tenant.
+// Nil is returned if ctx has no Protobuf event-data.
+func protoRequestValue(ctx context.Context) map[string]interface{} {
+	switch msg := protoRequest(ctx).(type) {
+	case *pb.SaleCommand:
+		return saleRequestFromProto(msg)
+	case *pb.UpdateCommand:
+		u := updateFromProto(msg)
+		return map[string]interface{}{
+			"filter": u.Filter,
+			"update": u.Update,
+		}
+	}
+	return nil
+}
+
+// DecodeEventContent decodes the event-data and returns the content-type it
+// was encoded in. Protobuf event-data is returned in ctx for commands handled
+// from Protobuf messages, and is otherwise replaced with its JSON-form.
+// The error-Document is returned if the content-type isn't supported for
+// the command, or if event-data can't be decoded.
+func DecodeEventContent(
+	ctx context.Context, event *model.Event,
+) (context.Context, ContentType, *model.Document) {
+	contentType, payload, err := ParseContent(event.Data)
+	if err != nil {
+		err = errors.Wrap(err, "Error parsing content-type")
+		return ctx, ContentTypeJSON, contentErrorDoc(event, err)
+	}
+
+	switch contentType {
+	case ContentTypeJSON:
+		event.Data = payload
+		return ctx, contentType, nil
+
+	case ContentTypeProtobuf:
+		codec := findProtoCodec(event.EventAction, event.ServiceAction)
+		if codec == nil {
+			err = fmt.Errorf(
+				"Content Error: %s is not supported for %s/%s",
+				contentType, event.EventAction, event.ServiceAction,
+			)
+			return ctx, contentType, contentErrorDoc(event, err)
+		}
+		if codec.decodeMessage != nil {
+			msg, err := codec.decodeMessage(payload)
+			if err != nil {
+				err = errors.Wrap(err, "Error decoding Protobuf event-data")
+				return ctx, contentType, contentErrorDoc(event, err)
+			}
+			ctx = context.WithValue(ctx, protoRequestKey{}, msg)
+			return ctx, contentType, nil
+		}
+		data, err := codec.decodeData(payload)
+		if err != nil {
+			err = errors.Wrap(err, "Error decoding Protobuf event-data")
+			return ctx, contentType, contentErrorDoc(event, err)
+		}
+		event.Data = data
+		return ctx, contentType, nil
+
+	default:
+		err = fmt.Errorf("Content Error: unsupported content-type %q", contentType)
+		return ctx, ContentTypeJSON, contentErrorDoc(event, err)
+	}
+}
+
+// EncodeDocumentContent returns the Document with its result encoded in the
+// content-type of event-data. Results that can't be encoded are returned as
+// JSON, which consumers can tell apart since it isn't marked.
+func EncodeDocumentContent(
+	event *model.Event, contentType ContentType, doc *model.Document,
+) *model.Document {
+	if doc == nil || contentType != ContentTypeProtobuf || len(doc.Result) == 0 {
+		return doc
+	}
+
+	var (
+		msg proto.Message
+		err error
+	)
+	if doc.ErrorCode == ValidationError {
+		msg, err = encodeValidationResult(doc.Result)
+	} else {
+		codec := findProtoCodec(event.EventAction, event.ServiceAction)
+		if codec == nil {
+			return doc
+		}
+		msg, err = codec.encodeResult(doc.Result)
+	}
+	if err == nil {
+		var payload []byte
+		payload, err = proto.Marshal(msg)
+		if err == nil {
+			encoded := *doc
+			encoded.Result = MarkContent(contentType, payload)
+			return &encoded
+		}
+	}
+	err = errors.Wrap(err, "Content: Error encoding Document-result, returning JSON")
+	log.Println(err)
+	return doc
+}
+
+func contentErrorDoc(event *model.Event, err error) *model.Document {
+	log.Println(err)
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		Error:         err.Error(),
+		ErrorCode:     UserError,
+		EventAction:   event.EventAction,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+func decodeInsertCommand(payload []byte) ([]byte, error) {
+	cmd := &pb.InsertCommand{}
+	err := proto.Unmarshal(payload, cmd)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling InsertCommand")
+		return nil, err
+	}
+
+	if cmd.Item != nil {
+		if len(cmd.Items) > 0 {
+			return nil, errors.New("InsertCommand Error: both item and items are set")
+		}
+		return json.Marshal(inventoryFromProto(cmd.Item))
+	}
+	if len(cmd.Items) == 0 {
+		return nil, errors.New("InsertCommand Error: no item or items are set")
+	}
+	items := make([]map[string]interface{}, len(cmd.Items))
+	for i, item := range cmd.Items {
+		if item == nil {
+			item = &pb.Inventory{}
+		}
+		items[i] = inventoryFromProto(item)
+	}
+	return json.Marshal(map[string]interface{}{
+		"items":   items,
+		"ordered": !cmd.Unordered,
+	})
+}
+
+// inventoryField is a field of pb.Inventory, by its proto-name, with its
+// JSON-name and value.
+type inventoryField struct {
+	proto string
+	json  string
+	value func(item *pb.Inventory) interface{}
+}
+
+var inventoryFields = []inventoryField{
+	{"id", "_id", func(i *pb.Inventory) interface{} {
+		return i.Id
+	}},
+	{"item_id", "itemID", func(i *pb.Inventory) interface{} {
+		return i.ItemId
+	}},
+	{"parent_item_id", "parentItemID", func(i *pb.Inventory) interface{} {
+		return i.ParentItemId
+	}},
+	{"device_id", "deviceID", func(i *pb.Inventory) interface{} {
+		return i.DeviceId
+	}},
+	{"rs_customer_id", "rsCustomerID", func(i *pb.Inventory) interface{} {
+		return i.RsCustomerId
+	}},
+
+	{"date_arrived", "dateArrived", func(i *pb.Inventory) interface{} {
+		return i.DateArrived
+	}},
+	{"date_sold", "dateSold", func(i *pb.Inventory) interface{} {
+		return i.DateSold
+	}},
+	{"timestamp", "timestamp", func(i *pb.Inventory) interface{} {
+		return i.Timestamp
+	}},
+	{"flash_sale_timestamp", "flashSaleTimestamp", func(i *pb.Inventory) interface{} {
+		return i.FlashSaleTimestamp
+	}},
+	{"projected_date", "projectedDate", func(i *pb.Inventory) interface{} {
+		return i.ProjectedDate
+	}},
+	{"schema_version", "schemaVersion", func(i *pb.Inventory) interface{} {
+		return i.SchemaVersion
+	}},
+
+	{"donate_weight", "donateWeight", func(i *pb.Inventory) interface{} {
+		return i.DonateWeight
+	}},
+	{"flash_sale_weight", "flashSaleWeight", func(i *pb.Inventory) interface{} {
+		return i.FlashSaleWeight
+	}},
+	{"price", "price", func(i *pb.Inventory) interface{} {
+		return i.Price
+	}},
+	{"sold_weight", "soldWeight", func(i *pb.Inventory) interface{} {
+		return i.SoldWeight
+	}},
+	{"total_weight", "totalWeight", func(i *pb.Inventory) interface{} {
+		return i.TotalWeight
+	}},
+	{"waste_weight", "wasteWeight", func(i *pb.Inventory) interface{} {
+		return i.WasteWeight
+	}},
+
+	{"location", "location", func(i *pb.Inventory) interface{} {
+		return i.Location
+	}},
+	{"lot", "lot", func(i *pb.Inventory) interface{} {
+		return i.Lot
+	}},
+	{"name", "name", func(i *pb.Inventory) interface{} {
+		return i.Name
+	}},
+	{"origin", "origin", func(i *pb.Inventory) interface{} {
+		return i.Origin
+	}},
+	{"gtin", "gtin", func(i *pb.Inventory) interface{} {
+		return i.Gtin
+	}},
+	{"sku", "sku", func(i *pb.Inventory) interface{} {
+		return i.Sku
+	}},
+	{"upc", "upc", func(i *pb.Inventory) interface{} {
+		return i.Upc
+	}},
+	{"unit", "unit", func(i *pb.Inventory) interface{} {
+		return i.Unit
+	}},
+
+	{"on_flash_sale", "onFlashSale", func(i *pb.Inventory) interface{} {
+		return i.OnFlashSale
+	}},
+}
+
+func findInventoryField(protoName string) *inventoryField {
+	for i := range inventoryFields {
+		if inventoryFields[i].proto == protoName {
+			return &inventoryFields[i]
+		}
+	}
+	return nil
+}
+
+// inventoryFromProto returns the JSON-fields of item. Blank fields are not set.
+func inventoryFromProto(item *pb.Inventory) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, f := range inventoryFields {
+		switch value := f.value(item).(type) {
+		case string:
+			if value != "" {
+				m[f.json] = value
+			}
+		case int64:
+			if value != 0 {
+				m[f.json] = value
+			}
+		case bool:
+			if value {
+				m[f.json] = value
+			}
+		}
+	}
+	return m
+}
+
+// inventoryToProto converts the item to Protobuf. Zero values are left blank.
+func inventoryToProto(i *Inventory) *pb.Inventory {
+	item := &pb.Inventory{
+		ItemId:       uuidString(i.ItemID),
+		ParentItemId: uuidString(i.ParentItemID),
+		DeviceId:     uuidString(i.DeviceID),
+		RsCustomerId: uuidString(i.RSCustomerID),
+
+		DateArrived:        i.DateArrived,
+		DateSold:           i.DateSold,
+		Timestamp:          i.Timestamp,
+		FlashSaleTimestamp: i.FlashSaleTimestamp,
+		ProjectedDate:      i.ProjectedDate,
+		SchemaVersion:      i.SchemaVersion,
+
+		DonateWeight:    decimalString(i.DonateWeight),
+		FlashSaleWeight: decimalString(i.FlashSaleWeight),
+		Price:           decimalString(i.Price),
+		SoldWeight:      decimalString(i.SoldWeight),
+		TotalWeight:     decimalString(i.TotalWeight),
+		WasteWeight:     decimalString(i.WasteWeight),
+
+		Location: i.Location,
+		Lot:      i.Lot,
+		Name:     i.Name,
+		Origin:   i.Origin,
+		Gtin:     i.GTIN,
+		Sku:      i.SKU,
+		Upc:      i.UPC,
+		Unit:     string(i.Unit),
+
+		OnFlashSale: i.OnFlashSale,
+	}
+	if i.ID != objectid.NilObjectID {
+		item.Id = i.ID.Hex()
+	}
+	return item
+}
+
+func encodeInsertResult(result []byte) (proto.Message, error) {
+	// Bulk-insert results are told apart from items by their insertedCount
+	probe := struct {
+		InsertedCount *int `json:"insertedCount"`
+	}{}
+	err := json.Unmarshal(result, &probe)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling insert-result")
+		return nil, err
+	}
+
+	if probe.InsertedCount == nil {
+		inv := &Inventory{}
+		err = json.Unmarshal(result, inv)
+		if err != nil {
+			err = errors.Wrap(err, "Error unmarshalling inserted Inventory")
+			return nil, err
+		}
+		return &pb.InsertResult{Item: inventoryToProto(inv)}, nil
+	}
+
+	bulk := &BulkInsertResult{}
+	err = json.Unmarshal(result, bulk)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling bulk-insert result")
+		return nil, err
+	}
+	bulkResult := &pb.BulkInsertResult{
+		Ordered:       bulk.Ordered,
+		InsertedCount: int32(bulk.InsertedCount),
+		FailedCount:   int32(bulk.FailedCount),
+	}
+	for _, r := range bulk.Items {
+		bulkResult.Items = append(bulkResult.Items, &pb.BulkInsertItemResult{
+			Index:       int32(r.Index),
+			ItemId:      r.ItemID,
+			Id:          r.ID,
+			Inserted:    r.Inserted,
+			Existing:    r.Existing,
+			Error:       r.Error,
+			ErrorCode:   int32(r.ErrorCode),
+			FieldErrors: fieldErrorsToProto(r.FieldErrors),
+		})
+	}
+	return &pb.InsertResult{Bulk: bulkResult}, nil
+}
+
+func decodeSaleCommand(payload []byte) (proto.Message, error) {
+	cmd := &pb.SaleCommand{}
+	err := proto.Unmarshal(payload, cmd)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling SaleCommand")
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// saleRequestFromProto returns the sale-request with the values its JSON-form
+// decodes to, which is what sale-handlers read. Blank fields are not set.
+func saleRequestFromProto(cmd *pb.SaleCommand) map[string]interface{} {
+	items := make([]interface{}, len(cmd.Items))
+	for i, line := range cmd.Items {
+		item := map[string]interface{}{}
+		if line != nil {
+			for field, value := range map[string]string{
+				"itemID":  line.ItemId,
+				"barcode": line.Barcode,
+				"sku":     line.Sku,
+				"lot":     line.Lot,
+				"weight":  line.Weight,
+				"unit":    line.Unit,
+			} {
+				if value != "" {
+					item[field] = value
+				}
+			}
+		}
+		items[i] = item
+	}
+	request := map[string]interface{}{
+		"items": items,
+	}
+	if cmd.ReservationId != "" {
+		request["reservationID"] = cmd.ReservationId
+	}
+	if cmd.PartialFulfillment {
+		request["partialFulfillment"] = true
+	}
+	return request
+}
+
+// saleRequest returns the sale-request from the event-data, or from its
+// SaleCommand in ctx for Protobuf event-data.
+func saleRequest(
+	ctx context.Context, event *model.Event,
+) (map[string]interface{}, error) {
+	if cmd, isProto := protoRequest(ctx).(*pb.SaleCommand); isProto {
+		return saleRequestFromProto(cmd), nil
+	}
+	m := map[string]interface{}{}
+	err := json.Unmarshal(event.Data, &m)
+	return m, err
+}
+
+// saleRequestJSON is the JSON-form of SaleCommand, for decoding the original
+// request in sale-results.
+type saleRequestJSON struct {
+	Items []struct {
+		ItemID  string   `json:"itemID"`
+		Barcode string   `json:"barcode"`
+		SKU     string   `json:"sku"`
+		Lot     string   `json:"lot"`
+		Weight  *Decimal `json:"weight"`
+		Unit    string   `json:"unit"`
+	} `json:"items"`
+	ReservationID      string `json:"reservationID"`
+	PartialFulfillment bool   `json:"partialFulfillment"`
+}
+
+func encodeSaleResult(result []byte) (proto.Message, error) {
+	resp := struct {
+		OriginalRequest saleRequestJSON  `json:"originalRequest"`
+		Result          []SaleItemResult `json:"result"`
+	}{}
+	err := json.Unmarshal(result, &resp)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling sale-result")
+		return nil, err
+	}
+
+	req := &pb.SaleCommand{
+		ReservationId:      resp.OriginalRequest.ReservationID,
+		PartialFulfillment: resp.OriginalRequest.PartialFulfillment,
+	}
+	for _, line := range resp.OriginalRequest.Items {
+		req.Items = append(req.Items, &pb.SaleLine{
+			ItemId:  line.ItemID,
+			Barcode: line.Barcode,
+			Sku:     line.SKU,
+			Lot:     line.Lot,
+			Weight:  decimalPtrString(line.Weight),
+			Unit:    line.Unit,
+		})
+	}
+
+	saleResult := &pb.SaleResult{OriginalRequest: req}
+	for _, r := range resp.Result {
+		saleResult.Result = append(saleResult.Result, &pb.SaleItemResult{
+			ItemId:              uuidString(r.ItemID),
+			Barcode:             r.Barcode,
+			Sku:                 r.SKU,
+			Error:               r.Error,
+			ErrorCode:           int32(r.ErrorCode),
+			TotalSoldWeight:     decimalString(r.TotalSoldWeight),
+			TotalWasteWeight:    decimalString(r.TotalWasteWeight),
+			TotalDonateWeight:   decimalString(r.TotalDonateWeight),
+			TotalWeight:         decimalString(r.TotalWeight),
+			AllocatedWeight:     decimalPtrString(r.AllocatedWeight),
+			RequestedWeight:     decimalPtrString(r.RequestedWeight),
+			FulfilledWeight:     decimalPtrString(r.FulfilledWeight),
+			ShortWeight:         decimalPtrString(r.ShortWeight),
+			ReservationConsumed: r.ReservationConsumed,
+			Unit:                string(r.Unit),
+		})
+	}
+	return saleResult, nil
+}
+
+func decodeUpdateCommand(payload []byte) (proto.Message, error) {
+	cmd := &pb.UpdateCommand{}
+	err := proto.Unmarshal(payload, cmd)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling UpdateCommand")
+		return nil, err
+	}
+	for _, path := range cmd.GetUpdateMask().GetPaths() {
+		if findInventoryField(path) == nil {
+			err = fmt.Errorf("UpdateCommand Error: unknown field %q in update_mask", path)
+			return nil, err
+		}
+	}
+	return cmd, nil
+}
+
+// updateFromProto returns the update with the values its JSON-form decodes to.
+// Only the fields in update_mask are updated, since blank fields can't be
+// told apart from fields that aren't set. The filter matches its set fields.
+func updateFromProto(cmd *pb.UpdateCommand) *inventoryUpdate {
+	filter := cmd.Filter
+	if filter == nil {
+		filter = &pb.Inventory{}
+	}
+	update := cmd.Update
+	if update == nil {
+		update = &pb.Inventory{}
+	}
+
+	u := &inventoryUpdate{
+		Filter: inventoryFromProto(filter),
+		Update: map[string]interface{}{},
+	}
+	for _, path := range cmd.GetUpdateMask().GetPaths() {
+		// Paths are checked when decoding the command
+		if f := findInventoryField(path); f != nil {
+			u.Update[f.json] = f.value(update)
+		}
+	}
+	return u
+}
+
+func encodeUpdateResult(result []byte) (proto.Message, error) {
+	r := updateResult{}
+	err := json.Unmarshal(result, &r)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling update-result")
+		return nil, err
+	}
+	return &pb.UpdateResult{
+		MatchedCount:  r.MatchedCount,
+		ModifiedCount: r.ModifiedCount,
+	}, nil
+}
+
+func encodeValidationResult(result []byte) (proto.Message, error) {
+	v := validationResult{}
+	err := json.Unmarshal(result, &v)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling validation-result")
+		return nil, err
+	}
+	return &pb.ValidationResult{FieldErrors: fieldErrorsToProto(v.FieldErrors)}, nil
+}
+
+func fieldErrorsToProto(fieldErrs ValidationErrors) []*pb.FieldError {
+	if len(fieldErrs) == 0 {
+		return nil
+	}
+	errs := make([]*pb.FieldError, len(fieldErrs))
+	for i, fe := range fieldErrs {
+		errs[i] = &pb.FieldError{Field: fe.Field, Message: fe.Message}
+	}
+	return errs
+}
+
+// uuidString returns the UUID-string, or blank for zero UUID.
+func uuidString(id uuuid.UUID) string {
+	if id == (uuuid.UUID{}) {
+		return ""
+	}
+	return id.String()
+}
+
+// decimalString returns the decimal-string, or blank for zero.
+func decimalString(d Decimal) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.String()
+}
+
+func decimalPtrString(d *Decimal) string {
+	if d == nil {
+		return ""
+	}
+	return decimalString(*d)
+}
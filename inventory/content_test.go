@@ -0,0 +1,279 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/TerrexTech/agg-inventory-cmd/pb"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	"github.com/golang/protobuf/proto"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/genproto/protobuf/field_mask"
+)
+
+var _ = Describe("Content", func() {
+	var itemID uuuid.UUID
+
+	BeforeEach(func() {
+		var err error
+		itemID, err = uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	protoEvent := func(
+		eventAction string, serviceAction string, msg proto.Message,
+	) *model.Event {
+		payload, err := proto.Marshal(msg)
+		Expect(err).ToNot(HaveOccurred())
+		return &model.Event{
+			AggregateID:   2,
+			EventAction:   eventAction,
+			ServiceAction: serviceAction,
+			Data:          MarkContent(ContentTypeProtobuf, payload),
+		}
+	}
+
+	protoResult := func(doc *model.Document, msg proto.Message) {
+		contentType, payload, err := ParseContent(doc.Result)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(contentType).To(Equal(ContentTypeProtobuf))
+		err = proto.Unmarshal(payload, msg)
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	It("should parse unmarked data as JSON", func() {
+		data := []byte(`{"itemID": "test"}`)
+		contentType, payload, err := ParseContent(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(contentType).To(Equal(ContentTypeJSON))
+		Expect(payload).To(Equal(data))
+		Expect(MarkContent(ContentTypeJSON, data)).To(Equal(data))
+	})
+
+	It("should parse marked data", func() {
+		data := MarkContent(ContentTypeProtobuf, []byte{0x0a, 0x00})
+		contentType, payload, err := ParseContent(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(contentType).To(Equal(ContentTypeProtobuf))
+		Expect(payload).To(Equal([]byte{0x0a, 0x00}))
+
+		_, _, err = ParseContent([]byte{0x00, 'a'})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should leave JSON event-data and results unchanged", func() {
+		data := []byte(`{"itemID": "` + itemID.String() + `"}`)
+		mockEvent := &model.Event{EventAction: "insert", Data: data}
+		ctx, contentType, doc := DecodeEventContent(context.Background(), mockEvent)
+		Expect(doc).To(BeNil())
+		Expect(contentType).To(Equal(ContentTypeJSON))
+		Expect(mockEvent.Data).To(Equal(data))
+		Expect(protoRequest(ctx)).To(BeNil())
+
+		doc = &model.Document{Result: data}
+		Expect(EncodeDocumentContent(mockEvent, contentType, doc)).To(Equal(doc))
+	})
+
+	It("should round-trip Protobuf inserts", func() {
+		item := &pb.Inventory{
+			ItemId:      itemID.String(),
+			Name:        "test-name",
+			TotalWeight: "12.5",
+			Price:       "2.25",
+			Timestamp:   10,
+			OnFlashSale: true,
+		}
+		mockEvent := protoEvent("insert", "", &pb.InsertCommand{Item: item})
+		_, contentType, doc := DecodeEventContent(context.Background(), mockEvent)
+		Expect(doc).To(BeNil())
+		Expect(contentType).To(Equal(ContentTypeProtobuf))
+		Expect(FindCommandSchema("insert", "").Validate(mockEvent.Data)).To(BeNil())
+
+		inv := &Inventory{}
+		err := json.Unmarshal(mockEvent.Data, inv)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inv.ItemID).To(Equal(itemID))
+		Expect(inv.TotalWeight.String()).To(Equal("12.5"))
+
+		result, err := json.Marshal(inv)
+		Expect(err).ToNot(HaveOccurred())
+		doc = EncodeDocumentContent(mockEvent, contentType, &model.Document{
+			Result: result,
+		})
+		insertResult := &pb.InsertResult{}
+		protoResult(doc, insertResult)
+		Expect(proto.Equal(insertResult.Item, item)).To(BeTrue())
+	})
+
+	It("should round-trip Protobuf bulk-inserts", func() {
+		mockEvent := protoEvent("insert", "", &pb.InsertCommand{
+			Items: []*pb.Inventory{
+				&pb.Inventory{ItemId: itemID.String(), Timestamp: 10},
+			},
+			Unordered: true,
+		})
+		_, contentType, doc := DecodeEventContent(context.Background(), mockEvent)
+		Expect(doc).To(BeNil())
+		bulk, isBulk := parseBulkInsert(mockEvent.Data)
+		Expect(isBulk).To(BeTrue())
+		Expect(bulk.Items).To(HaveLen(1))
+
+		result, err := json.Marshal(BulkInsertResult{
+			InsertedCount: 1,
+			Items: []BulkInsertItemResult{
+				BulkInsertItemResult{ItemID: itemID.String(), Inserted: true},
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		doc = EncodeDocumentContent(mockEvent, contentType, &model.Document{
+			Result: result,
+		})
+		insertResult := &pb.InsertResult{}
+		protoResult(doc, insertResult)
+		Expect(insertResult.Item).To(BeNil())
+		Expect(insertResult.Bulk.InsertedCount).To(Equal(int32(1)))
+		Expect(insertResult.Bulk.Items[0].ItemId).To(Equal(itemID.String()))
+	})
+
+	It("should pass Protobuf sales to handlers in ctx", func() {
+		cmd := &pb.SaleCommand{
+			Items: []*pb.SaleLine{
+				&pb.SaleLine{ItemId: itemID.String(), Weight: "1.5"},
+			},
+			PartialFulfillment: true,
+		}
+		mockEvent := protoEvent("update", "createSale", cmd)
+		data := mockEvent.Data
+		ctx, contentType, doc := DecodeEventContent(context.Background(), mockEvent)
+		Expect(doc).To(BeNil())
+		Expect(contentType).To(Equal(ContentTypeProtobuf))
+		Expect(mockEvent.Data).To(Equal(data))
+		Expect(proto.Equal(protoRequest(ctx), cmd)).To(BeTrue())
+		Expect(checkSchema(ctx, "update", mockEvent)).To(BeNil())
+
+		request, err := saleRequest(ctx, mockEvent)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(request).To(Equal(map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"itemID": itemID.String(), "weight": "1.5"},
+			},
+			"partialFulfillment": true,
+		}))
+
+		weight := decimalFloat(1.5)
+		result, err := json.Marshal(SaleValidationResp{
+			OriginalRequest: request,
+			Result: []SaleItemResult{
+				SaleItemResult{
+					ItemID:          itemID,
+					TotalSoldWeight: weight,
+					TotalWeight:     decimalInt(10),
+				},
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		doc = EncodeDocumentContent(mockEvent, contentType, &model.Document{
+			Result: result,
+		})
+		saleResult := &pb.SaleResult{}
+		protoResult(doc, saleResult)
+		Expect(saleResult.OriginalRequest.Items[0].Weight).To(Equal("1.5"))
+		Expect(saleResult.Result[0].ItemId).To(Equal(itemID.String()))
+		Expect(saleResult.Result[0].TotalSoldWeight).To(Equal("1.5"))
+		Expect(saleResult.Result[0].TotalWeight).To(Equal("10"))
+	})
+
+	It("should check Protobuf sales against the sale-schema", func() {
+		mockEvent := protoEvent("update", "createSale", &pb.SaleCommand{
+			Items: []*pb.SaleLine{&pb.SaleLine{ItemId: itemID.String()}},
+		})
+		ctx, _, doc := DecodeEventContent(context.Background(), mockEvent)
+		Expect(doc).To(BeNil())
+		doc = checkSchema(ctx, "update", mockEvent)
+		Expect(doc).ToNot(BeNil())
+		Expect(doc.ErrorCode).To(Equal(int16(ValidationError)))
+	})
+
+	It("should update only the fields in update_mask", func() {
+		mockEvent := protoEvent("update", "", &pb.UpdateCommand{
+			Filter: &pb.Inventory{ItemId: itemID.String()},
+			Update: &pb.Inventory{TotalWeight: "5", Lot: "test-lot", Timestamp: 10},
+			UpdateMask: &field_mask.FieldMask{
+				Paths: []string{"total_weight", "name", "timestamp"},
+			},
+		})
+		ctx, contentType, doc := DecodeEventContent(context.Background(), mockEvent)
+		Expect(doc).To(BeNil())
+		Expect(checkSchema(ctx, "update", mockEvent)).To(BeNil())
+
+		cmd, isUpdate := protoRequest(ctx).(*pb.UpdateCommand)
+		Expect(isUpdate).To(BeTrue())
+		u := updateFromProto(cmd)
+		Expect(u.Filter).To(Equal(map[string]interface{}{"itemID": itemID.String()}))
+		Expect(u.Update).To(Equal(map[string]interface{}{
+			"totalWeight": "5",
+			"name":        "",
+			"timestamp":   int64(10),
+		}))
+
+		result, err := json.Marshal(updateResult{MatchedCount: 1, ModifiedCount: 1})
+		Expect(err).ToNot(HaveOccurred())
+		doc = EncodeDocumentContent(mockEvent, contentType, &model.Document{
+			Result: result,
+		})
+		updateResult := &pb.UpdateResult{}
+		protoResult(doc, updateResult)
+		Expect(updateResult.MatchedCount).To(Equal(int64(1)))
+		Expect(updateResult.ModifiedCount).To(Equal(int64(1)))
+	})
+
+	It("should return error for unknown fields in update_mask", func() {
+		mockEvent := protoEvent("update", "", &pb.UpdateCommand{
+			Filter:     &pb.Inventory{ItemId: itemID.String()},
+			UpdateMask: &field_mask.FieldMask{Paths: []string{"totalWeight"}},
+		})
+		_, _, doc := DecodeEventContent(context.Background(), mockEvent)
+		Expect(doc.ErrorCode).To(Equal(int16(UserError)))
+	})
+
+	It("should encode validation-errors as Protobuf", func() {
+		mockEvent := protoEvent("insert", "", &pb.InsertCommand{
+			Item: &pb.Inventory{Name: "test-name"},
+		})
+		ctx, contentType, doc := DecodeEventContent(context.Background(), mockEvent)
+		Expect(doc).To(BeNil())
+		doc = checkSchema(ctx, "insert", mockEvent)
+		Expect(doc.ErrorCode).To(Equal(int16(ValidationError)))
+
+		doc = EncodeDocumentContent(mockEvent, contentType, doc)
+		result := &pb.ValidationResult{}
+		protoResult(doc, result)
+		Expect(result.FieldErrors).ToNot(BeEmpty())
+		Expect(result.FieldErrors[0].Field).To(Equal("itemID"))
+	})
+
+	It("should return error for unsupported content", func() {
+		ctx := context.Background()
+		mockEvent := protoEvent("delete", "", &pb.InsertCommand{})
+		_, _, doc := DecodeEventContent(ctx, mockEvent)
+		Expect(doc.ErrorCode).To(Equal(int16(UserError)))
+
+		// Commands with schemas of their own don't accept UpdateCommand
+		mockEvent = protoEvent("update", "cancelSale", &pb.UpdateCommand{})
+		_, _, doc = DecodeEventContent(ctx, mockEvent)
+		Expect(doc.ErrorCode).To(Equal(int16(UserError)))
+
+		mockEvent = &model.Event{
+			EventAction: "insert",
+			Data:        MarkContent("application/xml", []byte("<item/>")),
+		}
+		_, _, doc = DecodeEventContent(ctx, mockEvent)
+		Expect(doc.ErrorCode).To(Equal(int16(UserError)))
+
+		mockEvent = protoEvent("insert", "", &pb.InsertCommand{})
+		_, _, doc = DecodeEventContent(ctx, mockEvent)
+		Expect(doc.ErrorCode).To(Equal(int16(UserError)))
+	})
+})
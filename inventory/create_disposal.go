@@ -0,0 +1,90 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// DisposalResp is the response when waste or donation is recorded.
+type DisposalResp struct {
+	OriginalRequest map[string]interface{} `json:"originalRequest,omitempty"`
+	Result          []SaleItemResult       `json:"result,omitempty"`
+}
+
+// createDisposal handles "createWaste" and "createDonation" events, which add
+// the specified item-weights to waste or donate weights respectively.
+func createDisposal(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	collection *mongo.Collection,
+	event *model.Event,
+	tenant uuuid.UUID,
+) *model.Document {
+	m, err := saleRequest(ctx, event)
+	if err != nil {
+		err = errors.Wrap(err, "Disposal-Event: Error unmarshalling disposal-data")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	items, assertOK := m["items"].([]interface{})
+	if !assertOK {
+		err = errors.New("error asserting Items to array")
+		err = errors.Wrap(err, "Disposal-Event")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     UserError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	result := validateSaleItems(
+		ctx, etcd, collection, event, items, tenant, saleOptions{},
+	)
+	marshalResult, err := json.Marshal(DisposalResp{
+		OriginalRequest: m,
+		Result:          result,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Disposal-Event: Error marshalling result")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        marshalResult,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
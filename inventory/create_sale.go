@@ -8,25 +8,40 @@ import (
 	"os"
 	"time"
 
-	"github.com/coreos/etcd/clientv3"
+	clientv3 "go.etcd.io/etcd/client/v3"
 
 	"github.com/TerrexTech/go-kafkautils/kafka"
 
-	"github.com/TerrexTech/go-commonutils/commonutil"
 	"github.com/TerrexTech/go-eventstore-models/model"
 	"github.com/TerrexTech/go-mongoutils/mongo"
 	"github.com/TerrexTech/uuuid"
-	"github.com/coreos/etcd/clientv3/concurrency"
 	"github.com/pkg/errors"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // SaleItemResult is the result from updating the sale-item.
 type SaleItemResult struct {
-	ItemID          uuuid.UUID `json:"itemID,omitempty"`
-	Error           string     `json:"error,omitempty"`
-	ErrorCode       int        `json:"errorCode,omitempty"`
-	TotalSoldWeight float64    `json:"totalSoldWeight,omitempty"`
-	TotalWeight     float64    `json:"totalWeight,omitempty"`
+	ItemID            uuuid.UUID `json:"itemID,omitempty"`
+	Barcode           string     `json:"barcode,omitempty"`
+	SKU               string     `json:"sku,omitempty"`
+	Error             string     `json:"error,omitempty"`
+	ErrorCode         int        `json:"errorCode,omitempty"`
+	TotalSoldWeight   Decimal    `json:"totalSoldWeight"`
+	TotalWasteWeight  Decimal    `json:"totalWasteWeight"`
+	TotalDonateWeight Decimal    `json:"totalDonateWeight"`
+	TotalWeight       Decimal    `json:"totalWeight"`
+	// AllocatedWeight is the weight drawn down from item for a SKU sale-line.
+	AllocatedWeight *Decimal `json:"allocatedWeight,omitempty"`
+	// Requested, fulfilled, and short weights are set for partial fulfillment.
+	RequestedWeight *Decimal `json:"requestedWeight,omitempty"`
+	FulfilledWeight *Decimal `json:"fulfilledWeight,omitempty"`
+	ShortWeight     *Decimal `json:"shortWeight,omitempty"`
+	// ReservationConsumed is true if the sale used weight held by the item's
+	// reservation.
+	ReservationConsumed bool `json:"reservationConsumed,omitempty"`
+	// Unit is the unit for all weights in the result.
+	Unit Unit `json:"unit,omitempty"`
 }
 
 // SaleValidationResp is the response when a sale is validated.
@@ -35,15 +50,35 @@ type SaleValidationResp struct {
 	Result          []SaleItemResult       `json:"result,omitempty"`
 }
 
+// saleOptions are the request-level options for sale-lines.
+type saleOptions struct {
+	// reservationID is the reservation consumed by the sale.
+	reservationID uuuid.UUID
+	// partial sells the available weight of lines that exceed it,
+	// instead of rejecting the lines.
+	partial bool
+}
+
 var producer *kafka.Producer
 
+// PublishSaleEvents controls if validated sales are published to Sale Aggregate.
+// This is disabled when replaying events, since the events were already
+// published when they were first processed.
+var PublishSaleEvents = true
+
+// ItemLockPrefix returns the etcd key-prefix used for locking the specified item.
+func ItemLockPrefix(itemID string) string {
+	return fmt.Sprintf("/%s/", itemID)
+}
+
 func createSale(
+	ctx context.Context,
 	etcd *clientv3.Client,
 	collection *mongo.Collection,
 	event *model.Event,
+	tenant uuuid.UUID,
 ) *model.Document {
-	m := map[string]interface{}{}
-	err := json.Unmarshal(event.Data, &m)
+	m, err := saleRequest(ctx, event)
 	if err != nil {
 		err = errors.Wrap(err, "SaleCreated-Event: Error unmarshalling sale-data")
 		log.Println(err)
@@ -59,6 +94,9 @@ func createSale(
 	}
 
 	if m["items"] == nil {
+		err = errors.New("missing items")
+		err = errors.Wrap(err, "SaleCreated-Event")
+		log.Println(err)
 		return &model.Document{
 			AggregateID:   event.AggregateID,
 			CorrelationID: event.CorrelationID,
@@ -86,7 +124,45 @@ func createSale(
 		}
 	}
 
-	result := validateSaleItems(etcd, collection, event, items)
+	// Sale consumes the reservation held for its items, if any
+	opts := saleOptions{}
+	if m["reservationID"] != nil {
+		reservationIDStr, _ := m["reservationID"].(string)
+		opts.reservationID, err = uuuid.FromString(reservationIDStr)
+		if err != nil {
+			err = errors.Wrap(err, "SaleCreated-Event: Error parsing ReservationID")
+			log.Println(err)
+			return &model.Document{
+				AggregateID:   event.AggregateID,
+				CorrelationID: event.CorrelationID,
+				Error:         err.Error(),
+				ErrorCode:     UserError,
+				EventAction:   "insert",
+				ServiceAction: event.ServiceAction,
+				UUID:          event.UUID,
+			}
+		}
+	}
+
+	if m["partialFulfillment"] != nil {
+		opts.partial, assertOK = m["partialFulfillment"].(bool)
+		if !assertOK {
+			err = errors.New("error asserting partialFulfillment to bool")
+			err = errors.Wrap(err, "SaleCreated-Event")
+			log.Println(err)
+			return &model.Document{
+				AggregateID:   event.AggregateID,
+				CorrelationID: event.CorrelationID,
+				Error:         err.Error(),
+				ErrorCode:     UserError,
+				EventAction:   "insert",
+				ServiceAction: event.ServiceAction,
+				UUID:          event.UUID,
+			}
+		}
+	}
+
+	result := validateSaleItems(ctx, etcd, collection, event, items, tenant, opts)
 
 	marshalResult, err := json.Marshal(SaleValidationResp{
 		OriginalRequest: m,
@@ -106,18 +182,24 @@ func createSale(
 		}
 	}
 
-	if producer == nil {
-		kafkaBrokersStr := os.Getenv("KAFKA_BROKERS")
-		producer, err = kafka.NewProducer(&kafka.ProducerConfig{
-			KafkaBrokers: *commonutil.ParseHosts(kafkaBrokersStr),
-		})
-		if err != nil {
-			err = errors.Wrap(err, "ValidateSale: Error creating producer")
-			log.Println(err)
-			return nil
+	if !PublishSaleEvents {
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			EventAction:   "insert",
+			Result:        marshalResult,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
 		}
 	}
 
+	_, err = loadProducer()
+	if err != nil {
+		err = errors.Wrap(err, "ValidateSale")
+		log.Println(err)
+		return nil
+	}
+
 	uuid, err := uuuid.NewV4()
 	if err != nil {
 		err = errors.Wrap(err, "UpdateSale: Error generating UUID for SaleValidation")
@@ -126,14 +208,14 @@ func createSale(
 	}
 	topic := os.Getenv("KAFKA_PRODUCER_EVENT_TOPIC")
 	saleEvent, err := json.Marshal(model.Event{
-		AggregateID:   3,
+		AggregateID:   SaleAggregateID,
 		CorrelationID: event.CorrelationID,
 		Data:          marshalResult,
 		EventAction:   "insert",
 		NanoTime:      time.Now().UnixNano(),
 		ServiceAction: event.ServiceAction,
 		UUID:          uuid,
-		YearBucket:    2018,
+		YearBucket:    EventYearBucket,
 	})
 	if err != nil {
 		err = errors.Wrap(err, "CreateSale: Error Marshalling result")
@@ -141,7 +223,18 @@ func createSale(
 		return nil
 	}
 
-	producer.Input() <- kafka.CreateMessage(topic, saleEvent)
+	publishCtx, publishSpan := StartSpan(
+		ctx, "kafka.publish", attribute.String("messaging.destination", topic),
+	)
+	msg := kafka.CreateMessage(topic, saleEvent)
+	injectTraceParent(publishCtx, msg)
+	err = produce(ctx, msg)
+	EndSpan(publishSpan, err)
+	if err != nil {
+		// Sale is already applied, so only the error is logged
+		err = errors.Wrap(err, "SaleCreated-Event: Error publishing sale-event")
+		log.Println(err)
+	}
 
 	return &model.Document{
 		AggregateID:   event.AggregateID,
@@ -154,13 +247,20 @@ func createSale(
 }
 
 func validateSaleItems(
+	ctx context.Context,
 	etcd *clientv3.Client,
 	collection *mongo.Collection,
 	event *model.Event,
 	items []interface{},
+	tenant uuuid.UUID,
+	opts saleOptions,
 ) []SaleItemResult {
 	result := []SaleItemResult{}
+	now := eventTime(event)
 
+	items, result = expandSKULines(
+		collection, items, event.ServiceAction, tenant, opts, now,
+	)
 	for _, item := range items {
 		itemMap, assertOK := item.(map[string]interface{})
 		if !assertOK {
@@ -173,6 +273,20 @@ func validateSaleItems(
 			})
 			continue
 		}
+		if itemMap["barcode"] != nil {
+			barcode, _ := itemMap["barcode"].(string)
+			errCode, err := resolveBarcodeLine(collection, itemMap, tenant)
+			if err != nil {
+				err = errors.Wrap(err, "SaleCreated-Event: Error resolving barcode")
+				log.Println(err)
+				result = append(result, SaleItemResult{
+					Barcode:   barcode,
+					Error:     err.Error(),
+					ErrorCode: errCode,
+				})
+				continue
+			}
+		}
 		if itemMap["itemID"] == nil {
 			err := errors.New("missing ItemID")
 			err = errors.Wrap(err, "SaleCreated-Event")
@@ -193,16 +307,17 @@ func validateSaleItems(
 				Error:     err.Error(),
 				ErrorCode: UserError,
 			})
+			continue
 		}
 		itemID, err := uuuid.FromString(itemIDStr)
 		if err != nil {
-			err := errors.New("error parsing ItemID")
-			err = errors.Wrap(err, "SaleCreated-Event")
+			err = errors.Wrap(err, "SaleCreated-Event: Error parsing ItemID")
 			log.Println(err)
 			result = append(result, SaleItemResult{
 				Error:     err.Error(),
-				ErrorCode: InternalError,
+				ErrorCode: UserError,
 			})
+			continue
 		}
 
 		if itemMap["weight"] == nil {
@@ -218,7 +333,7 @@ func validateSaleItems(
 		}
 
 		// Get this early as we remove it below to match document in Mongo
-		soldWeight, err := commonutil.AssertFloat64(itemMap["weight"])
+		soldWeight, err := assertDecimal(itemMap["weight"])
 		if err != nil {
 			err := errors.New("error asserting sold-item weight")
 			err = errors.Wrap(err, "SaleCreated-Event")
@@ -231,12 +346,62 @@ func validateSaleItems(
 			continue
 		}
 
+		// Weight is in item's unit if no unit is specified
+		var saleUnit Unit
+		if itemMap["unit"] != nil {
+			unitStr, assertOK := itemMap["unit"].(string)
+			if !assertOK {
+				unitStr = fmt.Sprintf("%v", itemMap["unit"])
+			}
+			saleUnit, err = ParseUnit(unitStr)
+			if err != nil {
+				err = errors.Wrap(err, "SaleCreated-Event")
+				log.Println(err)
+				result = append(result, SaleItemResult{
+					ItemID:    itemID,
+					Error:     err.Error(),
+					ErrorCode: UserError,
+				})
+				continue
+			}
+		}
+
 		// So it can match document in Mongo
 		delete(itemMap, "weight")
 
+		err = checkContext(ctx)
+		if err != nil {
+			err = errors.Wrapf(err, "SaleCreatedEvent: ItemID: %s", itemIDStr)
+			log.Println(err)
+			result = append(result, SaleItemResult{
+				ItemID:    itemID,
+				Error:     err.Error(),
+				ErrorCode: Timeout,
+			})
+			continue
+		}
+		_, lockSpan := StartSpan(ctx, "etcd.lock", attribute.String("itemID", itemIDStr))
+		err = EtcdBreaker.Allow()
+		if err != nil {
+			EndSpan(lockSpan, err)
+			err = errors.Wrapf(
+				err,
+				"SaleCreatedEvent: Failed to obtain lock for Updating ItemID: %s",
+				itemIDStr,
+			)
+			log.Println(err)
+			result = append(result, SaleItemResult{
+				ItemID:    itemID,
+				Error:     err.Error(),
+				ErrorCode: Unavailable,
+			})
+			continue
+		}
 		lockTTL := concurrency.WithTTL(25)
 		lockSession, err := concurrency.NewSession(etcd, lockTTL)
 		if err != nil {
+			EtcdBreaker.Failure()
+			EndSpan(lockSpan, err)
 			err = errors.Wrapf(
 				err,
 				"SaleCreatedEvent: Failed to obtain lock for Updating ItemID: %s",
@@ -246,22 +411,40 @@ func validateSaleItems(
 			result = append(result, SaleItemResult{
 				ItemID:    itemID,
 				Error:     err.Error(),
-				ErrorCode: InternalError,
+				ErrorCode: Unavailable,
 			})
 			continue
 		}
 		defer lockSession.Close()
-		prefix := fmt.Sprintf("/%s/", itemIDStr)
-		mx := concurrency.NewMutex(lockSession, prefix)
+		mx := concurrency.NewMutex(lockSession, ItemLockPrefix(itemIDStr))
 
-		lockCtx, lockCancel := context.WithTimeout(context.Background(), 25*time.Second)
+		lockCtx, lockCancel := context.WithTimeout(ctx, 25*time.Second)
 		defer lockCancel()
 		err = mx.Lock(lockCtx)
 
 		unlockCtx, unlockCancel := context.WithTimeout(context.Background(), 25*time.Second)
 		defer unlockCancel()
 		defer mx.Unlock(unlockCtx)
+		EndSpan(lockSpan, err)
+		if err != nil && ctx.Err() != nil {
+			// Command stopped, so this is not an etcd-failure
+			EtcdBreaker.Cancel()
+			err = errors.Wrapf(
+				ctx.Err(),
+				"SaleCreatedEvent: Stopped waiting for lock on ItemID: %s",
+				itemIDStr,
+			)
+			log.Println(err)
+			result = append(result, SaleItemResult{
+				ItemID:    itemID,
+				Error:     err.Error(),
+				ErrorCode: Timeout,
+			})
+			lockSession.Close()
+			continue
+		}
 		if err != nil {
+			EtcdBreaker.Failure()
 			err = errors.Wrapf(
 				err,
 				"SaleCreatedEvent: Failed to apply obtained lock for ItemID: %s",
@@ -271,22 +454,26 @@ func validateSaleItems(
 			result = append(result, SaleItemResult{
 				ItemID:    itemID,
 				Error:     err.Error(),
-				ErrorCode: InternalError,
+				ErrorCode: Unavailable,
 			})
 			lockSession.Close()
 			continue
 		}
+		EtcdBreaker.Success()
 		findArgs := map[string]interface{}{
 			"itemID": itemIDStr,
 		}
+		_, findSpan := StartSpan(ctx, "mongo.findOne", attribute.String("itemID", itemIDStr))
 		findResult, err := collection.FindOne(findArgs)
+		EndSpan(findSpan, err)
 		if err != nil {
+			errCode := mongoErrorCode(err)
 			err := errors.Wrap(err, "SaleCreated-Event: Error getting Item from database")
 			log.Println(err)
 			result = append(result, SaleItemResult{
 				ItemID:    itemID,
 				Error:     err.Error(),
-				ErrorCode: DatabaseError,
+				ErrorCode: errCode,
 			})
 			continue
 		}
@@ -303,21 +490,207 @@ func validateSaleItems(
 			})
 			continue
 		}
+		err = checkItemTenant(event, tenant, inv)
+		if err != nil {
+			err = errors.Wrap(err, "SaleCreated-Event")
+			log.Println(err)
+			result = append(result, SaleItemResult{
+				ItemID:    itemID,
+				Error:     err.Error(),
+				ErrorCode: TenantError,
+			})
+			continue
+		}
+
+		itemUnit := inv.WeightUnit()
+		weight := soldWeight
+		if saleUnit != "" {
+			weight, err = ConvertWeight(soldWeight, saleUnit, itemUnit)
+			if err != nil {
+				err = errors.Wrap(err, "SaleCreated-Event: Error converting weight")
+				log.Println(err)
+				result = append(result, SaleItemResult{
+					ItemID:    itemID,
+					Error:     err.Error(),
+					ErrorCode: UserError,
+				})
+				continue
+			}
+		}
 
-		var totalSoldWeight float64
+		itemResult := SaleItemResult{
+			ItemID:      itemID,
+			TotalWeight: inv.TotalWeight,
+			Unit:        itemUnit,
+		}
+		isSale := event.ServiceAction == "createSale" ||
+			event.ServiceAction == "createFlashSale"
+		// Weight held by other reservations is not available for sales
+		reserved := Decimal{}
+		if isSale {
+			reserved, err = reservedWeight(itemID, now, opts.reservationID)
+			if err != nil {
+				errCode := mongoErrorCode(err)
+				err = errors.Wrap(err, "SaleCreated-Event: Error getting reserved weight")
+				log.Println(err)
+				result = append(result, SaleItemResult{
+					ItemID:    itemID,
+					Error:     err.Error(),
+					ErrorCode: errCode,
+				})
+				continue
+			}
+		}
+
+		halfWeight, err := deliveredWeight(weight)
+		if err != nil {
+			result = append(result, weightErrorResult(itemID, err))
+			continue
+		}
+		available, err := lineAvailableWeight(inv, event.ServiceAction, reserved)
+		if err != nil {
+			result = append(result, weightErrorResult(itemID, err))
+			continue
+		}
+		isSKULine := itemMap["sku"] != nil && itemMap["barcode"] == nil
+		if isSKULine && !opts.partial && weight.GreaterThan(available) {
+			// Item changed since the SKU-line was allocated, before the
+			// item was locked
+			err := fmt.Errorf(
+				"allocated weight %s exceeds the available weight %s of item",
+				weight, available,
+			)
+			err = errors.Wrap(err, "SaleCreated-Event")
+			log.Println(err)
+			sku, _ := itemMap["sku"].(string)
+			result = append(result, SaleItemResult{
+				ItemID:    itemID,
+				SKU:       sku,
+				Error:     err.Error(),
+				ErrorCode: Conflict,
+			})
+			continue
+		}
+		fulfilledWeight := soldWeight
+		if isSale && opts.partial {
+			requestedWeight := weight
+			if weight.GreaterThan(available) {
+				weight = available
+				halfWeight, err = deliveredWeight(weight)
+				if err != nil {
+					result = append(result, weightErrorResult(itemID, err))
+					continue
+				}
+				// Sale-event carries the fulfilled weight in sale-line's unit
+				fulfilledWeight = weight
+				if saleUnit != "" {
+					fulfilledWeight, err = ConvertWeight(weight, itemUnit, saleUnit)
+					if err != nil {
+						err = errors.Wrap(err, "SaleCreated-Event: Error converting weight")
+						log.Println(err)
+						result = append(result, SaleItemResult{
+							ItemID:    itemID,
+							Error:     err.Error(),
+							ErrorCode: UserError,
+						})
+						continue
+					}
+				}
+			}
+			shortWeight, err := requestedWeight.Sub(weight)
+			if err != nil {
+				result = append(result, weightErrorResult(itemID, err))
+				continue
+			}
+			itemResult.RequestedWeight = &requestedWeight
+			itemResult.FulfilledWeight = &weight
+			itemResult.ShortWeight = &shortWeight
+
+			if !halfWeight.GreaterThan(Decimal{}) {
+				err := errors.New("no weight available to fulfill sale")
+				err = errors.Wrap(err, "SaleCreated-Event")
+				log.Println(err)
+				itemResult.Error = err.Error()
+				itemResult.ErrorCode = UserError
+				result = append(result, itemResult)
+				continue
+			}
+		}
+		if isSKULine {
+			itemResult.SKU, _ = itemMap["sku"].(string)
+			allocatedWeight := weight
+			itemResult.AllocatedWeight = &allocatedWeight
+		}
 		updateArgs := map[string]interface{}{}
 		if event.ServiceAction == "createFlashSale" {
-			totalSoldWeight = inv.FlashSaleWeight + (soldWeight / 2)
+			flashSaleWeight, err := inv.FlashSaleWeight.Add(halfWeight)
+			usedWeight := Decimal{}
+			if err == nil {
+				usedWeight, err = sumDecimals(
+					inv.SoldWeight, inv.WasteWeight, inv.DonateWeight,
+					flashSaleWeight, reserved,
+				)
+			}
+			if err != nil {
+				result = append(result, weightErrorResult(itemID, err))
+				continue
+			}
+			if !reserved.IsZero() && usedWeight.GreaterThan(inv.TotalWeight) {
+				err := errors.New("sale-weight exceeds the available weight not reserved")
+				err = errors.Wrap(err, "SaleCreated-Event")
+				log.Println(err)
+				result = append(result, SaleItemResult{
+					ItemID:    itemID,
+					Error:     err.Error(),
+					ErrorCode: UserError,
+				})
+				continue
+			}
+			itemResult.TotalSoldWeight = flashSaleWeight
 			updateArgs["onFlashSale"] = true
-			updateArgs["flashSaleWeight"] = totalSoldWeight
-			log.Println("*****************")
-			log.Printf("%+v", updateArgs)
+			updateArgs["flashSaleWeight"] = itemResult.TotalSoldWeight.Decimal128()
 		} else {
-			// Temporary fix for duplicated message-issue
-			totalSoldWeight = inv.SoldWeight + (soldWeight / 2)
-			cumWeight := totalSoldWeight + inv.WasteWeight + inv.DonateWeight
-			if cumWeight > inv.TotalWeight {
-				err := errors.New("sale-weight exceeds the total available weight")
+			totalSoldWeight := inv.SoldWeight
+			totalWasteWeight := inv.WasteWeight
+			totalDonateWeight := inv.DonateWeight
+			exceedsMsg := "sale-weight exceeds the total available weight"
+
+			switch event.ServiceAction {
+			case "createWaste":
+				totalWasteWeight, err = totalWasteWeight.Add(weight)
+				updateArgs["wasteWeight"] = totalWasteWeight.Decimal128()
+				itemResult.TotalWasteWeight = totalWasteWeight
+				exceedsMsg = "waste-weight exceeds the total available weight"
+			case "createDonation":
+				totalDonateWeight, err = totalDonateWeight.Add(weight)
+				updateArgs["donateWeight"] = totalDonateWeight.Decimal128()
+				itemResult.TotalDonateWeight = totalDonateWeight
+				exceedsMsg = "donate-weight exceeds the total available weight"
+			default:
+				totalSoldWeight, err = totalSoldWeight.Add(halfWeight)
+				updateArgs["soldWeight"] = totalSoldWeight.Decimal128()
+				itemResult.TotalSoldWeight = totalSoldWeight
+			}
+
+			cumWeight := Decimal{}
+			reservedCumWeight := Decimal{}
+			if err == nil {
+				cumWeight, err = sumDecimals(
+					totalSoldWeight, totalWasteWeight, totalDonateWeight,
+				)
+			}
+			if err == nil {
+				reservedCumWeight, err = cumWeight.Add(reserved)
+			}
+			if err != nil {
+				result = append(result, weightErrorResult(itemID, err))
+				continue
+			}
+			if reservedCumWeight.GreaterThan(inv.TotalWeight) {
+				if !cumWeight.GreaterThan(inv.TotalWeight) {
+					exceedsMsg = "sale-weight exceeds the available weight not reserved"
+				}
+				err := errors.New(exceedsMsg)
 				err = errors.Wrap(err, "SaleCreated-Event")
 				log.Println(err)
 				result = append(result, SaleItemResult{
@@ -327,21 +700,61 @@ func validateSaleItems(
 				})
 				continue
 			}
-			updateArgs = map[string]interface{}{
-				"soldWeight": totalSoldWeight,
-			}
 		}
+		err = checkContext(ctx)
+		if err != nil {
+			err = errors.Wrapf(err, "SaleCreatedEvent: ItemID: %s", itemIDStr)
+			log.Println(err)
+			result = append(result, SaleItemResult{
+				ItemID:    itemID,
+				Error:     err.Error(),
+				ErrorCode: Timeout,
+			})
+			continue
+		}
+		_, updateSpan := StartSpan(
+			ctx, "mongo.updateMany", attribute.String("itemID", itemIDStr),
+		)
 		updateResult, err := collection.UpdateMany(findArgs, updateArgs)
+		EndSpan(updateSpan, err)
 		if err != nil {
+			errCode := mongoErrorCode(err)
 			err = errors.Wrap(err, "SaleCreated-Event: Error writing new weight to database")
 			log.Println(err)
 			result = append(result, SaleItemResult{
 				ItemID:    itemID,
 				Error:     err.Error(),
-				ErrorCode: DatabaseError,
+				ErrorCode: errCode,
 			})
 			continue
 		}
+		var ledgerErr error
+		if isSale && updateResult.ModifiedCount > 0 {
+			// Sale-line is recorded while item is locked
+			ledgerErr = recordSaleLineWithRetry(
+				ctx, event.CorrelationID, itemID, event.ServiceAction, halfWeight,
+			)
+		}
+		if ledgerErr != nil {
+			ledgerErr = errors.Wrap(ledgerErr, "SaleCreated-Event: Error recording sale-line")
+			log.Println(ledgerErr)
+			// Sales without sale-lines cannot be reversed, so the sale of
+			// this item is undone
+			err = restoreItemWeights(collection, inv, updateArgs)
+			if err != nil {
+				// Item is sold without sale-line, so this must be fixed manually
+				err = errors.Wrap(err, "SaleCreated-Event")
+				log.Println(err)
+			}
+		} else if isSale && updateResult.ModifiedCount > 0 {
+			itemResult.ReservationConsumed, err = consumeReservation(
+				opts.reservationID, itemID, now, halfWeight,
+			)
+			if err != nil {
+				err = errors.Wrap(err, "SaleCreated-Event: Error consuming reservation")
+				log.Println(err)
+			}
+		}
 		err = mx.Unlock(context.Background())
 		if err != nil {
 			err = errors.Wrapf(
@@ -358,7 +771,19 @@ func validateSaleItems(
 			)
 			log.Println(err)
 		}
-		itemMap["weight"] = soldWeight
+		itemMap["weight"] = fulfilledWeight
+		if fulfilledWeight != soldWeight {
+			itemMap["requestedWeight"] = soldWeight
+		}
+
+		if ledgerErr != nil {
+			result = append(result, SaleItemResult{
+				ItemID:    itemID,
+				Error:     ledgerErr.Error(),
+				ErrorCode: DatabaseError,
+			})
+			continue
+		}
 
 		if updateResult.ModifiedCount < 1 {
 			err = errors.New("no items updated")
@@ -383,12 +808,118 @@ func validateSaleItems(
 			continue
 		}
 
-		result = append(result, SaleItemResult{
-			ItemID:          itemID,
-			TotalSoldWeight: totalSoldWeight,
-			TotalWeight:     inv.TotalWeight,
-		})
+		result = append(result, itemResult)
 	}
 
+	// Reported for sales and disposals, so MongoBreaker sees the line-errors
+	for _, r := range result {
+		reportLineError(ctx, r.ErrorCode)
+	}
 	return result
 }
+
+// expandSKULines replaces the sale-lines that specify a SKU, instead of an
+// itemID or barcode, with the lines of items allocated for the SKU. Results
+// are returned for SKU-lines that could not be allocated.
+func expandSKULines(
+	collection *mongo.Collection,
+	items []interface{},
+	action string,
+	tenant uuuid.UUID,
+	opts saleOptions,
+	now int64,
+) ([]interface{}, []SaleItemResult) {
+	expanded := []interface{}{}
+	result := []SaleItemResult{}
+	for _, item := range items {
+		itemMap, assertOK := item.(map[string]interface{})
+		isSKULine := assertOK && itemMap["sku"] != nil &&
+			itemMap["itemID"] == nil && itemMap["barcode"] == nil
+		if !isSKULine {
+			expanded = append(expanded, item)
+			continue
+		}
+
+		sku, _ := itemMap["sku"].(string)
+		lines, errCode, err := allocateSKULine(
+			collection, itemMap, action, tenant, opts, now,
+		)
+		if err != nil {
+			err = errors.Wrap(err, "SaleCreated-Event: Error allocating sku")
+			log.Println(err)
+			result = append(result, SaleItemResult{
+				SKU:       sku,
+				Error:     err.Error(),
+				ErrorCode: errCode,
+			})
+			continue
+		}
+		for _, line := range lines {
+			expanded = append(expanded, line)
+		}
+	}
+	return expanded, result
+}
+
+// saleAvailableWeight returns the weight of item available for sales, not
+// held by reservations. Flash-sale weight is not available for flash-sales.
+func saleAvailableWeight(
+	inv *Inventory, reserved Decimal, flashSale bool,
+) (Decimal, error) {
+	remaining, err := inv.RemainingWeight()
+	if err != nil {
+		return Decimal{}, err
+	}
+	available, err := remaining.Sub(reserved)
+	if err == nil && flashSale {
+		available, err = available.Sub(inv.FlashSaleWeight)
+	}
+	if err != nil {
+		return Decimal{}, err
+	}
+	if available.IsNegative() {
+		return Decimal{}, nil
+	}
+	return available, nil
+}
+
+// lineAvailableWeight returns the largest line-weight of the service-action
+// that item can fulfill. Each delivery of a sale applies the delivered weight
+// of the line (see deliveredWeight), so sales can fulfill lines of up to
+// twice the weight available for sales. Waste and donations apply the full
+// weight of the line, and can use reserved weight.
+func lineAvailableWeight(
+	inv *Inventory, action string, reserved Decimal,
+) (Decimal, error) {
+	switch action {
+	case "createSale", "createFlashSale":
+		available, err := saleAvailableWeight(
+			inv, reserved, action == "createFlashSale",
+		)
+		if err != nil {
+			return Decimal{}, err
+		}
+		return lineWeight(available)
+	default:
+		remaining, err := inv.RemainingWeight()
+		if err != nil {
+			return Decimal{}, err
+		}
+		if remaining.IsNegative() {
+			return Decimal{}, nil
+		}
+		return remaining, nil
+	}
+}
+
+// weightErrorResult is the result for sale-lines whose weights could not be
+// calculated, such as weights out of range.
+func weightErrorResult(itemID uuuid.UUID, err error) SaleItemResult {
+	err = errors.Wrap(err, "SaleCreated-Event: Error calculating weight")
+	log.Println(err)
+	return SaleItemResult{
+		ItemID:    itemID,
+		Error:     err.Error(),
+		ErrorCode: UserError,
+	}
+}
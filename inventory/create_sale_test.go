@@ -0,0 +1,56 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CreateSale", func() {
+	var event *model.Event
+
+	BeforeEach(func() {
+		eventUUID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		event = &model.Event{
+			EventAction:   "update",
+			ServiceAction: "createSale",
+			UUID:          eventUUID,
+		}
+		PublishSaleEvents = false
+	})
+
+	AfterEach(func() {
+		PublishSaleEvents = true
+	})
+
+	It("should reject sales without items", func() {
+		event.Data = []byte(`{"reservationID": ""}`)
+		doc := createSale(context.Background(), nil, nil, event, uuuid.UUID{})
+		Expect(doc.ErrorCode).To(BeEquivalentTo(UserError))
+		Expect(doc.Error).To(ContainSubstring("missing items"))
+	})
+
+	It("should return one result for lines with invalid itemIDs", func() {
+		event.Data = []byte(`{"items": [
+			{"itemID": 3, "weight": 1},
+			{"itemID": "invalid-id", "weight": 1}
+		]}`)
+		doc := createSale(context.Background(), nil, nil, event, uuuid.UUID{})
+		Expect(doc.Error).To(BeEmpty())
+
+		resp := &SaleValidationResp{}
+		err := json.Unmarshal(doc.Result, resp)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Result).To(HaveLen(2))
+		for _, r := range resp.Result {
+			Expect(r.ItemID).To(Equal(uuuid.UUID{}))
+			Expect(r.ErrorCode).To(Equal(UserError))
+		}
+		Expect(resp.Result[1].Error).To(ContainSubstring("Error parsing ItemID"))
+	})
+})
@@ -0,0 +1,79 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/pkg/errors"
+)
+
+// DeadLetterTopic is the topic where failed events are published, along with
+// their failure-context. Failed events are only logged if this is blank.
+var DeadLetterTopic = ""
+
+// Reasons for dead-lettering events.
+const (
+	// DeadLetterRetriesExhausted is for events still failing with retryable
+	// errors after the last retry.
+	DeadLetterRetriesExhausted = "retriesExhausted"
+	// DeadLetterDeadlineExceeded is for events failing with retryable errors,
+	// which passed their deadline, or were cancelled, before the next retry.
+	DeadLetterDeadlineExceeded = "deadlineExceeded"
+	// DeadLetterMalformed is for events that could not be read or parsed.
+	DeadLetterMalformed = "malformed"
+)
+
+// DeadLetter is a failed event, with the context of its failure.
+type DeadLetter struct {
+	Event     model.Event `json:"event"`
+	Reason    string      `json:"reason"`
+	Error     string      `json:"error"`
+	ErrorCode int16       `json:"errorCode,omitempty"`
+	Retryable bool        `json:"retryable"`
+	Attempts  int         `json:"attempts"`
+	// Failures are the errors from each attempt.
+	Failures []string `json:"failures,omitempty"`
+	// Times are in Unix-nanoseconds.
+	FirstFailedAt int64  `json:"firstFailedAt,omitempty"`
+	FailedAt      int64  `json:"failedAt"`
+	Service       string `json:"service,omitempty"`
+}
+
+// DeadLetterMalformedEvent publishes the event that could not be read or
+// parsed to dead-letter topic.
+func DeadLetterMalformedEvent(event *model.Event, err error) {
+	deadLetter(&DeadLetter{
+		Event:    *event,
+		Reason:   DeadLetterMalformed,
+		Error:    err.Error(),
+		Attempts: 1,
+	})
+}
+
+// deadLetter logs the dead-letter, and publishes it to DeadLetterTopic.
+func deadLetter(dl *DeadLetter) {
+	dl.FailedAt = time.Now().UnixNano()
+	dl.Service = os.Getenv("SERVICE_NAME")
+
+	marshalDL, err := json.Marshal(dl)
+	if err != nil {
+		err = errors.Wrap(err, "DeadLetter: Error marshalling dead-letter")
+		log.Println(err)
+		return
+	}
+	log.Printf("DeadLetter: %s", marshalDL)
+
+	if DeadLetterTopic == "" {
+		return
+	}
+	// Dead-letters are published even if the command was cancelled
+	err = publishMessage(context.Background(), DeadLetterTopic, marshalDL)
+	if err != nil {
+		err = errors.Wrap(err, "DeadLetter: Error publishing dead-letter")
+		log.Println(err)
+	}
+}
@@ -0,0 +1,69 @@
+package inventory
+
+import (
+	"context"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/pkg/errors"
+)
+
+// CommandTimeout is the deadline for processing commands, including retries.
+var CommandTimeout = 30 * time.Second
+
+// ActionTimeouts are the deadlines for specific ServiceActions (or EventActions),
+// overriding CommandTimeout.
+var ActionTimeouts = map[string]time.Duration{}
+
+// TimeoutForEvent returns the deadline for processing the command-event.
+func TimeoutForEvent(event *model.Event) time.Duration {
+	if timeout, ok := ActionTimeouts[event.ServiceAction]; ok {
+		return timeout
+	}
+	if timeout, ok := ActionTimeouts[event.EventAction]; ok {
+		return timeout
+	}
+	return CommandTimeout
+}
+
+// CommandContext returns the context for processing the command-event, which
+// is cancelled when the parent is cancelled or the command's deadline passes.
+func CommandContext(
+	parent context.Context,
+	event *model.Event,
+) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, TimeoutForEvent(event))
+}
+
+// checkContext returns an error if the command passed its deadline or was
+// cancelled. Mongo-operations cannot be cancelled once started, so handlers
+// check this before each write.
+func checkContext(ctx context.Context) error {
+	err := ctx.Err()
+	if err != nil {
+		err = errors.Wrap(err, "Command stopped")
+		return err
+	}
+	return nil
+}
+
+// isContextError checks if the error is from a cancelled context or one that
+// passed its deadline.
+func isContextError(err error) bool {
+	cause := errors.Cause(err)
+	return cause == context.DeadlineExceeded || cause == context.Canceled
+}
+
+// timeoutDoc returns the Document for commands that passed their deadline,
+// or were cancelled.
+func timeoutDoc(event *model.Event, err error) *model.Document {
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		Error:         err.Error(),
+		ErrorCode:     Timeout,
+		EventAction:   event.EventAction,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
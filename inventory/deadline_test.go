@@ -0,0 +1,69 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Deadline", func() {
+	var (
+		defaultTimeout time.Duration
+		defaultActions map[string]time.Duration
+	)
+
+	BeforeEach(func() {
+		defaultTimeout = CommandTimeout
+		defaultActions = ActionTimeouts
+		CommandTimeout = time.Second
+		ActionTimeouts = map[string]time.Duration{
+			"createSale": 2 * time.Second,
+			"delete":     3 * time.Second,
+		}
+	})
+
+	AfterEach(func() {
+		CommandTimeout = defaultTimeout
+		ActionTimeouts = defaultActions
+	})
+
+	It("should use per-action deadlines, falling back to CommandTimeout", func() {
+		Expect(TimeoutForEvent(&model.Event{
+			EventAction:   "update",
+			ServiceAction: "createSale",
+		})).To(Equal(2 * time.Second))
+		Expect(TimeoutForEvent(&model.Event{
+			EventAction:   "delete",
+			ServiceAction: "deleteItem",
+		})).To(Equal(3 * time.Second))
+		Expect(TimeoutForEvent(&model.Event{
+			EventAction:   "update",
+			ServiceAction: "updateItem",
+		})).To(Equal(time.Second))
+	})
+
+	It("should set the command's deadline on its context", func() {
+		ctx, cancel := CommandContext(context.Background(), &model.Event{
+			ServiceAction: "createSale",
+		})
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		Expect(ok).To(BeTrue())
+		Expect(time.Until(deadline)).To(BeNumerically("~", 2*time.Second, 100*time.Millisecond))
+	})
+
+	It("should report cancelled contexts", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		Expect(checkContext(ctx)).To(Succeed())
+		cancel()
+
+		err := checkContext(ctx)
+		Expect(err).To(HaveOccurred())
+		Expect(isContextError(err)).To(BeTrue())
+		Expect(isContextError(errors.New("other error"))).To(BeFalse())
+	})
+})
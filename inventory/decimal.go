@@ -0,0 +1,287 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/mongodb/mongo-go-driver/bson/decimal"
+	"github.com/pkg/errors"
+)
+
+// decimalPlaces is the number of decimal-places stored by Decimal.
+const decimalPlaces = 6
+
+// decimalScale is the number of Decimal units in one whole number.
+const decimalScale = 1000000
+
+// maxDecimalWhole bounds the whole numbers that Decimal can hold.
+const maxDecimalWhole = math.MaxInt64 / decimalScale
+
+// maxDecimalUnits bounds the units of Decimal. Constructors and arithmetic
+// return errors for values out of range, instead of overflowing.
+const maxDecimalUnits = maxDecimalWhole * decimalScale
+
+// Limits for parsing decimal-strings, so malformed values cannot make parsing
+// slow or use excessive memory.
+const (
+	maxDecimalLength   = 64
+	maxDecimalExponent = 64
+)
+
+// decimalHalf is the Decimal 0.5.
+var decimalHalf = Decimal{decimalScale / 2}
+
+// Decimal is a fixed-point number with six decimal-places. It's used for
+// weights and prices, so repeated additions don't accumulate rounding-errors
+// like float64 does. Decimal is stored as Decimal128 in BSON, and as a
+// number in JSON.
+type Decimal struct {
+	units int64
+}
+
+// DecimalFromFloat returns the Decimal nearest to the float.
+func DecimalFromFloat(f float64) (Decimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}, fmt.Errorf("invalid decimal %f", f)
+	}
+	if math.Abs(f) >= maxDecimalWhole {
+		return Decimal{}, fmt.Errorf("decimal out of range: %g", f)
+	}
+	return decimalFromUnits(int64(math.Round(f * decimalScale)))
+}
+
+// DecimalFromInt returns the Decimal for the whole number.
+func DecimalFromInt(i int64) (Decimal, error) {
+	if i >= maxDecimalWhole || i <= -maxDecimalWhole {
+		return Decimal{}, fmt.Errorf("decimal out of range: %d", i)
+	}
+	return Decimal{i * decimalScale}, nil
+}
+
+// decimalFromUnits returns the Decimal with the units, or error if the units
+// are out of range.
+func decimalFromUnits(units int64) (Decimal, error) {
+	if units >= maxDecimalUnits || units <= -maxDecimalUnits {
+		return Decimal{}, fmt.Errorf(
+			"decimal out of range: %s", Decimal{units}.String(),
+		)
+	}
+	return Decimal{units}, nil
+}
+
+// ParseDecimal parses a decimal-string, such as "12.345" or "1.2e3".
+// Values with more than six decimal-places are rounded. Values too large
+// for Decimal are returned as errors.
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > maxDecimalLength {
+		return Decimal{}, fmt.Errorf("invalid decimal, exceeds %d characters", maxDecimalLength)
+	}
+	// Rat also parses fractions and hex-floats, which are not decimals
+	for _, c := range s {
+		if (c < '0' || c > '9') && !strings.ContainsRune("+-.eE", c) {
+			return Decimal{}, fmt.Errorf("invalid decimal %s", s)
+		}
+	}
+	if e := strings.IndexAny(s, "eE"); e >= 0 {
+		exp, err := strconv.Atoi(s[e+1:])
+		if err != nil || exp > maxDecimalExponent || exp < -maxDecimalExponent {
+			return Decimal{}, fmt.Errorf("invalid decimal %s", s)
+		}
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal %s", s)
+	}
+	if new(big.Rat).Abs(r).Cmp(big.NewRat(maxDecimalWhole, 1)) >= 0 {
+		return Decimal{}, fmt.Errorf("decimal out of range: %s", s)
+	}
+	return decimalFromRat(r)
+}
+
+// decimalFromRat returns the Decimal nearest to the Rat, rounding half away
+// from zero. Returns error if the Rat is out of range.
+func decimalFromRat(r *big.Rat) (Decimal, error) {
+	scaled := new(big.Rat).Mul(r, big.NewRat(decimalScale, 1))
+	num := scaled.Num()
+	den := scaled.Denom()
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	// Round half away from zero: compare 2*|rem| with denominator
+	rem.Abs(rem).Lsh(rem, 1)
+	if rem.Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	if !quo.IsInt64() {
+		return Decimal{}, fmt.Errorf("decimal out of range: %s", r.FloatString(1))
+	}
+	return decimalFromUnits(quo.Int64())
+}
+
+// assertDecimal converts the value, as decoded from JSON or BSON, to Decimal.
+// Float-values from existing documents are rounded to six decimal-places.
+func assertDecimal(v interface{}) (Decimal, error) {
+	switch t := v.(type) {
+	case Decimal:
+		return t, nil
+	case float64:
+		return DecimalFromFloat(t)
+	case float32:
+		return assertDecimal(float64(t))
+	case int:
+		return assertDecimal(int64(t))
+	case int32:
+		return DecimalFromInt(int64(t))
+	case int64:
+		return DecimalFromInt(t)
+	case json.Number:
+		return ParseDecimal(t.String())
+	case string:
+		return ParseDecimal(t)
+	case decimal.Decimal128:
+		return ParseDecimal(t.String())
+	default:
+		return Decimal{}, fmt.Errorf("cannot convert %T to decimal", v)
+	}
+}
+
+// Add returns d + o, or error if the sum is out of range.
+func (d Decimal) Add(o Decimal) (Decimal, error) {
+	sum := d.units + o.units
+	// The sum wrapped around if it moved opposite to the sign of o
+	if (sum > d.units) != (o.units > 0) {
+		return Decimal{}, fmt.Errorf("decimal out of range: %s + %s", d, o)
+	}
+	return decimalFromUnits(sum)
+}
+
+// Sub returns d - o, or error if the difference is out of range.
+func (d Decimal) Sub(o Decimal) (Decimal, error) {
+	// Units are in range, so negating them cannot overflow
+	return d.Add(Decimal{-o.units})
+}
+
+// Mul returns d * o, rounded to six decimal-places, or error if the product
+// is out of range.
+func (d Decimal) Mul(o Decimal) (Decimal, error) {
+	return decimalFromRat(new(big.Rat).Mul(d.Rat(), o.Rat()))
+}
+
+// Div returns d / o, rounded to six decimal-places.
+func (d Decimal) Div(o Decimal) (Decimal, error) {
+	if o.IsZero() {
+		return Decimal{}, errors.New("division by zero")
+	}
+	return decimalFromRat(new(big.Rat).Quo(d.Rat(), o.Rat()))
+}
+
+// sumDecimals returns the sum of the Decimals, or error if any partial sum
+// is out of range.
+func sumDecimals(ds ...Decimal) (Decimal, error) {
+	sum := Decimal{}
+	for _, d := range ds {
+		var err error
+		sum, err = sum.Add(d)
+		if err != nil {
+			return Decimal{}, err
+		}
+	}
+	return sum, nil
+}
+
+// Cmp returns -1 if d < o, 0 if d == o, and 1 if d > o.
+func (d Decimal) Cmp(o Decimal) int {
+	switch {
+	case d.units < o.units:
+		return -1
+	case d.units > o.units:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// GreaterThan checks if d > o.
+func (d Decimal) GreaterThan(o Decimal) bool {
+	return d.units > o.units
+}
+
+// LessThan checks if d < o.
+func (d Decimal) LessThan(o Decimal) bool {
+	return d.units < o.units
+}
+
+// IsZero checks if d is zero.
+func (d Decimal) IsZero() bool {
+	return d.units == 0
+}
+
+// IsNegative checks if d is less than zero.
+func (d Decimal) IsNegative() bool {
+	return d.units < 0
+}
+
+// Rat returns the exact value of d as Rat.
+func (d Decimal) Rat() *big.Rat {
+	return big.NewRat(d.units, decimalScale)
+}
+
+// Float64 returns the float nearest to d.
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// String returns d in decimal-notation, without trailing zeros.
+func (d Decimal) String() string {
+	units := d.units
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	whole := units / decimalScale
+	frac := units % decimalScale
+	if frac == 0 {
+		return sign + strconv.FormatInt(whole, 10)
+	}
+
+	fracStr := fmt.Sprintf("%0*d", decimalPlaces, frac)
+	fracStr = strings.TrimRight(fracStr, "0")
+	return fmt.Sprintf("%s%d.%s", sign, whole, fracStr)
+}
+
+// Decimal128 returns d as BSON Decimal128.
+func (d Decimal) Decimal128() decimal.Decimal128 {
+	// String-format of Decimal is always valid for parsing
+	d128, _ := decimal.ParseDecimal128(d.String())
+	return d128
+}
+
+// MarshalJSON returns d as JSON-number.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON parses d from a JSON-number or string.
+func (d *Decimal) UnmarshalJSON(in []byte) error {
+	s := strings.Trim(string(in), `"`)
+	if s == "null" {
+		return nil
+	}
+	parsed, err := ParseDecimal(s)
+	if err != nil {
+		err = errors.Wrap(err, "Unmarshal Error")
+		return err
+	}
+	*d = parsed
+	return nil
+}
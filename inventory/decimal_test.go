@@ -0,0 +1,165 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/mongodb/mongo-go-driver/bson/decimal"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Decimal", func() {
+	It("should parse and format decimals", func() {
+		d, err := ParseDecimal("12.345")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.String()).To(Equal("12.345"))
+
+		d, err = ParseDecimal("-0.5")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.String()).To(Equal("-0.5"))
+
+		_, err = ParseDecimal("abc")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should round to six decimal-places", func() {
+		d, err := ParseDecimal("1.0000005")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.String()).To(Equal("1.000001"))
+
+		d, err = ParseDecimal("-1.0000005")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.String()).To(Equal("-1.000001"))
+	})
+
+	It("should reject decimals out of range", func() {
+		d, err := ParseDecimal("9223372036853.999999")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.String()).To(Equal("9223372036853.999999"))
+
+		for _, s := range []string{
+			"9223372036854", "-9223372036854", "1e300", "1e99999999", "0x1p9999", "1/3",
+		} {
+			_, err = ParseDecimal(s)
+			Expect(err).To(HaveOccurred(), s)
+		}
+
+		_, err = assertDecimal(1e13)
+		Expect(err).To(HaveOccurred())
+		_, err = assertDecimal(int64(9223372036854))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject arithmetic out of range", func() {
+		max, err := ParseDecimal("9223372036853.999999")
+		Expect(err).ToNot(HaveOccurred())
+		min := Decimal{-max.units}
+		tiny := Decimal{1}
+
+		d, err := max.Sub(tiny)
+		Expect(err).ToNot(HaveOccurred())
+		d, err = d.Add(tiny)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d).To(Equal(max))
+		_, err = max.Add(tiny)
+		Expect(err).To(HaveOccurred())
+		_, err = min.Sub(tiny)
+		Expect(err).To(HaveOccurred())
+		_, err = max.Add(max)
+		Expect(err).To(HaveOccurred())
+		_, err = min.Add(min)
+		Expect(err).To(HaveOccurred())
+		_, err = sumDecimals(max, tiny, min)
+		Expect(err).To(HaveOccurred())
+
+		d, err = DecimalFromInt(3037000)
+		Expect(err).ToNot(HaveOccurred())
+		d, err = d.Mul(d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d).To(Equal(decimalInt(9223369000000)))
+		_, err = d.Mul(decimalInt(2))
+		Expect(err).To(HaveOccurred())
+		_, err = max.Div(decimalFloat(0.5))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject conversions out of range", func() {
+		_, err := DecimalFromInt(9223372036853)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = DecimalFromInt(9223372036854)
+		Expect(err).To(HaveOccurred())
+		_, err = DecimalFromInt(-9223372036854)
+		Expect(err).To(HaveOccurred())
+
+		_, err = DecimalFromFloat(9.2e12)
+		Expect(err).ToNot(HaveOccurred())
+		for _, f := range []float64{
+			9.3e12, -9.3e12, math.MaxFloat64, math.Inf(1), math.Inf(-1), math.NaN(),
+		} {
+			_, err = DecimalFromFloat(f)
+			Expect(err).To(HaveOccurred(), fmt.Sprint(f))
+		}
+
+		_, err = decimalFromRat(new(big.Rat).SetInt64(9223372036853))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = decimalFromRat(new(big.Rat).SetInt64(9223372036854))
+		Expect(err).To(HaveOccurred())
+		_, err = decimalFromRat(new(big.Rat).SetFrac64(math.MaxInt64, 1))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should not accumulate errors on repeated additions", func() {
+		sum := Decimal{}
+		step := decimalFloat(0.1)
+		for i := 0; i < 1000; i++ {
+			var err error
+			sum, err = sum.Add(step)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(sum).To(Equal(decimalInt(100)))
+	})
+
+	It("should convert values decoded from JSON and BSON", func() {
+		d, err := assertDecimal(13.4)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d).To(Equal(decimalFloat(13.4)))
+
+		d128, err := decimal.ParseDecimal128("13.4")
+		Expect(err).ToNot(HaveOccurred())
+		d, err = assertDecimal(d128)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d).To(Equal(decimalFloat(13.4)))
+	})
+
+	It("should marshal to JSON-number", func() {
+		marshal, err := json.Marshal(decimalFloat(2.25))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(marshal)).To(Equal("2.25"))
+
+		var d Decimal
+		err = json.Unmarshal([]byte(`"2.25"`), &d)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d).To(Equal(decimalFloat(2.25)))
+	})
+})
+
+// decimalInt returns the Decimal for test-values known to be in range.
+func decimalInt(i int64) Decimal {
+	d, err := DecimalFromInt(i)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// decimalFloat returns the Decimal for test-values known to be in range.
+func decimalFloat(f float64) Decimal {
+	d, err := DecimalFromFloat(f)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
@@ -0,0 +1,190 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+)
+
+// decodeBSONMap decodes the BSON-document into a map. Malformed documents
+// are returned as errors, even if the BSON-decoder panics on them.
+func decodeBSONMap(in []byte) (m map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			m = nil
+			err = fmt.Errorf("malformed BSON-document: %v", r)
+		}
+	}()
+
+	m = make(map[string]interface{})
+	err = bson.Unmarshal(in, m)
+	return m, err
+}
+
+// fieldDecoder decodes fields from a map, as decoded from JSON or BSON, and
+// collects a FieldError for every malformed value instead of panicking on it.
+// Fields that are missing or null are left unchanged.
+type fieldDecoder struct {
+	m    map[string]interface{}
+	errs ValidationErrors
+}
+
+// fail records the field-error for the value of field.
+func (d *fieldDecoder) fail(field string, format string, args ...interface{}) {
+	d.errs = append(d.errs, FieldError{
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// err returns the field-errors, or nil if all fields were decoded.
+func (d *fieldDecoder) err() error {
+	if len(d.errs) == 0 {
+		return nil
+	}
+	return d.errs
+}
+
+func (d *fieldDecoder) string(field string, out *string) {
+	if d.m[field] == nil {
+		return
+	}
+	s, assertOK := d.m[field].(string)
+	if !assertOK {
+		d.fail(field, "must be a string, got %s", valueType(d.m[field]))
+		return
+	}
+	*out = s
+}
+
+func (d *fieldDecoder) bool(field string, out *bool) {
+	if d.m[field] == nil {
+		return
+	}
+	b, assertOK := d.m[field].(bool)
+	if !assertOK {
+		d.fail(field, "must be a boolean, got %s", valueType(d.m[field]))
+		return
+	}
+	*out = b
+}
+
+func (d *fieldDecoder) uuid(field string, out *uuuid.UUID) {
+	if d.m[field] == nil {
+		return
+	}
+	s, assertOK := d.m[field].(string)
+	if !assertOK {
+		d.fail(field, "must be a UUID-string, got %s", valueType(d.m[field]))
+		return
+	}
+	id, err := uuuid.FromString(s)
+	if err != nil {
+		d.fail(field, "invalid UUID %q", s)
+		return
+	}
+	*out = id
+}
+
+func (d *fieldDecoder) objectID(field string, out *objectid.ObjectID) {
+	switch v := d.m[field].(type) {
+	case nil:
+	case objectid.ObjectID:
+		*out = v
+	case string:
+		id, err := objectid.FromHex(v)
+		if err != nil {
+			d.fail(field, "invalid ObjectID %q", v)
+			return
+		}
+		*out = id
+	default:
+		d.fail(field, "must be an ObjectID, got %s", valueType(v))
+	}
+}
+
+func (d *fieldDecoder) int64(field string, out *int64) {
+	if d.m[field] == nil {
+		return
+	}
+	i, err := assertInt64(d.m[field])
+	if err != nil {
+		d.fail(field, "%s", err)
+		return
+	}
+	*out = i
+}
+
+func (d *fieldDecoder) decimal(field string, out *Decimal) {
+	if d.m[field] == nil {
+		return
+	}
+	dec, err := assertDecimal(d.m[field])
+	if err != nil {
+		d.fail(field, "%s", err)
+		return
+	}
+	*out = dec
+}
+
+// assertInt64 converts the value, as decoded from JSON or BSON, to int64.
+// Fractional numbers are truncated, as done for existing timestamps.
+func assertInt64(v interface{}) (int64, error) {
+	switch t := v.(type) {
+	case int64:
+		return t, nil
+	case int32:
+		return int64(t), nil
+	case int:
+		return int64(t), nil
+	case float64:
+		// float64(math.MaxInt64) rounds up to 2^63, which is out of range
+		if math.IsNaN(t) || t < math.MinInt64 || t >= math.MaxInt64 {
+			return 0, fmt.Errorf("integer out of range: %v", t)
+		}
+		return int64(t), nil
+	case float32:
+		return assertInt64(float64(t))
+	case json.Number:
+		i, err := t.Int64()
+		if err == nil {
+			return i, nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("invalid integer %s", t)
+		}
+		return assertInt64(f)
+	case string:
+		i, err := strconv.ParseInt(t, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid integer %q", t)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("must be an integer, got %s", valueType(v))
+	}
+}
+
+// valueType returns the name of value's type for field-errors.
+func valueType(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, float32, int, int32, int64, json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
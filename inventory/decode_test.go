@@ -0,0 +1,166 @@
+package inventory
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inventory decoding", func() {
+	It("should return a field-error for each malformed value", func() {
+		inv := &Inventory{}
+		err := json.Unmarshal([]byte(`{
+			"_id": 12,
+			"itemID": 34,
+			"rsCustomerID": "not-a-uuid",
+			"lot": true,
+			"onFlashSale": "yes",
+			"timestamp": "soon",
+			"totalWeight": [1]
+		}`), inv)
+		Expect(err).To(HaveOccurred())
+
+		fieldErrs, isFieldErr := err.(ValidationErrors)
+		Expect(isFieldErr).To(BeTrue())
+		fields := []string{}
+		for _, fe := range fieldErrs {
+			fields = append(fields, fe.Field)
+		}
+		Expect(fields).To(ConsistOf(
+			"_id", "itemID", "rsCustomerID", "lot", "onFlashSale", "timestamp", "totalWeight",
+		))
+	})
+
+	It("should decode JSON-numbers exactly", func() {
+		inv := &Inventory{}
+		err := json.Unmarshal([]byte(`{
+			"timestamp": 1539000000123456789,
+			"dateArrived": 1539000000.5,
+			"totalWeight": 12345678901.123456
+		}`), inv)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inv.Timestamp).To(Equal(int64(1539000000123456789)))
+		Expect(inv.DateArrived).To(Equal(int64(1539000000)))
+		Expect(inv.TotalWeight.String()).To(Equal("12345678901.123456"))
+	})
+
+	It("should reject decimals and integers out of range", func() {
+		for _, data := range []string{
+			`{"totalWeight": 1e300}`,
+			`{"totalWeight": "1e99999999"}`,
+			`{"totalWeight": "0x1p9999"}`,
+			`{"totalWeight": 9223372036854.775807}`,
+			`{"timestamp": 1e300}`,
+		} {
+			err := json.Unmarshal([]byte(data), &Inventory{})
+			Expect(err).To(HaveOccurred(), data)
+			_, isFieldErr := err.(ValidationErrors)
+			Expect(isFieldErr).To(BeTrue(), data)
+		}
+	})
+
+	It("should return field-errors for malformed BSON-values", func() {
+		data, err := bson.Marshal(map[string]interface{}{
+			"_id":         int32(1),
+			"itemID":      int64(2),
+			"totalWeight": "heavy",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = (&Inventory{}).UnmarshalBSON(data)
+		fieldErrs, isFieldErr := err.(ValidationErrors)
+		Expect(isFieldErr).To(BeTrue())
+		Expect(fieldErrs).To(HaveLen(3))
+
+		err = (&Inventory{}).UnmarshalBSON([]byte{5, 0, 0, 0, 1})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+// fuzzSeedInventory is the seed-item for fuzzing Inventory decoding.
+func fuzzSeedInventory() *Inventory {
+	itemID, _ := uuuid.FromString("a6a2f34c-3ec5-4da6-8d4e-0e0b6b1a8b39")
+	return &Inventory{
+		ID:          objectid.New(),
+		ItemID:      itemID,
+		DateArrived: 1539000000,
+		Lot:         "lot-1",
+		Name:        "Apple",
+		SKU:         "sku-1",
+		TotalWeight: decimalFloat(120.5),
+		SoldWeight:  decimalFloat(20.25),
+		Unit:        "kg",
+	}
+}
+
+// FuzzInventoryJSON checks that decoding JSON never panics, that malformed
+// values are reported as errors, and that decoded items round-trip.
+func FuzzInventoryJSON(f *testing.F) {
+	seed, _ := fuzzSeedInventory().MarshalJSON()
+	f.Add(seed)
+	f.Add([]byte(`{"itemID": 12, "_id": 34, "totalWeight": "1e9"}`))
+	f.Add([]byte(`{"timestamp": 1e300, "price": [], "onFlashSale": "true"}`))
+	f.Add([]byte(`null`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		inv := &Inventory{}
+		err := inv.UnmarshalJSON(data)
+		if err != nil {
+			return
+		}
+
+		marshalled, err := inv.MarshalJSON()
+		if err != nil {
+			t.Fatalf("error marshalling decoded item: %s", err)
+		}
+		decoded := &Inventory{}
+		err = decoded.UnmarshalJSON(marshalled)
+		if err != nil {
+			t.Fatalf("error decoding marshalled item %s: %s", marshalled, err)
+		}
+		if *decoded != *inv {
+			t.Fatalf("item changed on round-trip: %+v != %+v", decoded, inv)
+		}
+	})
+}
+
+// FuzzInventoryBSON checks that decoding BSON never panics, that malformed
+// values are reported as errors, and that decoded items round-trip.
+func FuzzInventoryBSON(f *testing.F) {
+	seed, _ := fuzzSeedInventory().MarshalBSON()
+	f.Add(seed)
+	malformed, _ := bson.Marshal(map[string]interface{}{
+		"_id":         int32(1),
+		"itemID":      int64(2),
+		"totalWeight": "heavy",
+		"timestamp":   1e300,
+	})
+	f.Add(malformed)
+	f.Add([]byte{5, 0, 0, 0, 0})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		inv := &Inventory{}
+		err := inv.UnmarshalBSON(data)
+		if err != nil {
+			return
+		}
+
+		marshalled, err := inv.MarshalBSON()
+		if err != nil {
+			t.Fatalf("error marshalling decoded item: %s", err)
+		}
+		decoded := &Inventory{}
+		err = decoded.UnmarshalBSON(marshalled)
+		if err != nil {
+			t.Fatalf("error decoding marshalled item: %s", err)
+		}
+		if *decoded != *inv {
+			t.Fatalf("item changed on round-trip: %+v != %+v", decoded, inv)
+		}
+	})
+}
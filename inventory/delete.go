@@ -1,6 +1,7 @@
 package inventory
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 
@@ -14,10 +15,26 @@ type deleteResult struct {
 }
 
 // Delete handles "delete" events.
-func Delete(collection *mongo.Collection, event *model.Event) *model.Document {
-	filter := map[string]interface{}{}
+// No items are deleted if ctx is cancelled before deleting.
+// Event-data is first checked against the "delete" command-schema.
+func Delete(
+	ctx context.Context,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	errDoc := checkSchema(ctx, "delete", event)
+	if errDoc != nil {
+		return errDoc
+	}
+
+	tenant, err := resolveTenant(ctx, event)
+	if err != nil {
+		err = errors.Wrap(err, "Delete")
+		return tenantErrorDoc(event, err)
+	}
 
-	err := json.Unmarshal(event.Data, &filter)
+	filter := map[string]interface{}{}
+	err = json.Unmarshal(event.Data, &filter)
 	if err != nil {
 		err = errors.Wrap(err, "Delete: Error while unmarshalling Event-data")
 		log.Println(err)
@@ -47,6 +64,18 @@ func Delete(collection *mongo.Collection, event *model.Event) *model.Document {
 		}
 	}
 
+	err = scopeFilter(event, tenant, filter)
+	if err != nil {
+		err = errors.Wrap(err, "Delete")
+		return tenantErrorDoc(event, err)
+	}
+
+	err = checkContext(ctx)
+	if err != nil {
+		err = errors.Wrap(err, "Delete")
+		log.Println(err)
+		return timeoutDoc(event, err)
+	}
 	deleteStats, err := collection.DeleteMany(filter)
 	if err != nil {
 		err = errors.Wrap(err, "Delete: Error in DeleteMany")
@@ -10,3 +10,27 @@ const DatabaseError = 3
 // UserError occurs when there's an error because of user's action.
 // An example would be providing invalid input.
 const UserError = 4
+
+// ValidationError occurs when the provided data fails validation-rules.
+// The Document-result contains the errors for each invalid field.
+const ValidationError = 5
+
+// Conflict occurs when the request conflicts with existing data,
+// such as inserting an itemID that already exists.
+const Conflict = 6
+
+// TenantError occurs when the event accesses items of another tenant
+// (RSCustomerID), or the event's tenant cannot be determined.
+const TenantError = 7
+
+// Unauthorized occurs when the event's user is not allowed to run the command.
+const Unauthorized = 8
+
+// Unavailable occurs when a dependency, such as Mongo or etcd, is temporarily
+// unavailable. The command can be retried.
+const Unavailable = 9
+
+// Timeout occurs when the command passed its deadline, or was cancelled, such as
+// when the service shuts down. Writes already applied by the command are not
+// rolled back, so its result lists what was applied.
+const Timeout = 10
@@ -0,0 +1,143 @@
+package inventory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// gtinLength is the length of canonical GTIN-14 codes.
+const gtinLength = 14
+
+// ParseGTIN parses a UPC-A (12 digits), EAN-13 (13 digits), or GTIN-14 code,
+// verifies its check-digit, and returns the code in canonical GTIN-14 form
+// (zero-padded on the left). Spaces and hyphens in code are ignored.
+func ParseGTIN(code string) (string, error) {
+	digits := stripCodeSeparators(code)
+
+	switch len(digits) {
+	case 12, 13, 14:
+	default:
+		return "", fmt.Errorf(
+			"code must have 12 (UPC-A), 13 (EAN-13), or 14 (GTIN-14) digits, got %d",
+			len(digits),
+		)
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", errors.New("code must only contain digits")
+		}
+	}
+
+	payload := digits[:len(digits)-1]
+	checkDigit := int(digits[len(digits)-1] - '0')
+	expected := gtinCheckDigit(payload)
+	if checkDigit != expected {
+		return "", fmt.Errorf(
+			"invalid check-digit %d, expected %d", checkDigit, expected,
+		)
+	}
+
+	return strings.Repeat("0", gtinLength-len(digits)) + digits, nil
+}
+
+// stripCodeSeparators removes spaces and hyphens from the code.
+func stripCodeSeparators(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+// gtinCheckDigit calculates the GS1 check-digit for the code-payload
+// (the code without its check-digit).
+func gtinCheckDigit(payload string) int {
+	sum := 0
+	// Weights alternate 3, 1, 3... starting from the rightmost payload-digit
+	for i := 0; i < len(payload); i++ {
+		digit := int(payload[len(payload)-1-i] - '0')
+		if i%2 == 0 {
+			sum += digit * 3
+		} else {
+			sum += digit
+		}
+	}
+	return (10 - sum%10) % 10
+}
+
+// isGTINLike checks if the SKU looks like a scanned GTIN (only digits, with
+// a GTIN length), in which case it should have a valid check-digit.
+func isGTINLike(sku string) bool {
+	if len(sku) < 12 || len(sku) > gtinLength {
+		return false
+	}
+	for _, r := range sku {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validateCodes checks UPC and SKU codes, and returns field-errors
+// for invalid codes.
+func validateCodes(upc string, sku string) ValidationErrors {
+	errs := ValidationErrors{}
+	if upc != "" {
+		_, err := ParseGTIN(upc)
+		if err != nil {
+			errs = append(errs, FieldError{
+				Field:   "upc",
+				Message: err.Error(),
+			})
+		}
+	}
+	if isGTINLike(sku) {
+		_, err := ParseGTIN(sku)
+		if err != nil {
+			errs = append(errs, FieldError{
+				Field:   "sku",
+				Message: err.Error(),
+			})
+		}
+	}
+	return errs
+}
+
+// normalizeUpdateCodes validates the UPC and SKU codes in update-args,
+// and sets the normalized GTIN for updated (or cleared) UPC.
+func normalizeUpdateCodes(update map[string]interface{}) ValidationErrors {
+	// GTIN is always derived from UPC
+	delete(update, "gtin")
+
+	upc := ""
+	if update["upc"] != nil {
+		var assertOK bool
+		upc, assertOK = update["upc"].(string)
+		if !assertOK {
+			return ValidationErrors{
+				FieldError{
+					Field:   "upc",
+					Message: "must be a string",
+				},
+			}
+		}
+	}
+	sku, _ := update["sku"].(string)
+
+	errs := validateCodes(upc, sku)
+	if len(errs) > 0 {
+		return errs
+	}
+	if upc != "" {
+		// Error is already checked in validateCodes
+		update["gtin"], _ = ParseGTIN(upc)
+	} else if _, exists := update["upc"]; exists {
+		// Clearing UPC also clears the GTIN derived from it
+		update["gtin"] = ""
+	}
+	return nil
+}
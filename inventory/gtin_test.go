@@ -0,0 +1,61 @@
+package inventory
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GTIN", func() {
+	It("should normalize valid codes to GTIN-14", func() {
+		codes := map[string]string{
+			// UPC-A
+			"036000291452":    "00036000291452",
+			"0 36000-29145 2": "00036000291452",
+			// EAN-13
+			"4006381333931": "04006381333931",
+			// GTIN-14
+			"10614141000415": "10614141000415",
+		}
+		for code, expected := range codes {
+			gtin, err := ParseGTIN(code)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gtin).To(Equal(expected))
+		}
+	})
+
+	It("should reject invalid codes", func() {
+		codes := []string{
+			// Invalid check-digit
+			"036000291453",
+			// Invalid length
+			"12345",
+			// Non-digits
+			"03600029145A",
+		}
+		for _, code := range codes {
+			_, err := ParseGTIN(code)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+
+	It("should set normalized GTIN in update-args", func() {
+		update := map[string]interface{}{
+			"upc":  "036000291452",
+			"gtin": "ignored",
+		}
+		Expect(normalizeUpdateCodes(update)).To(BeNil())
+		Expect(update["gtin"]).To(Equal("00036000291452"))
+	})
+
+	It("should clear GTIN when UPC is cleared", func() {
+		for _, upc := range []interface{}{"", nil} {
+			update := map[string]interface{}{"upc": upc}
+			Expect(normalizeUpdateCodes(update)).To(BeNil())
+			Expect(update).To(HaveKeyWithValue("gtin", ""))
+		}
+
+		update := map[string]interface{}{"sku": "test-sku"}
+		Expect(normalizeUpdateCodes(update)).To(BeNil())
+		Expect(update).ToNot(HaveKey("gtin"))
+	})
+})
@@ -0,0 +1,259 @@
+package inventory
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// defaultIDIndex is the index MongoDB creates on _id. It's never reported
+// as extra or dropped.
+const defaultIDIndex = "_id_"
+
+// IndexKey is a field of an index.
+type IndexKey struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// IndexSpec defines an index on aggregate-collection.
+type IndexSpec struct {
+	Name   string     `json:"name"`
+	Keys   []IndexKey `json:"keys"`
+	Unique bool       `json:"unique,omitempty"`
+}
+
+// Validate checks if the index-spec is well-formed.
+func (s *IndexSpec) Validate() error {
+	if s.Name == "" {
+		return errors.New("IndexSpec Error: name is required")
+	}
+	if s.Name == defaultIDIndex {
+		return fmt.Errorf("IndexSpec Error: name %s is reserved", defaultIDIndex)
+	}
+	if len(s.Keys) == 0 {
+		return fmt.Errorf("IndexSpec Error: index %s has no keys", s.Name)
+	}
+	fields := map[string]bool{}
+	for _, k := range s.Keys {
+		if k.Field == "" {
+			return fmt.Errorf("IndexSpec Error: index %s has key without field", s.Name)
+		}
+		if fields[k.Field] {
+			return fmt.Errorf(
+				"IndexSpec Error: index %s has duplicate field %s", s.Name, k.Field,
+			)
+		}
+		fields[k.Field] = true
+	}
+	return nil
+}
+
+// equal checks if both specs index same keys, in same order and direction,
+// with same uniqueness. Names are not compared.
+func (s *IndexSpec) equal(other *IndexSpec) bool {
+	if s.Unique != other.Unique || len(s.Keys) != len(other.Keys) {
+		return false
+	}
+	for i, k := range s.Keys {
+		if k != other.Keys[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultIndexes returns the indexes for aggregate-collection used when no
+// index-config is provided. These cover the lookups done by command-handlers:
+// itemID for all commands, sku and lot for allocating sales, rsCustomerID
+// for tenant-checks, and projectedDate for stock-projections.
+func DefaultIndexes() []IndexSpec {
+	return []IndexSpec{
+		IndexSpec{
+			Name:   "itemID_index",
+			Keys:   []IndexKey{IndexKey{Field: "itemID"}},
+			Unique: true,
+		},
+		IndexSpec{
+			Name: "timestamp_index",
+			Keys: []IndexKey{IndexKey{Field: "timestamp", Desc: true}},
+		},
+		IndexSpec{
+			Name: "gtin_index",
+			Keys: []IndexKey{IndexKey{Field: "gtin"}},
+		},
+		IndexSpec{
+			Name: "location_index",
+			Keys: []IndexKey{IndexKey{Field: "location"}},
+		},
+		IndexSpec{
+			Name: "sku_index",
+			Keys: []IndexKey{IndexKey{Field: "sku"}},
+		},
+		IndexSpec{
+			Name: "lot_index",
+			Keys: []IndexKey{IndexKey{Field: "lot"}},
+		},
+		IndexSpec{
+			Name: "rsCustomerID_index",
+			Keys: []IndexKey{IndexKey{Field: "rsCustomerID"}},
+		},
+		IndexSpec{
+			Name: "projectedDate_index",
+			Keys: []IndexKey{IndexKey{Field: "projectedDate"}},
+		},
+	}
+}
+
+// ValidateIndexSpecs checks if each spec is well-formed and names are unique.
+// A unique index on itemID is required, since commands identify items by it.
+func ValidateIndexSpecs(specs []IndexSpec) error {
+	names := map[string]bool{}
+	hasItemID := false
+	for i := range specs {
+		s := &specs[i]
+		err := s.Validate()
+		if err != nil {
+			err = errors.Wrapf(err, "Invalid index-spec at index %d", i)
+			return err
+		}
+		if names[s.Name] {
+			return fmt.Errorf("IndexSpec Error: duplicate index-name %s", s.Name)
+		}
+		names[s.Name] = true
+
+		if s.isItemIDIndex() {
+			hasItemID = true
+		}
+	}
+	if !hasItemID {
+		return errors.New("IndexSpec Error: a unique index on itemID is required")
+	}
+	return nil
+}
+
+// isItemIDIndex checks if spec is a unique index on itemID.
+func (s *IndexSpec) isItemIDIndex() bool {
+	return s.Unique && len(s.Keys) == 1 && s.Keys[0].Field == "itemID"
+}
+
+// RequiredIndexes returns the unique index on itemID from specs. Only this
+// index is created with the collection, since commands rely on it. Other
+// indexes are created by ReconcileIndexes.
+func RequiredIndexes(specs []IndexSpec) []IndexSpec {
+	required := []IndexSpec{}
+	for i := range specs {
+		if specs[i].isItemIDIndex() {
+			required = append(required, specs[i])
+		}
+	}
+	return required
+}
+
+// IndexConfigs converts the specs to index-configs for creating collection.
+func IndexConfigs(specs []IndexSpec) []mongo.IndexConfig {
+	configs := []mongo.IndexConfig{}
+	for _, s := range specs {
+		columns := []mongo.IndexColumnConfig{}
+		for _, k := range s.Keys {
+			columns = append(columns, mongo.IndexColumnConfig{
+				Name:        k.Field,
+				IsDescOrder: k.Desc,
+			})
+		}
+		configs = append(configs, mongo.IndexConfig{
+			ColumnConfig: columns,
+			IsUnique:     s.Unique,
+			Name:         s.Name,
+		})
+	}
+	return configs
+}
+
+// IndexManager lists, creates and drops the indexes on a collection.
+type IndexManager interface {
+	List() ([]IndexSpec, error)
+	Create(spec IndexSpec) error
+	Drop(name string) error
+}
+
+// IndexReport is the result of reconciling indexes.
+type IndexReport struct {
+	// Missing are the configured indexes not found on collection.
+	Missing []string
+	// Extra are the indexes on collection that are not configured.
+	Extra []string
+	// Mismatched are the indexes with a configured name, but different keys or
+	// uniqueness. These are not changed, and have to be rebuilt manually.
+	Mismatched []string
+	Created    []string
+	Dropped    []string
+}
+
+// ReconcileIndexes compares the indexes on collection to specs. Missing indexes
+// are created, and extra indexes are dropped if dropExtra is true. Nothing is
+// changed if dryRun is true.
+func ReconcileIndexes(
+	m IndexManager, specs []IndexSpec, dropExtra bool, dryRun bool,
+) (*IndexReport, error) {
+	existing, err := m.List()
+	if err != nil {
+		err = errors.Wrap(err, "Error listing indexes")
+		return nil, err
+	}
+	existingByName := map[string]*IndexSpec{}
+	for i := range existing {
+		existingByName[existing[i].Name] = &existing[i]
+	}
+
+	report := &IndexReport{}
+	configured := map[string]bool{}
+	for i := range specs {
+		s := &specs[i]
+		configured[s.Name] = true
+
+		e, exists := existingByName[s.Name]
+		if !exists {
+			report.Missing = append(report.Missing, s.Name)
+			continue
+		}
+		if !s.equal(e) {
+			report.Mismatched = append(report.Mismatched, s.Name)
+		}
+	}
+	for name := range existingByName {
+		if name != defaultIDIndex && !configured[name] {
+			report.Extra = append(report.Extra, name)
+		}
+	}
+	sort.Strings(report.Extra)
+
+	if dryRun {
+		return report, nil
+	}
+	for i := range specs {
+		s := specs[i]
+		if _, exists := existingByName[s.Name]; exists {
+			continue
+		}
+		err = m.Create(s)
+		if err != nil {
+			err = errors.Wrapf(err, "Error creating index %s", s.Name)
+			return report, err
+		}
+		report.Created = append(report.Created, s.Name)
+	}
+	if dropExtra {
+		for _, name := range report.Extra {
+			err = m.Drop(name)
+			if err != nil {
+				err = errors.Wrapf(err, "Error dropping index %s", name)
+				return report, err
+			}
+			report.Dropped = append(report.Dropped, name)
+		}
+	}
+	return report, nil
+}
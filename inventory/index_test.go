@@ -0,0 +1,138 @@
+package inventory
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type mockIndexManager struct {
+	indexes []IndexSpec
+	created []string
+	dropped []string
+	failOn  string
+}
+
+func (m *mockIndexManager) List() ([]IndexSpec, error) {
+	return m.indexes, nil
+}
+
+func (m *mockIndexManager) Create(spec IndexSpec) error {
+	if spec.Name == m.failOn {
+		return errors.New("some-error")
+	}
+	m.created = append(m.created, spec.Name)
+	return nil
+}
+
+func (m *mockIndexManager) Drop(name string) error {
+	m.dropped = append(m.dropped, name)
+	return nil
+}
+
+var _ = Describe("Indexes", func() {
+	var (
+		specs   []IndexSpec
+		manager *mockIndexManager
+	)
+
+	BeforeEach(func() {
+		specs = []IndexSpec{
+			IndexSpec{
+				Name:   "itemID_index",
+				Keys:   []IndexKey{IndexKey{Field: "itemID"}},
+				Unique: true,
+			},
+			IndexSpec{
+				Name: "sku_index",
+				Keys: []IndexKey{IndexKey{Field: "sku"}},
+			},
+		}
+		manager = &mockIndexManager{
+			indexes: []IndexSpec{
+				IndexSpec{
+					Name: "_id_",
+					Keys: []IndexKey{IndexKey{Field: "_id"}},
+				},
+				IndexSpec{
+					Name:   "itemID_index",
+					Keys:   []IndexKey{IndexKey{Field: "itemID"}},
+					Unique: true,
+				},
+				IndexSpec{
+					Name: "old_index",
+					Keys: []IndexKey{IndexKey{Field: "old"}},
+				},
+			},
+		}
+	})
+
+	It("should validate default indexes", func() {
+		Expect(ValidateIndexSpecs(DefaultIndexes())).To(Succeed())
+	})
+
+	It("should require a unique index on itemID", func() {
+		specs[0].Unique = false
+		Expect(ValidateIndexSpecs(specs)).ToNot(Succeed())
+	})
+
+	It("should only create the itemID-index with collection", func() {
+		required := RequiredIndexes(DefaultIndexes())
+		Expect(required).To(HaveLen(1))
+		Expect(required[0].Name).To(Equal("itemID_index"))
+		Expect(RequiredIndexes(specs)).To(Equal(specs[:1]))
+	})
+
+	It("should return error for duplicate index-names", func() {
+		specs[1].Name = specs[0].Name
+		Expect(ValidateIndexSpecs(specs)).ToNot(Succeed())
+	})
+
+	It("should return error for duplicate fields in index", func() {
+		specs[1].Keys = append(specs[1].Keys, IndexKey{Field: "sku", Desc: true})
+		Expect(ValidateIndexSpecs(specs)).ToNot(Succeed())
+	})
+
+	It("should create missing indexes and report extra indexes", func() {
+		report, err := ReconcileIndexes(manager, specs, false, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Missing).To(Equal([]string{"sku_index"}))
+		Expect(report.Extra).To(Equal([]string{"old_index"}))
+		Expect(report.Created).To(Equal([]string{"sku_index"}))
+		Expect(report.Dropped).To(BeEmpty())
+		Expect(manager.created).To(Equal([]string{"sku_index"}))
+		Expect(manager.dropped).To(BeEmpty())
+	})
+
+	It("should drop extra indexes if enabled", func() {
+		report, err := ReconcileIndexes(manager, specs, true, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Dropped).To(Equal([]string{"old_index"}))
+		Expect(manager.dropped).To(Equal([]string{"old_index"}))
+	})
+
+	It("should not change indexes on dry-run", func() {
+		report, err := ReconcileIndexes(manager, specs, true, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Missing).To(Equal([]string{"sku_index"}))
+		Expect(report.Extra).To(Equal([]string{"old_index"}))
+		Expect(manager.created).To(BeEmpty())
+		Expect(manager.dropped).To(BeEmpty())
+	})
+
+	It("should report indexes with different keys as mismatched", func() {
+		manager.indexes[1].Unique = false
+		report, err := ReconcileIndexes(manager, specs, true, false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Mismatched).To(Equal([]string{"itemID_index"}))
+		Expect(manager.dropped).To(Equal([]string{"old_index"}))
+	})
+
+	It("should return error if creating index fails", func() {
+		manager.failOn = "sku_index"
+		_, err := ReconcileIndexes(manager, specs, true, false)
+		Expect(err).To(HaveOccurred())
+		Expect(manager.dropped).To(BeEmpty())
+	})
+})
@@ -1,20 +1,63 @@
 package inventory
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 
 	"github.com/TerrexTech/go-eventstore-models/model"
 	"github.com/TerrexTech/go-mongoutils/mongo"
-	"github.com/TerrexTech/uuuid"
 	"github.com/mongodb/mongo-go-driver/bson/objectid"
 	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 // Insert handles "insert" events.
-func Insert(collection *mongo.Collection, event *model.Event) *model.Document {
+// The event-data can also contain multiple items, see insertBulk.
+// Inserting an existing itemID is handled as per DuplicateInsertMode,
+// and upserts hold the item-lock from etcd.
+// No items are inserted if ctx is cancelled before inserting.
+// Event-data is first checked against the "insert" command-schema.
+func Insert(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	collection *mongo.Collection,
+	event *model.Event,
+) *model.Document {
+	errDoc := checkSchema(ctx, "insert", event)
+	if errDoc != nil {
+		return errDoc
+	}
+
+	tenant, err := resolveTenant(ctx, event)
+	if err != nil {
+		err = errors.Wrap(err, "Insert")
+		return tenantErrorDoc(event, err)
+	}
+
+	bulk, isBulk := parseBulkInsert(event.Data)
+	if isBulk {
+		return insertBulk(ctx, etcd, collection, event, bulk, tenant)
+	}
+
 	inv := &Inventory{}
-	err := json.Unmarshal(event.Data, inv)
+	err = json.Unmarshal(event.Data, inv)
+	if fieldErrs, isFieldErr := err.(ValidationErrors); isFieldErr {
+		err = errors.Wrap(fieldErrs, "Insert: Malformed Event-data")
+		log.Println(err)
+		// Error is ignored since marshalling field-errors cannot fail
+		result, _ := json.Marshal(validationResult{fieldErrs})
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     ValidationError,
+			EventAction:   event.EventAction,
+			Result:        result,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
 	if err != nil {
 		err = errors.Wrap(err, "Insert: Error while unmarshalling Event-data")
 		log.Println(err)
@@ -29,25 +72,51 @@ func Insert(collection *mongo.Collection, event *model.Event) *model.Document {
 		}
 	}
 
-	if inv.ItemID == (uuuid.UUID{}) {
-		err = errors.New("missing ItemID")
+	err = assignItemTenant(event, tenant, inv)
+	if err != nil {
 		err = errors.Wrap(err, "Insert")
+		return tenantErrorDoc(event, err)
+	}
+
+	validationErrs := validateInsert(inv)
+	if validationErrs != nil {
+		err = errors.Wrap(validationErrs, "Insert")
 		log.Println(err)
+		// Error is ignored since marshalling field-errors cannot fail
+		result, _ := json.Marshal(validationResult{validationErrs})
 		return &model.Document{
 			AggregateID:   event.AggregateID,
 			CorrelationID: event.CorrelationID,
 			Error:         err.Error(),
-			ErrorCode:     InternalError,
+			ErrorCode:     ValidationError,
 			EventAction:   event.EventAction,
+			Result:        result,
 			ServiceAction: event.ServiceAction,
 			UUID:          event.UUID,
 		}
 	}
 
+	prepareInsert(inv)
+
+	err = checkContext(ctx)
+	if err != nil {
+		err = errors.Wrap(err, "Insert")
+		log.Println(err)
+		return timeoutDoc(event, err)
+	}
 	insertResult, err := collection.InsertOne(inv)
 	if err != nil {
 		err = errors.Wrap(err, "Insert: Error Inserting Inventory into Mongo")
 		log.Println(err)
+		// Check if insert failed because itemID already exists
+		existing, findErr := findExistingItem(collection, inv.ItemID)
+		if findErr != nil {
+			findErr = errors.Wrap(findErr, "Insert")
+			log.Println(findErr)
+		}
+		if existing != nil {
+			return insertDuplicate(ctx, etcd, collection, event, inv, existing)
+		}
 		return &model.Document{
 			AggregateID:   event.AggregateID,
 			CorrelationID: event.CorrelationID,
@@ -0,0 +1,411 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	"github.com/mongodb/mongo-go-driver/mongo/insertopt"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// bulkInsert is the event-data for inserting multiple Inventory items.
+// Ordered defaults to true.
+type bulkInsert struct {
+	Items   []json.RawMessage `json:"items"`
+	Ordered *bool             `json:"ordered,omitempty"`
+}
+
+// orderedSkipMsg is the error for items skipped in ordered bulk-inserts.
+const orderedSkipMsg = "not inserted since an earlier item failed in ordered-insert"
+
+// BulkInsertResult is the Document-result for bulk-inserts.
+type BulkInsertResult struct {
+	Ordered       bool                   `json:"ordered"`
+	InsertedCount int                    `json:"insertedCount"`
+	FailedCount   int                    `json:"failedCount"`
+	Items         []BulkInsertItemResult `json:"items"`
+}
+
+// BulkInsertItemResult is the result for each item in a bulk-insert,
+// in the same order as the items in request.
+type BulkInsertItemResult struct {
+	Index    int    `json:"index"`
+	ItemID   string `json:"itemID,omitempty"`
+	ID       string `json:"_id,omitempty"`
+	Inserted bool   `json:"inserted"`
+	// Existing is true if itemID already existed, and the stored item was
+	// returned or upserted as per DuplicateInsertMode.
+	Existing    bool             `json:"existing,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	ErrorCode   int              `json:"errorCode,omitempty"`
+	FieldErrors ValidationErrors `json:"fieldErrors,omitempty"`
+}
+
+// parseBulkInsert checks if the event-data is a bulk-insert. The data can
+// either be an array of Inventory, or an object with "items" array and the
+// optional "ordered" flag.
+func parseBulkInsert(data []byte) (*bulkInsert, bool) {
+	bulk := &bulkInsert{}
+	items := []json.RawMessage{}
+	err := json.Unmarshal(data, &items)
+	if err == nil {
+		bulk.Items = items
+		return bulk, true
+	}
+
+	err = json.Unmarshal(data, bulk)
+	if err != nil || bulk.Items == nil {
+		return nil, false
+	}
+	return bulk, true
+}
+
+// prepareInsert sets the default-values for Inventory before its inserted.
+func prepareInsert(inv *Inventory) {
+	inv.SchemaVersion = CurrentSchemaVersion
+	if inv.Unit == "" {
+		inv.Unit = DefaultUnit
+	}
+	if inv.UPC != "" {
+		// UPC is validated before insert
+		inv.GTIN, _ = ParseGTIN(inv.UPC)
+	}
+}
+
+// insertBulk inserts all valid items from bulk-insert using a single
+// InsertMany. In ordered-mode, no items are inserted after the first
+// failed item.
+func insertBulk(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	collection *mongo.Collection,
+	event *model.Event,
+	bulk *bulkInsert,
+	tenant uuuid.UUID,
+) *model.Document {
+	ordered := bulk.Ordered == nil || *bulk.Ordered
+	results := make([]BulkInsertItemResult, len(bulk.Items))
+	docs := []interface{}{}
+	// Index of item in request for each document in docs
+	docIndexes := []int{}
+
+	stopped := false
+	for i, rawItem := range bulk.Items {
+		results[i].Index = i
+		if stopped {
+			results[i].Error = orderedSkipMsg
+			results[i].ErrorCode = UserError
+			continue
+		}
+
+		inv := &Inventory{}
+		err := json.Unmarshal(rawItem, inv)
+		if fieldErrs, isFieldErr := err.(ValidationErrors); isFieldErr {
+			err = errors.Wrapf(fieldErrs, "InsertBulk: Malformed item at index %d", i)
+			log.Println(err)
+			results[i].Error = err.Error()
+			results[i].ErrorCode = ValidationError
+			results[i].FieldErrors = fieldErrs
+			stopped = ordered
+			continue
+		}
+		if err != nil {
+			err = errors.Wrapf(err, "InsertBulk: Error unmarshalling item at index %d", i)
+			log.Println(err)
+			results[i].Error = err.Error()
+			results[i].ErrorCode = InternalError
+			stopped = ordered
+			continue
+		}
+		results[i].ItemID = inv.ItemID.String()
+
+		err = assignItemTenant(event, tenant, inv)
+		if err != nil {
+			err = errors.Wrapf(err, "InsertBulk: Item at index %d", i)
+			log.Println(err)
+			results[i].Error = err.Error()
+			results[i].ErrorCode = TenantError
+			stopped = ordered
+			continue
+		}
+
+		validationErrs := validateInsert(inv)
+		if validationErrs != nil {
+			err = errors.Wrapf(validationErrs, "InsertBulk: Item at index %d", i)
+			log.Println(err)
+			results[i].Error = err.Error()
+			results[i].ErrorCode = ValidationError
+			results[i].FieldErrors = validationErrs
+			stopped = ordered
+			continue
+		}
+
+		prepareInsert(inv)
+		// IDs are assigned here so inserted items can be identified
+		// even if InsertMany fails partially.
+		inv.ID = objectid.New()
+		results[i].ID = inv.ID.Hex()
+		docs = append(docs, inv)
+		docIndexes = append(docIndexes, i)
+	}
+
+	if len(docs) > 0 {
+		err := checkContext(ctx)
+		if err != nil {
+			err = errors.Wrap(err, "InsertBulk")
+			log.Println(err)
+			return timeoutDoc(event, err)
+		}
+	}
+	if len(docs) > 0 && DuplicateInsertMode != InsertReject {
+		var err error
+		docs, docIndexes, err = resolveBulkDuplicates(
+			ctx, etcd, collection, event, docs, docIndexes, results, ordered,
+		)
+		if err != nil {
+			err = errors.Wrap(err, "InsertBulk")
+			log.Println(err)
+			return &model.Document{
+				AggregateID:   event.AggregateID,
+				CorrelationID: event.CorrelationID,
+				Error:         err.Error(),
+				ErrorCode:     DatabaseError,
+				EventAction:   event.EventAction,
+				ServiceAction: event.ServiceAction,
+				UUID:          event.UUID,
+			}
+		}
+	}
+
+	if len(docs) > 0 {
+		// go-mongoutils' InsertMany inserts one document at a time without
+		// options, so mongo-driver is used for ordered/unordered inserts.
+		_, err := collection.Collection().InsertMany(
+			ctx, docs, insertopt.Ordered(ordered),
+		)
+		if err != nil {
+			err = errors.Wrap(err, "InsertBulk: Error inserting items into Mongo")
+			log.Println(err)
+			err = resolveBulkInsertErrors(
+				collection, event, docs, docIndexes, results, ordered,
+			)
+			if err != nil {
+				err = errors.Wrap(err, "InsertBulk")
+				log.Println(err)
+				return &model.Document{
+					AggregateID:   event.AggregateID,
+					CorrelationID: event.CorrelationID,
+					Error:         err.Error(),
+					ErrorCode:     DatabaseError,
+					EventAction:   event.EventAction,
+					ServiceAction: event.ServiceAction,
+					UUID:          event.UUID,
+				}
+			}
+		} else {
+			for _, i := range docIndexes {
+				results[i].Inserted = true
+			}
+		}
+	}
+
+	bulkResult := BulkInsertResult{
+		Ordered: ordered,
+		Items:   results,
+	}
+	for _, r := range results {
+		if r.Inserted {
+			bulkResult.InsertedCount++
+		}
+		if r.Error != "" {
+			bulkResult.FailedCount++
+		}
+	}
+
+	result, err := json.Marshal(bulkResult)
+	if err != nil {
+		err = errors.Wrap(err, "InsertBulk: Error marshalling bulk-insert result")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		Result:        result,
+		EventAction:   event.EventAction,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+// resolveBulkDuplicates resolves the documents whose itemIDs already exist,
+// as per DuplicateInsertMode. The remaining documents, which are to be
+// inserted, are returned with their indexes in request.
+func resolveBulkDuplicates(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	collection *mongo.Collection,
+	event *model.Event,
+	docs []interface{},
+	docIndexes []int,
+	results []BulkInsertItemResult,
+	ordered bool,
+) ([]interface{}, []int, error) {
+	itemIDs := []string{}
+	for _, doc := range docs {
+		itemIDs = append(itemIDs, doc.(*Inventory).ItemID.String())
+	}
+	existingDocs, err := collection.Find(map[string]interface{}{
+		"itemID": map[string]interface{}{
+			"$in": itemIDs,
+		},
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Error finding existing items")
+	}
+	existing := map[string]*Inventory{}
+	for _, d := range existingDocs {
+		inv, assertOK := d.(*Inventory)
+		if assertOK {
+			existing[inv.ItemID.String()] = inv
+		}
+	}
+
+	newDocs := []interface{}{}
+	newDocIndexes := []int{}
+	for docIndex, doc := range docs {
+		inv := doc.(*Inventory)
+		r := &results[docIndexes[docIndex]]
+		existingInv := existing[inv.ItemID.String()]
+		if existingInv == nil {
+			newDocs = append(newDocs, doc)
+			newDocIndexes = append(newDocIndexes, docIndexes[docIndex])
+			continue
+		}
+
+		var (
+			stored    *Inventory
+			fieldErrs ValidationErrors
+			errCode   int
+			err       error
+		)
+		if TenantIsolation {
+			err = checkDuplicateTenant(event, inv.RSCustomerID, existingInv)
+			errCode = Conflict
+		}
+		if err == nil {
+			stored, fieldErrs, errCode, err = resolveDuplicate(
+				ctx, etcd, collection, inv, existingInv, eventTime(event),
+			)
+		}
+		if err != nil {
+			err = errors.Wrapf(err, "InsertBulk: Item at index %d", docIndexes[docIndex])
+			log.Println(err)
+			r.ID = ""
+			r.Error = err.Error()
+			r.ErrorCode = errCode
+			r.FieldErrors = fieldErrs
+			if ordered {
+				// Remaining items are not inserted in ordered-mode
+				for _, i := range docIndexes[docIndex+1:] {
+					results[i].ID = ""
+					results[i].Error = orderedSkipMsg
+					results[i].ErrorCode = UserError
+				}
+				break
+			}
+			continue
+		}
+		r.ID = stored.ID.Hex()
+		r.Existing = true
+	}
+	return newDocs, newDocIndexes, nil
+}
+
+// resolveBulkInsertErrors finds which documents were inserted after a failed
+// InsertMany, and sets the error for the rest. Documents whose itemID already
+// exists are reported as duplicates, unless the itemID belongs to another
+// tenant (see checkDuplicateTenant).
+// Since itemIDs are unique, a document was inserted if the item stored for its
+// itemID has the document's ObjectID.
+func resolveBulkInsertErrors(
+	collection *mongo.Collection,
+	event *model.Event,
+	docs []interface{},
+	docIndexes []int,
+	results []BulkInsertItemResult,
+	ordered bool,
+) error {
+	itemIDs := []string{}
+	for _, doc := range docs {
+		itemIDs = append(itemIDs, doc.(*Inventory).ItemID.String())
+	}
+
+	existingDocs, err := collection.Find(map[string]interface{}{
+		"itemID": map[string]interface{}{
+			"$in": itemIDs,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Error finding existing items")
+	}
+	// Stored item for each itemID
+	existing := map[string]*Inventory{}
+	for _, d := range existingDocs {
+		inv, assertOK := d.(*Inventory)
+		if assertOK {
+			existing[inv.ItemID.String()] = inv
+		}
+	}
+
+	failed := false
+	for docIndex, doc := range docs {
+		inv := doc.(*Inventory)
+		r := &results[docIndexes[docIndex]]
+		stored, isDuplicate := existing[inv.ItemID.String()]
+		if isDuplicate && stored.ID == inv.ID {
+			r.Inserted = true
+			continue
+		}
+
+		r.ID = ""
+		switch {
+		case ordered && failed:
+			r.Error = orderedSkipMsg
+			r.ErrorCode = UserError
+		case isDuplicate:
+			err := fmt.Errorf("itemID %s already exists", inv.ItemID)
+			if TenantIsolation {
+				// Inserted item is already assigned to event's tenant
+				tenantErr := checkDuplicateTenant(event, inv.RSCustomerID, stored)
+				if tenantErr != nil {
+					err = tenantErr
+				}
+			}
+			r.Error = err.Error()
+			r.ErrorCode = Conflict
+			failed = true
+		default:
+			r.Error = "error inserting item into database"
+			r.ErrorCode = DatabaseError
+			failed = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package inventory
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BulkInsert", func() {
+	It("should parse array of items as ordered bulk-insert", func() {
+		bulk, isBulk := parseBulkInsert([]byte(`[{"lot": "a"}, {"lot": "b"}]`))
+		Expect(isBulk).To(BeTrue())
+		Expect(bulk.Items).To(HaveLen(2))
+		Expect(bulk.Ordered).To(BeNil())
+	})
+
+	It("should parse items with ordered-flag", func() {
+		data := []byte(`{"items": [{"lot": "a"}], "ordered": false}`)
+		bulk, isBulk := parseBulkInsert(data)
+		Expect(isBulk).To(BeTrue())
+		Expect(bulk.Items).To(HaveLen(1))
+		Expect(*bulk.Ordered).To(BeFalse())
+	})
+
+	It("should not parse single item as bulk-insert", func() {
+		_, isBulk := parseBulkInsert([]byte(`{"lot": "a", "totalWeight": 12}`))
+		Expect(isBulk).To(BeFalse())
+	})
+})
@@ -0,0 +1,324 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// InsertMode defines how inserts are handled when the itemID already exists.
+type InsertMode string
+
+// Supported insert-modes.
+const (
+	// InsertReject returns a Conflict error.
+	InsertReject InsertMode = "reject"
+	// InsertReturnExisting returns the stored item without changing it.
+	InsertReturnExisting InsertMode = "returnExisting"
+	// InsertUpsert replaces the descriptive fields of stored item.
+	// The sold, waste, donate, and flash-sale weights are never overwritten.
+	InsertUpsert InsertMode = "upsert"
+)
+
+// DuplicateInsertMode is the InsertMode used for inserting existing itemIDs.
+var DuplicateInsertMode = InsertReject
+
+// upsertKeptFields are the fields that are never overwritten by upserts.
+// These are changed only by sales, flash-sales, and transfers, and must
+// match the fields kept by mergeUpsert.
+var upsertKeptFields = []string{
+	"_id",
+	"itemID",
+	"dateSold",
+	"donateWeight",
+	"flashSaleTimestamp",
+	"flashSaleWeight",
+	"location",
+	"onFlashSale",
+	"parentItemID",
+	"soldWeight",
+	"wasteWeight",
+}
+
+// ParseInsertMode returns the InsertMode for the provided string.
+// Blank string returns InsertReject.
+func ParseInsertMode(mode string) (InsertMode, error) {
+	switch InsertMode(mode) {
+	case "":
+		return InsertReject, nil
+	case InsertReject, InsertReturnExisting, InsertUpsert:
+		return InsertMode(mode), nil
+	default:
+		return "", fmt.Errorf(
+			"unsupported insert-mode %s, must be one of reject, returnExisting, upsert",
+			mode,
+		)
+	}
+}
+
+// findExistingItem returns the stored item with the itemID,
+// or nil if no such item exists.
+func findExistingItem(
+	collection *mongo.Collection,
+	itemID uuuid.UUID,
+) (*Inventory, error) {
+	findResults, err := collection.Find(map[string]interface{}{
+		"itemID": itemID.String(),
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error finding existing item")
+		return nil, err
+	}
+	for _, r := range findResults {
+		inv, assertOK := r.(*Inventory)
+		if assertOK {
+			return inv, nil
+		}
+	}
+	return nil, nil
+}
+
+// resolveDuplicate applies the DuplicateInsertMode for inserting inv, whose
+// itemID already exists as the existing item. The returned item is the
+// stored item after resolution. Reservations active at now are kept by
+// upserts. The returned int is the error-code if the error is not nil.
+func resolveDuplicate(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	collection *mongo.Collection,
+	inv *Inventory,
+	existing *Inventory,
+	now int64,
+) (*Inventory, ValidationErrors, int, error) {
+	switch DuplicateInsertMode {
+	case InsertReturnExisting:
+		return existing, nil, 0, nil
+	case InsertUpsert:
+		return lockedUpsertItem(ctx, etcd, collection, inv, now)
+	default:
+		err := fmt.Errorf("itemID %s already exists", inv.ItemID)
+		return nil, nil, Conflict, err
+	}
+}
+
+// lockedUpsertItem upserts inv while holding the item-lock, so sales and
+// transfers of the item cannot change it between reading and updating.
+// The existing item and its reservations are read again after obtaining
+// the lock.
+func lockedUpsertItem(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	collection *mongo.Collection,
+	inv *Inventory,
+	now int64,
+) (*Inventory, ValidationErrors, int, error) {
+	unlock, err := lockItem(ctx, etcd, inv.ItemID.String())
+	if err != nil {
+		err = errors.Wrap(err, "Upsert")
+		if isContextError(err) {
+			return nil, nil, Timeout, err
+		}
+		return nil, nil, Unavailable, err
+	}
+	defer unlock()
+
+	existing, err := findExistingItem(collection, inv.ItemID)
+	if err != nil {
+		return nil, nil, DatabaseError, errors.Wrap(err, "Upsert")
+	}
+	if existing == nil {
+		err = fmt.Errorf("itemID %s was deleted while upserting", inv.ItemID)
+		return nil, nil, Conflict, errors.Wrap(err, "Upsert")
+	}
+	reserved, err := reservedWeight(inv.ItemID, now, uuuid.UUID{})
+	if err != nil {
+		err = errors.Wrap(err, "Upsert")
+		return nil, nil, mongoErrorCode(err), err
+	}
+	err = checkContext(ctx)
+	if err != nil {
+		return nil, nil, Timeout, errors.Wrap(err, "Upsert")
+	}
+	return upsertItem(collection, inv, existing, reserved)
+}
+
+// upsertItem replaces the descriptive fields of existing item with the
+// fields from inv, keeping the upsertKeptFields of existing item.
+// The new totalWeight must cover the weight already used, on flash-sale,
+// and reserved. The caller must hold the item-lock.
+func upsertItem(
+	collection *mongo.Collection,
+	inv *Inventory,
+	existing *Inventory,
+	reserved Decimal,
+) (*Inventory, ValidationErrors, int, error) {
+	fieldErrs := ValidationErrors{}
+	if inv.WeightUnit() != existing.WeightUnit() {
+		fieldErrs = append(fieldErrs, FieldError{
+			Field: "unit",
+			Message: fmt.Sprintf(
+				"cannot change unit from %s to %s using upsert",
+				existing.WeightUnit(), inv.WeightUnit(),
+			),
+		})
+	}
+	heldWeight, err := sumDecimals(
+		existing.SoldWeight, existing.WasteWeight, existing.DonateWeight,
+		existing.FlashSaleWeight, reserved,
+	)
+	if err != nil {
+		return nil, nil, InternalError, errors.Wrap(err, "Upsert")
+	}
+	if heldWeight.GreaterThan(inv.TotalWeight) {
+		fieldErrs = append(fieldErrs, FieldError{
+			Field: "totalWeight",
+			Message: fmt.Sprintf(
+				"must not be less than stored sold, waste, donate, flash-sale, "+
+					"and reserved weights (%s)",
+				heldWeight,
+			),
+		})
+	}
+	if len(fieldErrs) > 0 {
+		return nil, fieldErrs, ValidationError, errors.Wrap(fieldErrs, "Upsert")
+	}
+
+	upserted := mergeUpsert(inv, existing)
+	update, err := upsertUpdate(upserted)
+	if err != nil {
+		err = errors.Wrap(err, "Upsert")
+		return nil, nil, InternalError, err
+	}
+
+	_, err = collection.UpdateMany(
+		map[string]interface{}{
+			"itemID": existing.ItemID.String(),
+		},
+		update,
+	)
+	if err != nil {
+		err = errors.Wrap(err, "Upsert: Error updating item")
+		return nil, nil, DatabaseError, err
+	}
+	return upserted, nil, 0, nil
+}
+
+// mergeUpsert returns inv with the upsertKeptFields of existing item.
+func mergeUpsert(inv *Inventory, existing *Inventory) *Inventory {
+	upserted := *inv
+	upserted.ID = existing.ID
+	upserted.ItemID = existing.ItemID
+	upserted.DateSold = existing.DateSold
+	upserted.DonateWeight = existing.DonateWeight
+	upserted.FlashSaleTimestamp = existing.FlashSaleTimestamp
+	upserted.FlashSaleWeight = existing.FlashSaleWeight
+	upserted.Location = existing.Location
+	upserted.OnFlashSale = existing.OnFlashSale
+	upserted.ParentItemID = existing.ParentItemID
+	upserted.SoldWeight = existing.SoldWeight
+	upserted.WasteWeight = existing.WasteWeight
+	return &upserted
+}
+
+// upsertUpdate returns the update-args for the upserted item, without the
+// upsertKeptFields.
+func upsertUpdate(upserted *Inventory) (map[string]interface{}, error) {
+	marshalInv, err := upserted.MarshalBSON()
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling item")
+		return nil, err
+	}
+	update := map[string]interface{}{}
+	err = bson.Unmarshal(marshalInv, update)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling item to map")
+		return nil, err
+	}
+	for _, field := range upsertKeptFields {
+		delete(update, field)
+	}
+	return update, nil
+}
+
+// insertDuplicate returns the Document for single-item insert of an
+// existing itemID, as per DuplicateInsertMode.
+func insertDuplicate(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	collection *mongo.Collection,
+	event *model.Event,
+	inv *Inventory,
+	existing *Inventory,
+) *model.Document {
+	if TenantIsolation {
+		// Inserted item is already assigned to event's tenant
+		err := checkDuplicateTenant(event, inv.RSCustomerID, existing)
+		if err != nil {
+			err = errors.Wrap(err, "Insert")
+			log.Println(err)
+			return &model.Document{
+				AggregateID:   event.AggregateID,
+				CorrelationID: event.CorrelationID,
+				Error:         err.Error(),
+				ErrorCode:     Conflict,
+				EventAction:   event.EventAction,
+				ServiceAction: event.ServiceAction,
+				UUID:          event.UUID,
+			}
+		}
+	}
+
+	stored, fieldErrs, errCode, err := resolveDuplicate(
+		ctx, etcd, collection, inv, existing, eventTime(event),
+	)
+	if err != nil {
+		err = errors.Wrap(err, "Insert")
+		log.Println(err)
+		var result []byte
+		if fieldErrs != nil {
+			// Error is ignored since marshalling field-errors cannot fail
+			result, _ = json.Marshal(validationResult{fieldErrs})
+		}
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     int16(errCode),
+			EventAction:   event.EventAction,
+			Result:        result,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	result, err := json.Marshal(stored)
+	if err != nil {
+		err = errors.Wrap(err, "Insert: Error marshalling stored Inventory")
+		log.Println(err)
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     InternalError,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		Result:        result,
+		EventAction:   event.EventAction,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
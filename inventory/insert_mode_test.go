@@ -0,0 +1,105 @@
+package inventory
+
+import (
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InsertMode", func() {
+	It("should parse insert-modes", func() {
+		mode, err := ParseInsertMode("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mode).To(Equal(InsertReject))
+
+		mode, err = ParseInsertMode("upsert")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mode).To(Equal(InsertUpsert))
+
+		_, err = ParseInsertMode("overwrite")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject duplicates by default", func() {
+		inv := &Inventory{}
+		_, _, errCode, err := resolveDuplicate(nil, nil, nil, inv, &Inventory{}, 0)
+		Expect(err).To(HaveOccurred())
+		Expect(errCode).To(Equal(Conflict))
+	})
+
+	It("should return existing item without changes", func() {
+		DuplicateInsertMode = InsertReturnExisting
+		defer func() {
+			DuplicateInsertMode = InsertReject
+		}()
+
+		existing := &Inventory{Lot: "stored-lot"}
+		stored, _, _, err := resolveDuplicate(
+			nil, nil, nil, &Inventory{Lot: "new-lot"}, existing, 0,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stored).To(Equal(existing))
+	})
+
+	It("should not upsert totalWeight below stored used-weights", func() {
+		existing := &Inventory{
+			TotalWeight: decimalInt(100),
+			SoldWeight:  decimalInt(60),
+			WasteWeight: decimalInt(20),
+		}
+		inv := &Inventory{TotalWeight: decimalInt(50)}
+		_, fieldErrs, errCode, err := upsertItem(nil, inv, existing, Decimal{})
+		Expect(err).To(HaveOccurred())
+		Expect(errCode).To(Equal(ValidationError))
+		Expect(fieldErrs).To(HaveLen(1))
+		Expect(fieldErrs[0].Field).To(Equal("totalWeight"))
+	})
+
+	It("should not upsert totalWeight below flash-sale and reserved weights", func() {
+		existing := &Inventory{
+			TotalWeight:     decimalInt(100),
+			SoldWeight:      decimalInt(20),
+			FlashSaleWeight: decimalInt(30),
+		}
+		inv := &Inventory{TotalWeight: decimalInt(60)}
+		_, fieldErrs, errCode, err := upsertItem(nil, inv, existing, decimalInt(15))
+		Expect(err).To(HaveOccurred())
+		Expect(errCode).To(Equal(ValidationError))
+		Expect(fieldErrs).To(HaveLen(1))
+		Expect(fieldErrs[0].Message).To(ContainSubstring("(65)"))
+	})
+
+	It("should keep sale, flash-sale, and location fields of stored item", func() {
+		parentItemID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		existing := &Inventory{
+			ID:                 objectid.New(),
+			DateSold:           10,
+			FlashSaleTimestamp: 20,
+			FlashSaleWeight:    decimalInt(5),
+			Location:           "stored-location",
+			OnFlashSale:        true,
+			ParentItemID:       parentItemID,
+			SoldWeight:         decimalInt(30),
+		}
+		inv := &Inventory{Lot: "new-lot", Location: "new-location"}
+		upserted := mergeUpsert(inv, existing)
+		Expect(upserted.Lot).To(Equal("new-lot"))
+		Expect(upserted.ID).To(Equal(existing.ID))
+		Expect(upserted.DateSold).To(Equal(existing.DateSold))
+		Expect(upserted.FlashSaleTimestamp).To(Equal(existing.FlashSaleTimestamp))
+		Expect(upserted.FlashSaleWeight).To(Equal(existing.FlashSaleWeight))
+		Expect(upserted.Location).To(Equal(existing.Location))
+		Expect(upserted.OnFlashSale).To(BeTrue())
+		Expect(upserted.ParentItemID).To(Equal(existing.ParentItemID))
+		Expect(upserted.SoldWeight).To(Equal(existing.SoldWeight))
+
+		update, err := upsertUpdate(upserted)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(update).To(HaveKey("lot"))
+		for _, field := range upsertKeptFields {
+			Expect(update).ToNot(HaveKey(field))
+		}
+	})
+})
@@ -1,6 +1,7 @@
 package inventory
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 	"time"
@@ -40,7 +41,7 @@ var _ = Describe("InventoryAggregate", func() {
 				Version:       3,
 				YearBucket:    2018,
 			}
-			kr := Delete(nil, mockEvent)
+			kr := Delete(context.Background(), nil, mockEvent)
 			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
 			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
 			Expect(kr.Error).ToNot(BeEmpty())
@@ -69,11 +70,11 @@ var _ = Describe("InventoryAggregate", func() {
 				Version:       3,
 				YearBucket:    2018,
 			}
-			kr := Insert(nil, mockEvent)
+			kr := Insert(context.Background(), nil, nil, mockEvent)
 			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
 			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
 			Expect(kr.Error).ToNot(BeEmpty())
-			Expect(kr.ErrorCode).To(Equal(int16(InternalError)))
+			Expect(kr.ErrorCode).To(Equal(int16(ValidationError)))
 			Expect(kr.UUID).To(Equal(mockEvent.UUID))
 		})
 	})
@@ -104,7 +105,7 @@ var _ = Describe("InventoryAggregate", func() {
 				Version:       3,
 				YearBucket:    2018,
 			}
-			kr := Update(nil, nil, mockEvent)
+			kr := Update(context.Background(), nil, nil, mockEvent)
 			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
 			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
 			Expect(kr.Error).ToNot(BeEmpty())
@@ -139,7 +140,7 @@ var _ = Describe("InventoryAggregate", func() {
 				Version:       3,
 				YearBucket:    2018,
 			}
-			kr := Update(nil, nil, mockEvent)
+			kr := Update(context.Background(), nil, nil, mockEvent)
 			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
 			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
 			Expect(kr.Error).ToNot(BeEmpty())
@@ -176,7 +177,7 @@ var _ = Describe("InventoryAggregate", func() {
 				Version:       3,
 				YearBucket:    2018,
 			}
-			kr := Update(nil, nil, mockEvent)
+			kr := Update(context.Background(), nil, nil, mockEvent)
 			Expect(kr.AggregateID).To(Equal(mockEvent.AggregateID))
 			Expect(kr.CorrelationID).To(Equal(mockEvent.CorrelationID))
 			Expect(kr.Error).ToNot(BeEmpty())
@@ -0,0 +1,61 @@
+package inventory
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// lockTimeout is the timeout for obtaining and releasing item-locks.
+const lockTimeout = 25 * time.Second
+
+// lockItem obtains the etcd-lock for the item, the same lock used when
+// updating item-weights for sales. The returned function releases the lock.
+// Fails fast if EtcdBreaker is open. Waiting for the lock stops when ctx is
+// cancelled. The lock is always released, even after ctx is cancelled.
+func lockItem(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	itemID string,
+) (func(), error) {
+	err := EtcdBreaker.Allow()
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to obtain lock for ItemID: %s", itemID)
+		return nil, err
+	}
+	lockSession, err := concurrency.NewSession(etcd, concurrency.WithTTL(25))
+	if err != nil {
+		EtcdBreaker.Failure()
+		err = errors.Wrapf(err, "Failed to obtain lock for ItemID: %s", itemID)
+		return nil, err
+	}
+	mx := concurrency.NewMutex(lockSession, ItemLockPrefix(itemID))
+
+	lockCtx, lockCancel := context.WithTimeout(ctx, lockTimeout)
+	defer lockCancel()
+	err = mx.Lock(lockCtx)
+	if err != nil {
+		lockSession.Close()
+		if ctx.Err() != nil {
+			// Command stopped, so this is not an etcd-failure
+			EtcdBreaker.Cancel()
+			err = errors.Wrapf(ctx.Err(), "Stopped waiting for lock on ItemID: %s", itemID)
+			return nil, err
+		}
+		EtcdBreaker.Failure()
+		err = errors.Wrapf(err, "Failed to apply obtained lock for ItemID: %s", itemID)
+		return nil, err
+	}
+	EtcdBreaker.Success()
+
+	unlock := func() {
+		unlockCtx, unlockCancel := context.WithTimeout(context.Background(), lockTimeout)
+		defer unlockCancel()
+		mx.Unlock(unlockCtx)
+		lockSession.Close()
+	}
+	return unlock, nil
+}
@@ -0,0 +1,353 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/mongodb/mongo-go-driver/bson/decimal"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	"github.com/mongodb/mongo-go-driver/mongo/findopt"
+	"github.com/pkg/errors"
+)
+
+// CurrentSchemaVersion is the schema-version of Inventory documents written by
+// this service. A Migration must be registered for each version above 1.
+const CurrentSchemaVersion = 4
+
+// Migration upgrades Inventory documents from the previous schema-version
+// to its Version.
+type Migration struct {
+	Version     int64
+	Description string
+	// Migrate changes the document, as decoded from BSON, in place.
+	Migrate func(doc map[string]interface{}) error
+}
+
+// migrations are the registered Migrations, by their Version.
+var migrations = map[int64]*Migration{}
+
+// RegisterMigration registers the migration for its schema-version.
+// Panics if a migration is already registered for the version, since
+// migrations are registered on init.
+func RegisterMigration(m *Migration) {
+	if m.Version < 2 || m.Version > CurrentSchemaVersion {
+		panic(fmt.Sprintf(
+			"migration-version must be between 2 and %d, got %d",
+			CurrentSchemaVersion, m.Version,
+		))
+	}
+	if migrations[m.Version] != nil {
+		panic(fmt.Sprintf("migration already registered for version %d", m.Version))
+	}
+	migrations[m.Version] = m
+}
+
+func init() {
+	RegisterMigration(&Migration{
+		Version:     2,
+		Description: "Add flash-sale fields",
+		Migrate: func(doc map[string]interface{}) error {
+			setMissing(doc, "onFlashSale", false)
+			setMissing(doc, "flashSaleWeight", Decimal{}.Decimal128())
+			setMissing(doc, "flashSaleTimestamp", int64(0))
+			return nil
+		},
+	})
+	RegisterMigration(&Migration{
+		Version:     3,
+		Description: "Add projectedDate",
+		Migrate: func(doc map[string]interface{}) error {
+			setMissing(doc, "projectedDate", int64(0))
+			return nil
+		},
+	})
+	RegisterMigration(&Migration{
+		Version:     4,
+		Description: "Store weights and prices as Decimal128",
+		Migrate: func(doc map[string]interface{}) error {
+			fields := []string{
+				"donateWeight", "flashSaleWeight", "price",
+				"soldWeight", "totalWeight", "wasteWeight",
+			}
+			for _, field := range fields {
+				if doc[field] == nil {
+					continue
+				}
+				if _, isDecimal := doc[field].(decimal.Decimal128); isDecimal {
+					continue
+				}
+				d, err := assertDecimal(doc[field])
+				if err != nil {
+					// Malformed values are reported as field-errors when decoded
+					continue
+				}
+				doc[field] = d.Decimal128()
+			}
+			return nil
+		},
+	})
+}
+
+// setMissing sets the field on document if it's not set.
+func setMissing(doc map[string]interface{}, field string, value interface{}) {
+	if _, exists := doc[field]; !exists {
+		doc[field] = value
+	}
+}
+
+// documentSchemaVersion returns the schema-version of stored document.
+// Documents without a schema-version are version 1.
+func documentSchemaVersion(doc map[string]interface{}) (int64, error) {
+	if doc["schemaVersion"] == nil {
+		return 1, nil
+	}
+	version, err := assertInt64(doc["schemaVersion"])
+	if err != nil {
+		return 0, ValidationErrors{FieldError{
+			Field:   "schemaVersion",
+			Message: err.Error(),
+		}}
+	}
+	if version < 1 {
+		return 1, nil
+	}
+	return version, nil
+}
+
+// migrateDocument upgrades the stored document to CurrentSchemaVersion, by
+// running the migrations above its version in order. Documents from newer
+// versions of the service are left unchanged, since their fields are a
+// superset of the current fields. Returns the document's stored version.
+func migrateDocument(doc map[string]interface{}) (int64, error) {
+	version, err := documentSchemaVersion(doc)
+	if err != nil {
+		return 0, err
+	}
+	for v := version + 1; v <= CurrentSchemaVersion; v++ {
+		m := migrations[v]
+		if m == nil {
+			return version, fmt.Errorf("no migration registered for schema-version %d", v)
+		}
+		err = m.Migrate(doc)
+		if err != nil {
+			err = errors.Wrapf(err, "Error migrating document to schema-version %d", v)
+			return version, err
+		}
+		doc["schemaVersion"] = v
+	}
+	return version, nil
+}
+
+// StoredDocument is an Inventory document as stored, before migrations.
+// It's used as SchemaStruct when migrating documents in bulk.
+type StoredDocument struct {
+	Fields map[string]interface{}
+}
+
+// UnmarshalBSON decodes the document-fields as stored.
+func (s *StoredDocument) UnmarshalBSON(in []byte) error {
+	fields, err := decodeBSONMap(in)
+	if err != nil {
+		err = errors.Wrap(err, "Unmarshal Error")
+		return err
+	}
+	s.Fields = fields
+	return nil
+}
+
+// MigrationStats are the results of migrating documents in bulk.
+type MigrationStats struct {
+	Scanned  int
+	Migrated int
+	// Conflicted are the documents changed while being migrated. These are
+	// migrated when read, or by running the migration again.
+	Conflicted int
+	Failed     int
+	// FromVersions are the number of documents found at each schema-version.
+	FromVersions map[int64]int
+}
+
+// migrationUpdate returns the fields changed by migration, and the filter
+// matching the document only if those fields still have their original
+// values, so concurrent updates, such as sales, are not overwritten.
+func migrationUpdate(
+	orig map[string]interface{},
+	migrated map[string]interface{},
+) (map[string]interface{}, map[string]interface{}) {
+	filter := map[string]interface{}{
+		"_id": orig["_id"],
+	}
+	update := map[string]interface{}{}
+	for field, value := range migrated {
+		origValue, exists := orig[field]
+		if exists && reflect.DeepEqual(origValue, value) {
+			continue
+		}
+		update[field] = value
+		if exists {
+			filter[field] = origValue
+		} else {
+			filter[field] = map[string]interface{}{
+				"$exists": false,
+			}
+		}
+	}
+	return filter, update
+}
+
+// MigrateDocuments upgrades all documents below CurrentSchemaVersion in the
+// collection, in batches of batchSize. The collection must use StoredDocument
+// as its SchemaStruct. Only the fields changed by migrations are written.
+// Documents are only counted if dryRun is true.
+func MigrateDocuments(
+	coll *mongo.Collection,
+	batchSize int64,
+	dryRun bool,
+) (*MigrationStats, error) {
+	findBatch := func(lastID objectid.ObjectID) ([]*StoredDocument, error) {
+		return findMigrationBatch(coll, lastID, batchSize)
+	}
+	return migrateBatches(coll, findBatch, dryRun)
+}
+
+// migrateBatches migrates the batches returned by findBatch, until a batch
+// is empty. Each batch is found after the last document of previous batch.
+func migrateBatches(
+	coll *mongo.Collection,
+	findBatch func(lastID objectid.ObjectID) ([]*StoredDocument, error),
+	dryRun bool,
+) (*MigrationStats, error) {
+	stats := &MigrationStats{
+		FromVersions: map[int64]int{},
+	}
+	lastID := objectid.NilObjectID
+	for {
+		batch, err := findBatch(lastID)
+		if err != nil {
+			err = errors.Wrap(err, "Migration: Error finding documents")
+			return stats, err
+		}
+		if len(batch) == 0 {
+			return stats, nil
+		}
+
+		for _, stored := range batch {
+			id, assertOK := stored.Fields["_id"].(objectid.ObjectID)
+			if !assertOK {
+				return stats, errors.New("Migration: error asserting _id to ObjectID")
+			}
+			lastID = id
+			stats.Scanned++
+
+			migrateDocumentFields(coll, stored.Fields, stats, dryRun)
+		}
+	}
+}
+
+// migrationFilter matches the documents below CurrentSchemaVersion, after
+// the document lastID, if lastID is not NilObjectID.
+func migrationFilter(lastID objectid.ObjectID) map[string]interface{} {
+	filter := map[string]interface{}{
+		"$or": []interface{}{
+			map[string]interface{}{
+				"schemaVersion": map[string]interface{}{
+					"$exists": false,
+				},
+			},
+			map[string]interface{}{
+				"schemaVersion": map[string]interface{}{
+					"$lt": CurrentSchemaVersion,
+				},
+			},
+		},
+	}
+	if lastID != objectid.NilObjectID {
+		filter["_id"] = map[string]interface{}{
+			"$gt": lastID,
+		}
+	}
+	return filter
+}
+
+// findMigrationBatch returns up to batchSize documents to migrate, ordered by
+// _id, after the document lastID. The driver-collection is queried directly,
+// since mongo.Collection only accepts ObjectIDs as _id in filters.
+func findMigrationBatch(
+	coll *mongo.Collection,
+	lastID objectid.ObjectID,
+	batchSize int64,
+) ([]*StoredDocument, error) {
+	timeout := time.Duration(coll.Connection.Timeout) * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cur, err := coll.Collection().Find(
+		ctx,
+		migrationFilter(lastID),
+		findopt.Sort(map[string]interface{}{"_id": 1}),
+		findopt.Limit(batchSize),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	batch := []*StoredDocument{}
+	for cur.Next(ctx) {
+		stored := &StoredDocument{}
+		err = cur.Decode(stored)
+		if err != nil {
+			err = errors.Wrap(err, "Error decoding document")
+			return nil, err
+		}
+		batch = append(batch, stored)
+	}
+	return batch, cur.Err()
+}
+
+// migrateDocumentFields migrates the stored document, and writes the
+// changed fields to collection.
+func migrateDocumentFields(
+	coll *mongo.Collection,
+	orig map[string]interface{},
+	stats *MigrationStats,
+	dryRun bool,
+) {
+	migrated := map[string]interface{}{}
+	for field, value := range orig {
+		migrated[field] = value
+	}
+	version, err := migrateDocument(migrated)
+	stats.FromVersions[version]++
+	if err == nil {
+		// Migrated documents must be valid, so they can be read
+		err = (&Inventory{}).unmarshalFromMap(migrated)
+	}
+	if err != nil {
+		err = errors.Wrapf(err, "Migration: Error migrating document %v", orig["_id"])
+		log.Println(err)
+		stats.Failed++
+		return
+	}
+	if dryRun {
+		return
+	}
+
+	filter, update := migrationUpdate(orig, migrated)
+	updateResult, err := coll.UpdateMany(filter, update)
+	if err != nil {
+		err = errors.Wrapf(err, "Migration: Error writing document %v", orig["_id"])
+		log.Println(err)
+		stats.Failed++
+		return
+	}
+	if updateResult.MatchedCount < 1 {
+		stats.Conflicted++
+		return
+	}
+	stats.Migrated++
+}
@@ -0,0 +1,132 @@
+package inventory
+
+import (
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/decimal"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	mgo "github.com/mongodb/mongo-go-driver/mongo"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Migration", func() {
+	It("should have a migration registered for each schema-version", func() {
+		for v := int64(2); v <= CurrentSchemaVersion; v++ {
+			Expect(migrations[v]).ToNot(BeNil(), "schema-version %d", v)
+		}
+	})
+
+	It("should migrate documents without schema-version", func() {
+		doc := map[string]interface{}{
+			"itemID":      "a6a2f34c-3ec5-4da6-8d4e-0e0b6b1a8b39",
+			"totalWeight": 120.5,
+			"soldWeight":  int32(20),
+		}
+		version, err := migrateDocument(doc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version).To(Equal(int64(1)))
+
+		Expect(doc["schemaVersion"]).To(Equal(int64(CurrentSchemaVersion)))
+		Expect(doc["onFlashSale"]).To(Equal(false))
+		Expect(doc["projectedDate"]).To(Equal(int64(0)))
+		Expect(doc["totalWeight"]).To(BeAssignableToTypeOf(decimal.Decimal128{}))
+		Expect(doc["soldWeight"]).To(BeAssignableToTypeOf(decimal.Decimal128{}))
+	})
+
+	It("should not change documents of current or newer schema-version", func() {
+		doc := map[string]interface{}{
+			"schemaVersion": int32(CurrentSchemaVersion + 1),
+			"totalWeight":   120.5,
+		}
+		version, err := migrateDocument(doc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version).To(Equal(int64(CurrentSchemaVersion + 1)))
+		Expect(doc["totalWeight"]).To(Equal(120.5))
+	})
+
+	It("should migrate documents when read", func() {
+		data, err := bson.Marshal(map[string]interface{}{
+			"itemID":        "a6a2f34c-3ec5-4da6-8d4e-0e0b6b1a8b39",
+			"totalWeight":   120.5,
+			"schemaVersion": int32(2),
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		inv := &Inventory{}
+		err = inv.UnmarshalBSON(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inv.SchemaVersion).To(Equal(int64(CurrentSchemaVersion)))
+		Expect(inv.TotalWeight.String()).To(Equal("120.5"))
+	})
+
+	It("should only write fields changed by migrations", func() {
+		id := objectid.New()
+		orig := map[string]interface{}{
+			"_id":         id,
+			"lot":         "lot-1",
+			"totalWeight": 120.5,
+		}
+		migrated := map[string]interface{}{}
+		for k, v := range orig {
+			migrated[k] = v
+		}
+		_, err := migrateDocument(migrated)
+		Expect(err).ToNot(HaveOccurred())
+
+		filter, update := migrationUpdate(orig, migrated)
+		Expect(update).ToNot(HaveKey("_id"))
+		Expect(update).ToNot(HaveKey("lot"))
+		Expect(update).To(HaveKey("totalWeight"))
+		Expect(update).To(HaveKeyWithValue("schemaVersion", int64(CurrentSchemaVersion)))
+
+		// Filter matches original values, so concurrent changes are not overwritten
+		Expect(filter).To(HaveKeyWithValue("_id", id))
+		Expect(filter).To(HaveKeyWithValue("totalWeight", 120.5))
+		Expect(filter).To(HaveKeyWithValue("schemaVersion", map[string]interface{}{
+			"$exists": false,
+		}))
+	})
+
+	It("should migrate documents in batches after the last document", func() {
+		ids := []objectid.ObjectID{}
+		docs := []*StoredDocument{}
+		for i := 0; i < 5; i++ {
+			id := objectid.New()
+			ids = append(ids, id)
+			docs = append(docs, &StoredDocument{Fields: map[string]interface{}{
+				"_id":         id,
+				"itemID":      "a6a2f34c-3ec5-4da6-8d4e-0e0b6b1a8b39",
+				"totalWeight": 120.5,
+			}})
+		}
+
+		lastIDs := []objectid.ObjectID{}
+		findBatch := func(lastID objectid.ObjectID) ([]*StoredDocument, error) {
+			lastIDs = append(lastIDs, lastID)
+			filter, err := mgo.TransformDocument(migrationFilter(lastID))
+			Expect(err).ToNot(HaveOccurred())
+			start := 0
+			if lastID != objectid.NilObjectID {
+				Expect(filter.Lookup("_id", "$gt").ObjectID()).To(Equal(lastID))
+				for start < len(ids) && ids[start] != lastID {
+					start++
+				}
+				start++
+			}
+			end := start + 2
+			if end > len(docs) {
+				end = len(docs)
+			}
+			return docs[start:end], nil
+		}
+
+		stats, err := migrateBatches(nil, findBatch, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats.Scanned).To(Equal(5))
+		Expect(stats.Failed).To(Equal(0))
+		Expect(stats.FromVersions).To(Equal(map[int64]int{1: 5}))
+		Expect(lastIDs).To(Equal([]objectid.ObjectID{
+			objectid.NilObjectID, ids[1], ids[3], ids[4],
+		}))
+	})
+})
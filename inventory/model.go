@@ -1,9 +1,9 @@
 package inventory
 
 import (
+	"bytes"
 	"encoding/json"
-
-	util "github.com/TerrexTech/go-commonutils/commonutil"
+	"io"
 
 	"github.com/TerrexTech/uuuid"
 	"github.com/mongodb/mongo-go-driver/bson"
@@ -14,6 +14,14 @@ import (
 // AggregateID is the global AggregateID for Inventory Aggregate.
 const AggregateID int8 = 2
 
+// SaleAggregateID is the AggregateID of Sale Aggregate, which receives the
+// validated sales and their reversals.
+const SaleAggregateID int8 = 3
+
+// EventYearBucket is the EventStore year-bucket of published events and
+// event-queries.
+const EventYearBucket int16 = 2018
+
 // Inventory defines the Inventory Aggregate.
 type Inventory struct {
 	ID                 objectid.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
@@ -21,22 +29,29 @@ type Inventory struct {
 	DateArrived        int64             `bson:"dateArrived,omitempty" json:"dateArrived,omitempty"`
 	DateSold           int64             `bson:"dateSold,omitempty" json:"dateSold,omitempty"`
 	DeviceID           uuuid.UUID        `bson:"deviceID,omitempty" json:"deviceID,omitempty"`
-	DonateWeight       float64           `bson:"donateWeight,omitempty" json:"donateWeight,omitempty"`
+	DonateWeight       Decimal           `bson:"donateWeight,omitempty" json:"donateWeight,omitempty"`
+	Location           string            `bson:"location,omitempty" json:"location,omitempty"`
 	Lot                string            `bson:"lot,omitempty" json:"lot,omitempty"`
 	Name               string            `bson:"name,omitempty" json:"name,omitempty"`
 	Origin             string            `bson:"origin,omitempty" json:"origin,omitempty"`
-	Price              float64           `bson:"price,omitempty" json:"price,omitempty"`
+	Price              Decimal           `bson:"price,omitempty" json:"price,omitempty"`
 	RSCustomerID       uuuid.UUID        `bson:"rsCustomerID,omitempty" json:"rsCustomerID,omitempty"`
-	FlashSaleWeight    float64           `bson:"flashSaleWeight,omitempty" json:"flashSaleWeight,omitempty"`
+	FlashSaleWeight    Decimal           `bson:"flashSaleWeight,omitempty" json:"flashSaleWeight,omitempty"`
+	GTIN               string            `bson:"gtin,omitempty" json:"gtin,omitempty"`
 	SKU                string            `bson:"sku,omitempty" json:"sku,omitempty"`
-	SoldWeight         float64           `bson:"soldWeight,omitempty" json:"soldWeight,omitempty"`
+	SoldWeight         Decimal           `bson:"soldWeight,omitempty" json:"soldWeight,omitempty"`
 	Timestamp          int64             `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
-	TotalWeight        float64           `bson:"totalWeight,omitempty" json:"totalWeight,omitempty"`
+	TotalWeight        Decimal           `bson:"totalWeight,omitempty" json:"totalWeight,omitempty"`
+	Unit               Unit              `bson:"unit,omitempty" json:"unit,omitempty"`
 	UPC                string            `bson:"upc,omitempty" json:"upc,omitempty"`
-	WasteWeight        float64           `bson:"wasteWeight,omitempty" json:"wasteWeight,omitempty"`
+	WasteWeight        Decimal           `bson:"wasteWeight,omitempty" json:"wasteWeight,omitempty"`
 	OnFlashSale        bool              `bson:"onFlashSale,omitempty" json:"onFlashSale,omitempty"`
+	ParentItemID       uuuid.UUID        `bson:"parentItemID,omitempty" json:"parentItemID,omitempty"`
 	FlashSaleTimestamp int64             `bson:"flashSaleTimestamp,omitempty" json:"flashSaleTimestamp,omitempty"`
 	ProjectedDate      int64             `bson:"projectedDate,omitempty" json:"projectedDate,omitempty"`
+	// SchemaVersion is the schema-version of stored document. Documents are
+	// migrated to CurrentSchemaVersion when read.
+	SchemaVersion int64 `bson:"schemaVersion,omitempty" json:"schemaVersion,omitempty"`
 }
 
 // MarshalBSON returns bytes of BSON-type.
@@ -46,27 +61,38 @@ func (i Inventory) MarshalBSON() ([]byte, error) {
 		"dateArrived":        i.DateArrived,
 		"dateSold":           i.DateSold,
 		"deviceID":           i.DeviceID.String(),
-		"donateWeight":       i.DonateWeight,
+		"donateWeight":       i.DonateWeight.Decimal128(),
+		"location":           i.Location,
 		"lot":                i.Lot,
 		"name":               i.Name,
 		"origin":             i.Origin,
 		"onFlashSale":        i.OnFlashSale,
-		"price":              i.Price,
+		"price":              i.Price.Decimal128(),
 		"rsCustomerID":       i.RSCustomerID.String(),
-		"flashSaleWeight":    i.FlashSaleWeight,
+		"flashSaleWeight":    i.FlashSaleWeight.Decimal128(),
+		"gtin":               i.GTIN,
 		"sku":                i.SKU,
-		"soldWeight":         i.SoldWeight,
+		"soldWeight":         i.SoldWeight.Decimal128(),
 		"timestamp":          i.Timestamp,
-		"totalWeight":        i.TotalWeight,
+		"totalWeight":        i.TotalWeight.Decimal128(),
+		"unit":               string(i.Unit),
 		"upc":                i.UPC,
-		"wasteWeight":        i.WasteWeight,
+		"wasteWeight":        i.WasteWeight.Decimal128(),
 		"flashSaleTimestamp": i.FlashSaleTimestamp,
 		"projectedDate":      i.ProjectedDate,
+		"schemaVersion":      i.SchemaVersion,
 	}
 
+	// Marshalled fields are always of current schema-version
+	if i.SchemaVersion == 0 {
+		in["schemaVersion"] = int64(CurrentSchemaVersion)
+	}
 	if i.ID != objectid.NilObjectID {
 		in["_id"] = i.ID
 	}
+	if i.ParentItemID != (uuuid.UUID{}) {
+		in["parentItemID"] = i.ParentItemID.String()
+	}
 	return bson.Marshal(in)
 }
 
@@ -78,6 +104,7 @@ func (i *Inventory) MarshalJSON() ([]byte, error) {
 		"dateSold":           i.DateSold,
 		"deviceID":           i.DeviceID.String(),
 		"donateWeight":       i.DonateWeight,
+		"location":           i.Location,
 		"lot":                i.Lot,
 		"name":               i.Name,
 		"origin":             i.Origin,
@@ -85,39 +112,59 @@ func (i *Inventory) MarshalJSON() ([]byte, error) {
 		"price":              i.Price,
 		"rsCustomerID":       i.RSCustomerID.String(),
 		"flashSaleWeight":    i.FlashSaleWeight,
+		"gtin":               i.GTIN,
 		"sku":                i.SKU,
 		"soldWeight":         i.SoldWeight,
 		"timestamp":          i.Timestamp,
 		"totalWeight":        i.TotalWeight,
+		"unit":               string(i.Unit),
 		"upc":                i.UPC,
 		"wasteWeight":        i.WasteWeight,
 		"flashSaleTimestamp": i.FlashSaleTimestamp,
 		"projectedDate":      i.ProjectedDate,
 	}
 
+	if i.SchemaVersion != 0 {
+		in["schemaVersion"] = i.SchemaVersion
+	}
 	if i.ID != objectid.NilObjectID {
 		in["_id"] = i.ID.Hex()
 	}
+	if i.ParentItemID != (uuuid.UUID{}) {
+		in["parentItemID"] = i.ParentItemID.String()
+	}
 	return json.Marshal(in)
 }
 
 // UnmarshalBSON returns BSON-type from bytes.
+// Documents are migrated to CurrentSchemaVersion.
 func (i *Inventory) UnmarshalBSON(in []byte) error {
-	m := make(map[string]interface{})
-	err := bson.Unmarshal(in, m)
+	m, err := decodeBSONMap(in)
 	if err != nil {
 		err = errors.Wrap(err, "Unmarshal Error")
 		return err
 	}
+	_, err = migrateDocument(m)
+	if err != nil {
+		return err
+	}
 
 	err = i.unmarshalFromMap(m)
 	return err
 }
 
 // UnmarshalJSON returns JSON-type from bytes.
+// Numbers are decoded exactly, instead of as float64.
 func (i *Inventory) UnmarshalJSON(in []byte) error {
 	m := make(map[string]interface{})
-	err := json.Unmarshal(in, &m)
+	dec := json.NewDecoder(bytes.NewReader(in))
+	dec.UseNumber()
+	err := dec.Decode(&m)
+	if err == nil {
+		if _, tokenErr := dec.Token(); tokenErr != io.EOF {
+			err = errors.New("unexpected data after JSON-object")
+		}
+	}
 	if err != nil {
 		err = errors.Wrap(err, "Unmarshal Error")
 		return err
@@ -127,160 +174,43 @@ func (i *Inventory) UnmarshalJSON(in []byte) error {
 	return err
 }
 
-// unmarshalFromMap unmarshals Map into Inventory.
+// unmarshalFromMap unmarshals Map into Inventory. Every malformed field is
+// returned as a FieldError in ValidationErrors.
 func (i *Inventory) unmarshalFromMap(m map[string]interface{}) error {
-	var err error
-	var assertOK bool
-
-	// Hoping to discover a better way to do this someday
-	if m["_id"] != nil {
-		i.ID, assertOK = m["_id"].(objectid.ObjectID)
-		if !assertOK {
-			i.ID, err = objectid.FromHex(m["_id"].(string))
-			if err != nil {
-				err = errors.Wrap(err, "Error while asserting ObjectID")
-				return err
-			}
-		}
-	}
-
-	if m["itemID"] != nil {
-		i.ItemID, err = uuuid.FromString(m["itemID"].(string))
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting ItemID")
-			return err
-		}
-	}
-
-	if m["deviceID"] != nil {
-		i.DeviceID, err = uuuid.FromString(m["deviceID"].(string))
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting DeviceID")
-			return err
-		}
-	}
-
-	if m["rsCustomerID"] != nil {
-		i.RSCustomerID, err = uuuid.FromString(m["rsCustomerID"].(string))
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting RSCustomerID")
-			return err
-		}
-	}
-
-	if m["dateArrived"] != nil {
-		i.DateArrived, err = util.AssertInt64(m["dateArrived"])
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting DateArrived")
-			return err
-		}
-	}
-	if m["dateSold"] != nil {
-		i.DateSold, err = util.AssertInt64(m["dateSold"])
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting DateSold")
-			return err
-		}
-	}
-	if m["donateWeight"] != nil {
-		i.DonateWeight, err = util.AssertFloat64(m["donateWeight"])
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting DonateWeight")
-			return err
-		}
-	}
-	if m["lot"] != nil {
-		i.Lot, assertOK = m["lot"].(string)
-		if !assertOK {
-			return errors.New("Error while asserting Lot")
-		}
-	}
-	if m["name"] != nil {
-		i.Name, assertOK = m["name"].(string)
-		if !assertOK {
-			return errors.New("Error while asserting Name")
-		}
-	}
-	if m["origin"] != nil {
-		i.Origin, assertOK = m["origin"].(string)
-		if !assertOK {
-			return errors.New("Error while asserting Origin")
-		}
-	}
-	if m["price"] != nil {
-		i.Price, err = util.AssertFloat64(m["price"])
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting Price")
-			return err
-		}
-	}
-	if m["flashSaleWeight"] != nil {
-		i.FlashSaleWeight, err = util.AssertFloat64(m["flashSaleWeight"])
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting FlashSaleWeight")
-			return err
-		}
-	}
-	if m["sku"] != nil {
-		i.SKU, assertOK = m["sku"].(string)
-		if !assertOK {
-			return errors.New("Error while asserting Sku")
-		}
-	}
-	if m["soldWeight"] != nil {
-		i.SoldWeight, err = util.AssertFloat64(m["soldWeight"])
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting SoldWeight")
-			return err
-		}
-	}
-	if m["timestamp"] != nil {
-		i.Timestamp, err = util.AssertInt64(m["timestamp"])
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting Timestamp")
-			return err
-		}
-	}
-	if m["totalWeight"] != nil {
-		i.TotalWeight, err = util.AssertFloat64(m["totalWeight"])
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting TotalWeight")
-			return err
-		}
-	}
-	if m["upc"] != nil {
-		i.UPC, assertOK = m["upc"].(string)
-		if !assertOK {
-			return errors.New("Error while asserting UPC")
-		}
-	}
-	if m["onFlashSale"] != nil {
-		i.OnFlashSale, assertOK = m["onFlashSale"].(bool)
-		if !assertOK {
-			return errors.New("Error while asserting OnFlashSale")
-		}
-	}
-	if m["wasteWeight"] != nil {
-		i.WasteWeight, err = util.AssertFloat64(m["wasteWeight"])
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting WasteWeight")
-			return err
-		}
-	}
-	if m["flashSaleTimestamp"] != nil {
-		i.FlashSaleTimestamp, err = util.AssertInt64(m["flashSaleTimestamp"])
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting FlashSaleTimestamp")
-			return err
-		}
-	}
-	if m["projectedDate"] != nil {
-		i.ProjectedDate, err = util.AssertInt64(m["projectedDate"])
-		if err != nil {
-			err = errors.Wrap(err, "Error while asserting ProjectedDate")
-			return err
-		}
-	}
-
-	return nil
+	d := &fieldDecoder{m: m}
+
+	d.objectID("_id", &i.ID)
+	d.uuid("itemID", &i.ItemID)
+	d.uuid("parentItemID", &i.ParentItemID)
+	d.uuid("deviceID", &i.DeviceID)
+	d.uuid("rsCustomerID", &i.RSCustomerID)
+
+	d.int64("dateArrived", &i.DateArrived)
+	d.int64("dateSold", &i.DateSold)
+	d.int64("timestamp", &i.Timestamp)
+	d.int64("flashSaleTimestamp", &i.FlashSaleTimestamp)
+	d.int64("projectedDate", &i.ProjectedDate)
+	d.int64("schemaVersion", &i.SchemaVersion)
+
+	d.decimal("donateWeight", &i.DonateWeight)
+	d.decimal("price", &i.Price)
+	d.decimal("flashSaleWeight", &i.FlashSaleWeight)
+	d.decimal("soldWeight", &i.SoldWeight)
+	d.decimal("totalWeight", &i.TotalWeight)
+	d.decimal("wasteWeight", &i.WasteWeight)
+
+	d.string("location", &i.Location)
+	d.string("lot", &i.Lot)
+	d.string("name", &i.Name)
+	d.string("origin", &i.Origin)
+	d.string("gtin", &i.GTIN)
+	d.string("sku", &i.SKU)
+	d.string("upc", &i.UPC)
+	unit := string(i.Unit)
+	d.string("unit", &unit)
+	i.Unit = Unit(unit)
+
+	d.bool("onFlashSale", &i.OnFlashSale)
+
+	return d.err()
 }
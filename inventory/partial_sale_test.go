@@ -0,0 +1,53 @@
+package inventory
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PartialSale", func() {
+	var inv *Inventory
+
+	BeforeEach(func() {
+		inv = &Inventory{
+			TotalWeight:     decimalInt(100),
+			SoldWeight:      decimalInt(40),
+			WasteWeight:     decimalInt(10),
+			FlashSaleWeight: decimalInt(20),
+		}
+	})
+
+	It("should exclude reserved weight from available weight", func() {
+		available, err := saleAvailableWeight(inv, decimalInt(15), false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(Equal(decimalInt(35)))
+	})
+
+	It("should exclude flash-sale weight for flash-sales", func() {
+		available, err := saleAvailableWeight(inv, decimalInt(15), true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(Equal(decimalInt(15)))
+	})
+
+	It("should not return negative available weight", func() {
+		available, err := saleAvailableWeight(inv, decimalInt(80), false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(Equal(Decimal{}))
+	})
+
+	It("should fulfill sale-lines of twice the delivered available weight", func() {
+		available, err := lineAvailableWeight(inv, "createSale", decimalInt(15))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(Equal(decimalInt(70)))
+
+		available, err = lineAvailableWeight(inv, "createFlashSale", decimalInt(15))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(Equal(decimalInt(30)))
+	})
+
+	It("should fulfill waste and donation lines of the remaining weight", func() {
+		available, err := lineAvailableWeight(inv, "createWaste", Decimal{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(available).To(Equal(decimalInt(50)))
+	})
+})
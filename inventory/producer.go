@@ -0,0 +1,63 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-kafkautils/kafka"
+	"github.com/pkg/errors"
+)
+
+// loadProducer creates the Kafka-producer for publishing events,
+// if not already created.
+func loadProducer() (*kafka.Producer, error) {
+	if producer != nil {
+		return producer, nil
+	}
+
+	kafkaBrokersStr := os.Getenv("KAFKA_BROKERS")
+	p, err := kafka.NewProducer(&kafka.ProducerConfig{
+		KafkaBrokers: *commonutil.ParseHosts(kafkaBrokersStr),
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error creating producer")
+		return nil, err
+	}
+	producer = p
+	return producer, nil
+}
+
+// publishEvent produces the event on specified topic.
+func publishEvent(ctx context.Context, topic string, event *model.Event) error {
+	marshalEvent, err := json.Marshal(event)
+	if err != nil {
+		err = errors.Wrap(err, "Error marshalling event")
+		return err
+	}
+	return publishMessage(ctx, topic, marshalEvent)
+}
+
+// publishMessage produces the message on specified topic. Returns an error if
+// ctx is cancelled before producer accepts the message.
+func publishMessage(ctx context.Context, topic string, msg []byte) error {
+	return produce(ctx, kafka.CreateMessage(topic, msg))
+}
+
+// produce sends the Kafka-message to producer, unless ctx is cancelled first.
+func produce(ctx context.Context, msg *sarama.ProducerMessage) error {
+	p, err := loadProducer()
+	if err != nil {
+		return err
+	}
+	select {
+	case p.Input() <- msg:
+		return nil
+	case <-ctx.Done():
+		err = errors.Wrap(ctx.Err(), "Stopped waiting for producer")
+		return err
+	}
+}
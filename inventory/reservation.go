@@ -0,0 +1,675 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	util "github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Reservations is the collection where stock-reservations are recorded.
+// Its SchemaStruct must be Reservation. Reservations are not supported,
+// and not subtracted from available weight, if this is nil.
+var Reservations *mongo.Collection
+
+// ReservationTTL is the time a reservation holds stock, if the
+// reservation-request doesn't specify its own TTL.
+var ReservationTTL = 15 * time.Minute
+
+// Reservation is the weight held on an item between checkout and payment.
+// A reservation is active until it's released, consumed by a sale, or expired.
+type Reservation struct {
+	ID            objectid.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	ReservationID uuuid.UUID        `bson:"reservationID" json:"reservationID"`
+	ItemID        uuuid.UUID        `bson:"itemID" json:"itemID"`
+	RSCustomerID  uuuid.UUID        `bson:"rsCustomerID" json:"rsCustomerID"`
+	// EventUUID is the UUID of the event that last reserved the weight.
+	EventUUID uuuid.UUID `bson:"eventUUID" json:"eventUUID"`
+	Weight    Decimal    `bson:"weight" json:"weight"`
+	// ExpiresAt is the Unix-time (seconds) when the reservation expires.
+	ExpiresAt int64 `bson:"expiresAt" json:"expiresAt"`
+	Released  bool  `bson:"released" json:"released"`
+	Consumed  bool  `bson:"consumed" json:"consumed"`
+	Timestamp int64 `bson:"timestamp" json:"timestamp"`
+}
+
+// IsActive checks if the reservation still holds its weight at the
+// provided Unix-time.
+func (r *Reservation) IsActive(now int64) bool {
+	return !r.Released && !r.Consumed && r.ExpiresAt > now
+}
+
+// MarshalBSON returns bytes of BSON-type.
+func (r Reservation) MarshalBSON() ([]byte, error) {
+	in := map[string]interface{}{
+		"reservationID": r.ReservationID.String(),
+		"itemID":        r.ItemID.String(),
+		"eventUUID":     r.EventUUID.String(),
+		"weight":        r.Weight.Decimal128(),
+		"expiresAt":     r.ExpiresAt,
+		"released":      r.Released,
+		"consumed":      r.Consumed,
+		"timestamp":     r.Timestamp,
+	}
+	if r.RSCustomerID != (uuuid.UUID{}) {
+		in["rsCustomerID"] = r.RSCustomerID.String()
+	}
+	if r.ID != objectid.NilObjectID {
+		in["_id"] = r.ID
+	}
+	return bson.Marshal(in)
+}
+
+// UnmarshalBSON returns BSON-type from bytes.
+func (r *Reservation) UnmarshalBSON(in []byte) error {
+	m := make(map[string]interface{})
+	err := bson.Unmarshal(in, m)
+	if err != nil {
+		err = errors.Wrap(err, "Unmarshal Error")
+		return err
+	}
+
+	var assertOK bool
+	if m["_id"] != nil {
+		r.ID, assertOK = m["_id"].(objectid.ObjectID)
+		if !assertOK {
+			return errors.New("Error while asserting ObjectID")
+		}
+	}
+	reservationID, _ := m["reservationID"].(string)
+	r.ReservationID, err = uuuid.FromString(reservationID)
+	if err != nil {
+		err = errors.Wrap(err, "Error while asserting ReservationID")
+		return err
+	}
+	itemID, _ := m["itemID"].(string)
+	r.ItemID, err = uuuid.FromString(itemID)
+	if err != nil {
+		err = errors.Wrap(err, "Error while asserting ItemID")
+		return err
+	}
+	if m["rsCustomerID"] != nil {
+		rsCustomerID, _ := m["rsCustomerID"].(string)
+		r.RSCustomerID, err = uuuid.FromString(rsCustomerID)
+		if err != nil {
+			err = errors.Wrap(err, "Error while asserting RSCustomerID")
+			return err
+		}
+	}
+	if m["eventUUID"] != nil {
+		eventUUID, _ := m["eventUUID"].(string)
+		r.EventUUID, err = uuuid.FromString(eventUUID)
+		if err != nil {
+			err = errors.Wrap(err, "Error while asserting EventUUID")
+			return err
+		}
+	}
+	if m["weight"] != nil {
+		r.Weight, err = assertDecimal(m["weight"])
+		if err != nil {
+			err = errors.Wrap(err, "Error while asserting Weight")
+			return err
+		}
+	}
+	if m["expiresAt"] != nil {
+		r.ExpiresAt, err = util.AssertInt64(m["expiresAt"])
+		if err != nil {
+			err = errors.Wrap(err, "Error while asserting ExpiresAt")
+			return err
+		}
+	}
+	if m["timestamp"] != nil {
+		r.Timestamp, err = util.AssertInt64(m["timestamp"])
+		if err != nil {
+			err = errors.Wrap(err, "Error while asserting Timestamp")
+			return err
+		}
+	}
+	r.Released, _ = m["released"].(bool)
+	r.Consumed, _ = m["consumed"].(bool)
+	return nil
+}
+
+// MarshalJSON returns bytes of JSON-type.
+func (r *Reservation) MarshalJSON() ([]byte, error) {
+	in := map[string]interface{}{
+		"reservationID": r.ReservationID.String(),
+		"itemID":        r.ItemID.String(),
+		"eventUUID":     r.EventUUID.String(),
+		"weight":        r.Weight,
+		"expiresAt":     r.ExpiresAt,
+		"released":      r.Released,
+		"consumed":      r.Consumed,
+		"timestamp":     r.Timestamp,
+	}
+	if r.RSCustomerID != (uuuid.UUID{}) {
+		in["rsCustomerID"] = r.RSCustomerID.String()
+	}
+	if r.ID != objectid.NilObjectID {
+		in["_id"] = r.ID.Hex()
+	}
+	return json.Marshal(in)
+}
+
+// reservationRequest is the event-data for "reserveStock" and
+// "releaseReservation". ReservationID defaults to event's CorrelationID.
+// For releases, Items are optional, and all items are released if blank.
+type reservationRequest struct {
+	ReservationID uuuid.UUID        `json:"reservationID"`
+	TTLSec        int64             `json:"ttlSec,omitempty"`
+	Items         []reservationLine `json:"items,omitempty"`
+}
+
+// reservationLine is the weight reserved on an item.
+type reservationLine struct {
+	ItemID uuuid.UUID `json:"itemID"`
+	Weight Decimal    `json:"weight"`
+	Unit   string     `json:"unit,omitempty"`
+}
+
+// ReservationItemResult is the result of reserving or releasing an item.
+type ReservationItemResult struct {
+	ItemID    uuuid.UUID `json:"itemID,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	ErrorCode int        `json:"errorCode,omitempty"`
+	// ReservedWeight is the weight held by this reservation.
+	ReservedWeight Decimal `json:"reservedWeight"`
+	// AvailableWeight is the weight left for sales and other reservations.
+	AvailableWeight Decimal `json:"availableWeight"`
+	Released        bool    `json:"released,omitempty"`
+	Unit            Unit    `json:"unit,omitempty"`
+}
+
+// ReservationResp is the response when stock is reserved or released.
+type ReservationResp struct {
+	ReservationID uuuid.UUID              `json:"reservationID"`
+	ExpiresAt     int64                   `json:"expiresAt,omitempty"`
+	Result        []ReservationItemResult `json:"result"`
+}
+
+// eventTime returns the Unix-time (seconds) of the event. Reservation-expiry
+// is checked against the event-time, so replayed events see the same
+// active reservations as when they were first processed.
+func eventTime(event *model.Event) int64 {
+	if event.NanoTime > 0 {
+		return event.NanoTime / int64(time.Second)
+	}
+	return time.Now().Unix()
+}
+
+// findReservations returns the reservation-lines of item, optionally
+// limited to the reservation if reservationID is not zero.
+func findReservations(
+	itemID uuuid.UUID, reservationID uuuid.UUID,
+) ([]*Reservation, error) {
+	if Reservations == nil {
+		return nil, errors.New("reservations are not configured")
+	}
+	filter := map[string]interface{}{
+		"itemID": itemID.String(),
+	}
+	if reservationID != (uuuid.UUID{}) {
+		filter["reservationID"] = reservationID.String()
+	}
+	findResults, err := Reservations.Find(filter)
+	if err != nil {
+		err = errors.Wrap(err, "Error finding reservations")
+		return nil, err
+	}
+	reservations := []*Reservation{}
+	for _, r := range findResults {
+		reservation, assertOK := r.(*Reservation)
+		if assertOK {
+			reservations = append(reservations, reservation)
+		}
+	}
+	return reservations, nil
+}
+
+// reservedWeight returns the weight held on item by reservations active at
+// the provided time, excluding the lines of reservation exclude.
+// Only active reservations are read, using the itemID_active_expiresAt index.
+// The caller must hold the item-lock.
+func reservedWeight(itemID uuuid.UUID, now int64, exclude uuuid.UUID) (Decimal, error) {
+	if Reservations == nil {
+		return Decimal{}, nil
+	}
+	findResults, err := Reservations.Find(map[string]interface{}{
+		"itemID":   itemID.String(),
+		"released": false,
+		"consumed": false,
+		"expiresAt": map[string]interface{}{
+			"$gt": now,
+		},
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error finding active reservations")
+		return Decimal{}, err
+	}
+	reservations := []*Reservation{}
+	for _, r := range findResults {
+		reservation, assertOK := r.(*Reservation)
+		if assertOK {
+			reservations = append(reservations, reservation)
+		}
+	}
+	return activeReservedWeight(reservations, now, exclude)
+}
+
+// activeReservedWeight returns the total weight of the reservations active
+// at the provided time, excluding the lines of reservation exclude.
+func activeReservedWeight(
+	reservations []*Reservation, now int64, exclude uuuid.UUID,
+) (Decimal, error) {
+	reserved := Decimal{}
+	for _, r := range reservations {
+		if exclude != (uuuid.UUID{}) && r.ReservationID == exclude {
+			continue
+		}
+		if !r.IsActive(now) {
+			continue
+		}
+		var err error
+		reserved, err = reserved.Add(r.Weight)
+		if err != nil {
+			return Decimal{}, err
+		}
+	}
+	return reserved, nil
+}
+
+// consumeReservation reduces the item's line of the reservation by the
+// weight applied by a sale, if the line is active. The line is consumed once
+// its weight is used up. Since each sale-delivery applies its delivered
+// weight (see deliveredWeight), a reservation stays active until all
+// deliveries of the sale are applied.
+// The caller must hold the item-lock.
+func consumeReservation(
+	reservationID uuuid.UUID, itemID uuuid.UUID, now int64, weight Decimal,
+) (bool, error) {
+	if Reservations == nil || reservationID == (uuuid.UUID{}) {
+		return false, nil
+	}
+	reservations, err := findReservations(itemID, reservationID)
+	if err != nil {
+		return false, err
+	}
+	if len(reservations) == 0 || !reservations[0].IsActive(now) {
+		return false, nil
+	}
+	remaining, consumed, err := consumeWeight(reservations[0], weight)
+	if err != nil {
+		err = errors.Wrap(err, "Error calculating remaining reserved weight")
+		return false, err
+	}
+	_, err = Reservations.UpdateMany(
+		map[string]interface{}{
+			"reservationID": reservationID.String(),
+			"itemID":        itemID.String(),
+		},
+		map[string]interface{}{
+			"weight":   remaining.Decimal128(),
+			"consumed": consumed,
+		},
+	)
+	if err != nil {
+		err = errors.Wrap(err, "Error consuming reservation")
+		return false, err
+	}
+	return true, nil
+}
+
+// consumeWeight returns the weight left on reservation after the sale of
+// weight, and whether the reservation is used up.
+func consumeWeight(r *Reservation, weight Decimal) (Decimal, bool, error) {
+	remaining, err := r.Weight.Sub(weight)
+	if err != nil {
+		return Decimal{}, false, err
+	}
+	if !remaining.GreaterThan(Decimal{}) {
+		return Decimal{}, true, nil
+	}
+	return remaining, false, nil
+}
+
+// reserveStock handles "reserveStock" events. The weight of each item is
+// held for the TTL, and is not available to sales or other reservations
+// until it's released, consumed by a sale, or expired. Reserving an item
+// again in the same reservation replaces its weight and expiry.
+func reserveStock(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	collection *mongo.Collection,
+	event *model.Event,
+	tenant uuuid.UUID,
+) *model.Document {
+	req, errDoc := parseReservationRequest(event)
+	if errDoc != nil {
+		return errDoc
+	}
+	if len(req.Items) == 0 {
+		err := errors.New("Reservation-Event: no items to reserve")
+		log.Println(err)
+		return reservationErrorDoc(event, err, UserError)
+	}
+
+	ttl := int64(ReservationTTL / time.Second)
+	if req.TTLSec != 0 {
+		ttl = req.TTLSec
+	}
+	if ttl <= 0 {
+		err := fmt.Errorf("Reservation-Event: ttlSec must be positive, got %d", ttl)
+		log.Println(err)
+		return reservationErrorDoc(event, err, UserError)
+	}
+	now := eventTime(event)
+	expiresAt := now + ttl
+
+	result := []ReservationItemResult{}
+	for _, item := range req.Items {
+		r := reserveItem(
+			ctx, etcd, collection, event, tenant, req.ReservationID, item, now, expiresAt,
+		)
+		reportLineError(ctx, r.ErrorCode)
+		result = append(result, r)
+	}
+	return reservationDoc(event, ReservationResp{
+		ReservationID: req.ReservationID,
+		ExpiresAt:     expiresAt,
+		Result:        result,
+	})
+}
+
+// reserveItem reserves the weight on item, if the weight is available.
+func reserveItem(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	collection *mongo.Collection,
+	event *model.Event,
+	tenant uuuid.UUID,
+	reservationID uuuid.UUID,
+	item reservationLine,
+	now int64,
+	expiresAt int64,
+) ReservationItemResult {
+	itemResult := ReservationItemResult{ItemID: item.ItemID}
+	fail := func(err error, errCode int) ReservationItemResult {
+		err = errors.Wrap(err, "Reservation-Event")
+		log.Println(err)
+		itemResult.Error = err.Error()
+		itemResult.ErrorCode = errCode
+		return itemResult
+	}
+
+	if item.ItemID == (uuuid.UUID{}) {
+		return fail(errors.New("missing ItemID"), UserError)
+	}
+	if !item.Weight.GreaterThan(Decimal{}) {
+		err := fmt.Errorf("reserve-weight must be positive, got %s", item.Weight)
+		return fail(err, UserError)
+	}
+	var reqUnit Unit
+	if item.Unit != "" {
+		unit, err := ParseUnit(item.Unit)
+		if err != nil {
+			return fail(err, UserError)
+		}
+		reqUnit = unit
+	}
+
+	unlock, err := lockItem(ctx, etcd, item.ItemID.String())
+	if err != nil {
+		if isContextError(err) {
+			return fail(err, Timeout)
+		}
+		return fail(err, Unavailable)
+	}
+	defer unlock()
+
+	findResult, err := collection.FindOne(map[string]interface{}{
+		"itemID": item.ItemID.String(),
+	})
+	if err != nil {
+		errCode := mongoErrorCode(err)
+		err = errors.Wrap(err, "Error getting Item from database")
+		return fail(err, errCode)
+	}
+	inv, assertOK := findResult.(*Inventory)
+	if !assertOK {
+		err = errors.New("error asserting database-result to Inventory-Item")
+		return fail(err, InternalError)
+	}
+	err = checkItemTenant(event, tenant, inv)
+	if err != nil {
+		return fail(err, TenantError)
+	}
+
+	itemUnit := inv.WeightUnit()
+	itemResult.Unit = itemUnit
+	weight := item.Weight
+	if reqUnit != "" {
+		weight, err = ConvertWeight(item.Weight, reqUnit, itemUnit)
+		if err != nil {
+			err = errors.Wrap(err, "Error converting weight")
+			return fail(err, UserError)
+		}
+	}
+
+	existing, err := findReservations(item.ItemID, reservationID)
+	if err != nil {
+		return fail(err, DatabaseError)
+	}
+	reserved, err := reservedWeight(item.ItemID, now, reservationID)
+	if err != nil {
+		return fail(err, DatabaseError)
+	}
+	available, err := inv.RemainingWeight()
+	if err == nil {
+		available, err = available.Sub(reserved)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "Error calculating available weight")
+		return fail(err, UserError)
+	}
+
+	// Same event delivered again
+	if len(existing) > 0 && existing[0].EventUUID == event.UUID {
+		itemResult.ReservedWeight = existing[0].Weight
+		// Reserved weight never exceeds available weight, so this cannot
+		// go out of range
+		itemResult.AvailableWeight, _ = available.Sub(existing[0].Weight)
+		return itemResult
+	}
+	if weight.GreaterThan(available) {
+		err = fmt.Errorf(
+			"reserve-weight %s exceeds the available weight %s", weight, available,
+		)
+		return fail(err, UserError)
+	}
+
+	err = checkContext(ctx)
+	if err != nil {
+		return fail(err, Timeout)
+	}
+	reservation := &Reservation{
+		ReservationID: reservationID,
+		ItemID:        item.ItemID,
+		RSCustomerID:  inv.RSCustomerID,
+		EventUUID:     event.UUID,
+		Weight:        weight,
+		ExpiresAt:     expiresAt,
+		Timestamp:     time.Now().Unix(),
+	}
+	if len(existing) == 0 {
+		_, err = Reservations.InsertOne(reservation)
+	} else {
+		_, err = Reservations.UpdateMany(
+			map[string]interface{}{
+				"reservationID": reservationID.String(),
+				"itemID":        item.ItemID.String(),
+			},
+			map[string]interface{}{
+				"eventUUID": event.UUID.String(),
+				"weight":    weight.Decimal128(),
+				"expiresAt": expiresAt,
+				"released":  false,
+				"consumed":  false,
+				"timestamp": reservation.Timestamp,
+			},
+		)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "Error writing reservation to database")
+		return fail(err, DatabaseError)
+	}
+
+	itemResult.ReservedWeight = weight
+	// Weight is checked against available weight above
+	itemResult.AvailableWeight, _ = available.Sub(weight)
+	return itemResult
+}
+
+// releaseReservation handles "releaseReservation" events. The active lines
+// of reservation are released, limited to the specified items if any.
+func releaseReservation(
+	ctx context.Context,
+	collection *mongo.Collection,
+	event *model.Event,
+	tenant uuuid.UUID,
+) *model.Document {
+	req, errDoc := parseReservationRequest(event)
+	if errDoc != nil {
+		return errDoc
+	}
+
+	filter := map[string]interface{}{
+		"reservationID": req.ReservationID.String(),
+		"released":      false,
+		"consumed":      false,
+		"expiresAt": map[string]interface{}{
+			"$gt": eventTime(event),
+		},
+	}
+	if len(req.Items) > 0 {
+		itemIDs := []string{}
+		for _, item := range req.Items {
+			itemIDs = append(itemIDs, item.ItemID.String())
+		}
+		filter["itemID"] = map[string]interface{}{
+			"$in": itemIDs,
+		}
+	}
+	err := scopeFilter(event, tenant, filter)
+	if err != nil {
+		err = errors.Wrap(err, "Reservation-Event")
+		log.Println(err)
+		return reservationErrorDoc(event, err, TenantError)
+	}
+
+	findResults, err := Reservations.Find(filter)
+	if err != nil {
+		err = errors.Wrap(err, "Reservation-Event: Error finding reservations")
+		log.Println(err)
+		return reservationErrorDoc(event, err, DatabaseError)
+	}
+	if len(findResults) == 0 {
+		err = fmt.Errorf(
+			"Reservation-Event: no active reservation found with ReservationID %s",
+			req.ReservationID,
+		)
+		log.Println(err)
+		return reservationErrorDoc(event, err, UserError)
+	}
+	err = checkContext(ctx)
+	if err != nil {
+		err = errors.Wrap(err, "Reservation-Event")
+		log.Println(err)
+		return reservationErrorDoc(event, err, Timeout)
+	}
+	_, err = Reservations.UpdateMany(filter, map[string]interface{}{
+		"released": true,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Reservation-Event: Error releasing reservation")
+		log.Println(err)
+		return reservationErrorDoc(event, err, DatabaseError)
+	}
+
+	result := []ReservationItemResult{}
+	for _, r := range findResults {
+		reservation, assertOK := r.(*Reservation)
+		if !assertOK {
+			continue
+		}
+		result = append(result, ReservationItemResult{
+			ItemID:         reservation.ItemID,
+			ReservedWeight: reservation.Weight,
+			Released:       true,
+		})
+	}
+	return reservationDoc(event, ReservationResp{
+		ReservationID: req.ReservationID,
+		Result:        result,
+	})
+}
+
+// parseReservationRequest parses the event-data for reservation-events.
+func parseReservationRequest(event *model.Event) (*reservationRequest, *model.Document) {
+	if Reservations == nil {
+		err := errors.New("Reservation-Event: reservations are not configured")
+		log.Println(err)
+		return nil, reservationErrorDoc(event, err, InternalError)
+	}
+	req := &reservationRequest{}
+	err := json.Unmarshal(event.Data, req)
+	if err != nil {
+		err = errors.Wrap(err, "Reservation-Event: Error unmarshalling reservation-data")
+		log.Println(err)
+		return nil, reservationErrorDoc(event, err, UserError)
+	}
+	if req.ReservationID == (uuuid.UUID{}) {
+		req.ReservationID = event.CorrelationID
+	}
+	return req, nil
+}
+
+// reservationDoc returns the Document with the reservation-result.
+func reservationDoc(event *model.Event, resp ReservationResp) *model.Document {
+	marshalResult, err := json.Marshal(resp)
+	if err != nil {
+		err = errors.Wrap(err, "Reservation-Event: Error marshalling result")
+		log.Println(err)
+		return reservationErrorDoc(event, err, InternalError)
+	}
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        marshalResult,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+// reservationErrorDoc returns the Document for failed reservation-events.
+func reservationErrorDoc(event *model.Event, err error, errCode int16) *model.Document {
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		Error:         err.Error(),
+		ErrorCode:     errCode,
+		EventAction:   event.EventAction,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
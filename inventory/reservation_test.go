@@ -0,0 +1,129 @@
+package inventory
+
+import (
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reservation", func() {
+	var reservation *Reservation
+
+	BeforeEach(func() {
+		reservationID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		itemID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		eventUUID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+
+		reservation = &Reservation{
+			ReservationID: reservationID,
+			ItemID:        itemID,
+			EventUUID:     eventUUID,
+			Weight:        decimalFloat(2.5),
+			ExpiresAt:     1540000900,
+			Timestamp:     1540000000,
+		}
+	})
+
+	It("should marshal and unmarshal reservations as BSON", func() {
+		reservation.Consumed = true
+		marshalReservation, err := reservation.MarshalBSON()
+		Expect(err).ToNot(HaveOccurred())
+
+		unmarshalReservation := &Reservation{}
+		err = unmarshalReservation.UnmarshalBSON(marshalReservation)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unmarshalReservation).To(Equal(reservation))
+	})
+
+	It("should be active until released, consumed, or expired", func() {
+		Expect(reservation.IsActive(1540000000)).To(BeTrue())
+		Expect(reservation.IsActive(1540000900)).To(BeFalse())
+
+		reservation.Released = true
+		Expect(reservation.IsActive(1540000000)).To(BeFalse())
+		reservation.Released = false
+		reservation.Consumed = true
+		Expect(reservation.IsActive(1540000000)).To(BeFalse())
+	})
+
+	It("should use event-time for expiry", func() {
+		eventNano := time.Unix(1540000000, 0).UnixNano()
+		Expect(eventTime(&model.Event{NanoTime: eventNano})).To(
+			Equal(int64(1540000000)),
+		)
+	})
+
+	Context("sale availability", func() {
+		var inv *Inventory
+		var other *Reservation
+
+		BeforeEach(func() {
+			inv = &Inventory{
+				ItemID:      reservation.ItemID,
+				TotalWeight: decimalInt(10),
+				SoldWeight:  decimalInt(4),
+			}
+			otherID, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+			other = &Reservation{
+				ReservationID: otherID,
+				ItemID:        reservation.ItemID,
+				Weight:        decimalInt(1),
+				ExpiresAt:     1540000600,
+			}
+		})
+
+		available := func(now int64, exclude uuuid.UUID) Decimal {
+			reserved, err := activeReservedWeight(
+				[]*Reservation{reservation, other}, now, exclude,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			weight, err := saleAvailableWeight(inv, reserved, false)
+			Expect(err).ToNot(HaveOccurred())
+			return weight
+		}
+
+		It("should hold reserved weight from sales", func() {
+			Expect(available(1540000000, uuuid.UUID{})).To(Equal(decimalFloat(2.5)))
+		})
+
+		It("should let sales use the weight of their own reservation", func() {
+			Expect(available(1540000000, reservation.ReservationID)).To(
+				Equal(decimalInt(5)),
+			)
+		})
+
+		It("should return released weight to sales", func() {
+			reservation.Released = true
+			Expect(available(1540000000, uuuid.UUID{})).To(Equal(decimalInt(5)))
+		})
+
+		It("should return expired weight to sales", func() {
+			Expect(available(1540000600, uuuid.UUID{})).To(Equal(decimalFloat(3.5)))
+			Expect(available(1540000900, uuuid.UUID{})).To(Equal(decimalInt(6)))
+		})
+
+		It("should consume reservation over all deliveries of a sale", func() {
+			reservation.Weight = decimalInt(2)
+			applied, err := deliveredWeight(decimalInt(2))
+			Expect(err).ToNot(HaveOccurred())
+
+			remaining, consumed, err := consumeWeight(reservation, applied)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(consumed).To(BeFalse())
+			Expect(remaining).To(Equal(decimalInt(1)))
+
+			reservation.Weight = remaining
+			remaining, consumed, err = consumeWeight(reservation, applied)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(consumed).To(BeTrue())
+			Expect(remaining).To(Equal(Decimal{}))
+		})
+	})
+})
@@ -0,0 +1,222 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	mgo "github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy controls how commands that fail with retryable errors are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times the command is run, including
+	// the first attempt.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. The delay doubles on
+	// each retry, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy returns the default policy of three attempts,
+// with delays starting at 200ms.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+	}
+}
+
+// Retry is the policy for retrying commands in HandleWithRetry.
+var Retry = DefaultRetryPolicy()
+
+// jitterRand is the random-source for retry-jitter.
+var jitterRand = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// IsRetryable checks if the error-code is for temporary errors, such as
+// database-errors or unavailable locks, after which the command can be retried.
+// Other errors, such as invalid input, fail the same way when retried.
+func IsRetryable(errCode int16) bool {
+	return errCode == DatabaseError || errCode == Unavailable
+}
+
+// isBackendFailure checks if the Document failed with a retryable error from
+// a backend.
+func isBackendFailure(doc *model.Document) bool {
+	return doc != nil && doc.Error != "" && IsRetryable(doc.ErrorCode)
+}
+
+// mongoErrorCode returns the error-code for the error of a Mongo-operation.
+// Items not found are not backend-failures, and are returned as UserError.
+func mongoErrorCode(err error) int {
+	if errors.Cause(err) == mgo.ErrNoDocuments {
+		return UserError
+	}
+	return DatabaseError
+}
+
+// lineFailuresKey is the context-key for the lineFailures of a command.
+type lineFailuresKey struct{}
+
+// lineFailures records if lines of a multi-line command, such as a sale,
+// failed with Mongo-errors. Line-errors are returned in the command's result
+// instead of as Document-errors, but are still reported to MongoBreaker.
+type lineFailures struct {
+	mongo bool
+}
+
+// reportLineError records the error-code of a failed line of the command
+// running with ctx. Lines failing with DatabaseError count as Mongo-failures.
+func reportLineError(ctx context.Context, errCode int) {
+	failures, ok := ctx.Value(lineFailuresKey{}).(*lineFailures)
+	if ok && errCode == DatabaseError {
+		failures.mongo = true
+	}
+}
+
+// runWithBreaker runs the command-handler if MongoBreaker allows it, and
+// reports the result to breaker. The command is a Mongo-failure if it fails
+// with DatabaseError, or if any of its lines do (see reportLineError).
+// Commands fail fast with Unavailable error-code while the breaker is open.
+func runWithBreaker(
+	ctx context.Context,
+	event *model.Event,
+	handle func(ctx context.Context) *model.Document,
+) *model.Document {
+	err := MongoBreaker.Allow()
+	if err != nil {
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     Unavailable,
+			EventAction:   event.EventAction,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	failures := &lineFailures{}
+	doc := handle(context.WithValue(ctx, lineFailuresKey{}, failures))
+	if (isBackendFailure(doc) && doc.ErrorCode == DatabaseError) || failures.mongo {
+		MongoBreaker.Failure()
+	} else {
+		MongoBreaker.Success()
+	}
+	return doc
+}
+
+// Delay returns the delay before the retry following the attempt (1-based).
+// The delay is exponential with "equal jitter", so it's randomly between
+// half and full of the exponential delay.
+func (r RetryPolicy) Delay(attempt int) time.Duration {
+	delay := r.BaseDelay
+	for i := 1; i < attempt && delay < r.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.MaxDelay {
+		delay = r.MaxDelay
+	}
+	half := int64(delay / 2)
+	if half <= 0 {
+		return delay
+	}
+
+	jitterRand.Lock()
+	jitter := jitterRand.Int63n(half + 1)
+	jitterRand.Unlock()
+	return time.Duration(half + jitter)
+}
+
+// HandleWithRetry runs the command-handler, and retries it as per Retry if
+// the returned Document has a retryable error-code. The handler is guarded
+// by MongoBreaker. Events still failing
+// after the last attempt are published to dead-letter topic. The Document
+// from the last attempt is returned.
+//
+// Only Document-level errors are retried. Errors on individual lines of
+// multi-line commands, such as sales, are returned in the Document-result,
+// since retrying would apply the successful lines again.
+//
+// The handler is run with ctx, which should carry the command's deadline
+// (see CommandContext). No retries are made once ctx is cancelled, and the
+// command fails with Timeout error-code.
+func HandleWithRetry(
+	ctx context.Context,
+	event *model.Event,
+	handle func(ctx context.Context) *model.Document,
+) *model.Document {
+	failures := []string{}
+	firstFailedAt := int64(0)
+	reason := DeadLetterRetriesExhausted
+
+	var doc *model.Document
+	for attempt := 1; ; attempt++ {
+		err := checkContext(ctx)
+		if err != nil {
+			err = errors.Wrap(err, "Retry")
+			log.Println(err)
+			return timeoutDoc(event, err)
+		}
+		doc = runWithBreaker(ctx, event, handle)
+		if !isBackendFailure(doc) {
+			return doc
+		}
+		if firstFailedAt == 0 {
+			firstFailedAt = time.Now().UnixNano()
+		}
+		failure := fmt.Sprintf(
+			"attempt %d: error-code %d: %s", attempt, doc.ErrorCode, doc.Error,
+		)
+		failures = append(failures, failure)
+		if attempt >= Retry.MaxAttempts {
+			break
+		}
+
+		delay := Retry.Delay(attempt)
+		deadline, hasDeadline := ctx.Deadline()
+		if !hasDeadline || time.Now().Add(delay).Before(deadline) {
+			log.Printf(
+				"Retry: Event %s failed with retryable error-code %d, retrying in %s",
+				event.UUID, doc.ErrorCode, delay,
+			)
+			select {
+			case <-time.After(delay):
+				continue
+			case <-ctx.Done():
+			}
+		}
+		// Cancelled, or next retry cannot run before the deadline
+		reason = DeadLetterDeadlineExceeded
+		break
+	}
+
+	deadLetter(&DeadLetter{
+		Event:         *event,
+		Reason:        reason,
+		Error:         doc.Error,
+		ErrorCode:     doc.ErrorCode,
+		Retryable:     true,
+		Attempts:      len(failures),
+		Failures:      failures,
+		FirstFailedAt: firstFailedAt,
+	})
+	if reason == DeadLetterDeadlineExceeded {
+		err := fmt.Errorf(
+			"Retry: deadline passed after %d attempts, last error: %s",
+			len(failures), doc.Error,
+		)
+		return timeoutDoc(event, err)
+	}
+	return doc
+}
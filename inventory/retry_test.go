@@ -0,0 +1,130 @@
+package inventory
+
+import (
+	"context"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	mgo "github.com/mongodb/mongo-go-driver/mongo"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Retry", func() {
+	var (
+		ctx          context.Context
+		defaultRetry RetryPolicy
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		defaultRetry = Retry
+		Retry = RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    4 * time.Millisecond,
+		}
+	})
+
+	AfterEach(func() {
+		Retry = defaultRetry
+	})
+
+	It("should classify error-codes as retryable or permanent", func() {
+		Expect(IsRetryable(DatabaseError)).To(BeTrue())
+		Expect(IsRetryable(Unavailable)).To(BeTrue())
+		Expect(IsRetryable(UserError)).To(BeFalse())
+		Expect(IsRetryable(ValidationError)).To(BeFalse())
+	})
+
+	It("should not classify items not found as backend-failures", func() {
+		err := errors.Wrap(mgo.ErrNoDocuments, "FindOne Decoding Error")
+		Expect(mongoErrorCode(err)).To(Equal(UserError))
+		err = errors.Wrap(errors.New("server selection timeout"), "FindOne Decoding Error")
+		Expect(mongoErrorCode(err)).To(Equal(DatabaseError))
+	})
+
+	It("should back off exponentially with jitter, up to max-delay", func() {
+		policy := RetryPolicy{
+			BaseDelay: 100 * time.Millisecond,
+			MaxDelay:  time.Second,
+		}
+		for i := 0; i < 20; i++ {
+			ms := time.Millisecond
+			Expect(policy.Delay(1)).To(BeNumerically("~", 75*ms, 25*ms))
+			Expect(policy.Delay(3)).To(BeNumerically("~", 300*ms, 100*ms))
+			Expect(policy.Delay(10)).To(BeNumerically("~", 750*ms, 250*ms))
+		}
+	})
+
+	It("should retry retryable errors until max-attempts", func() {
+		attempts := 0
+		doc := HandleWithRetry(ctx, &model.Event{}, func(context.Context) *model.Document {
+			attempts++
+			return &model.Document{
+				Error:     "database unavailable",
+				ErrorCode: DatabaseError,
+			}
+		})
+		Expect(attempts).To(Equal(3))
+		Expect(doc.ErrorCode).To(Equal(int16(DatabaseError)))
+	})
+
+	It("should not retry permanent errors or successful commands", func() {
+		attempts := 0
+		HandleWithRetry(ctx, &model.Event{}, func(context.Context) *model.Document {
+			attempts++
+			return &model.Document{
+				Error:     "invalid input",
+				ErrorCode: UserError,
+			}
+		})
+		Expect(attempts).To(Equal(1))
+
+		attempts = 0
+		doc := HandleWithRetry(ctx, &model.Event{}, func(context.Context) *model.Document {
+			attempts++
+			if attempts < 2 {
+				return &model.Document{
+					Error:     "lock unavailable",
+					ErrorCode: Unavailable,
+				}
+			}
+			return &model.Document{}
+		})
+		Expect(attempts).To(Equal(2))
+		Expect(doc.Error).To(BeEmpty())
+	})
+
+	It("should not retry past the deadline", func() {
+		Retry.BaseDelay = 50 * time.Millisecond
+		Retry.MaxDelay = 50 * time.Millisecond
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		attempts := 0
+		doc := HandleWithRetry(ctx, &model.Event{}, func(context.Context) *model.Document {
+			attempts++
+			return &model.Document{
+				Error:     "database unavailable",
+				ErrorCode: DatabaseError,
+			}
+		})
+		Expect(attempts).To(Equal(1))
+		Expect(doc.ErrorCode).To(Equal(int16(Timeout)))
+	})
+
+	It("should not run the command once cancelled", func() {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		attempts := 0
+		doc := HandleWithRetry(ctx, &model.Event{}, func(context.Context) *model.Document {
+			attempts++
+			return &model.Document{}
+		})
+		Expect(attempts).To(Equal(0))
+		Expect(doc.ErrorCode).To(Equal(int16(Timeout)))
+	})
+})
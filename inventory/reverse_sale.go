@@ -0,0 +1,449 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// saleReversal is the event-data for "cancelSale" and "returnItems".
+// Items are only used for returns, and default to ToWaste of reversal.
+type saleReversal struct {
+	SaleCorrelationID uuuid.UUID   `json:"saleCorrelationID"`
+	ToWaste           bool         `json:"toWaste,omitempty"`
+	Items             []returnLine `json:"items,omitempty"`
+}
+
+// returnLine is the weight returned for an item in "returnItems".
+type returnLine struct {
+	ItemID  uuuid.UUID `json:"itemID"`
+	Weight  Decimal    `json:"weight"`
+	Unit    string     `json:"unit,omitempty"`
+	ToWaste *bool      `json:"toWaste,omitempty"`
+}
+
+// ReversalItemResult is the result of putting back the weight sold from an item.
+type ReversalItemResult struct {
+	ItemID    uuuid.UUID `json:"itemID,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	ErrorCode int        `json:"errorCode,omitempty"`
+	// ReturnedWeight is the weight put back by this event.
+	ReturnedWeight Decimal `json:"returnedWeight"`
+	// TotalReturnedWeight is the weight put back for the sale so far.
+	TotalReturnedWeight Decimal `json:"totalReturnedWeight"`
+	ToWaste             bool    `json:"toWaste"`
+	TotalSoldWeight     Decimal `json:"totalSoldWeight"`
+	TotalWasteWeight    Decimal `json:"totalWasteWeight"`
+	Unit                Unit    `json:"unit,omitempty"`
+}
+
+// SaleReversalResp is the response when a sale is cancelled, or its items
+// are returned.
+type SaleReversalResp struct {
+	SaleCorrelationID uuuid.UUID           `json:"saleCorrelationID"`
+	Result            []ReversalItemResult `json:"result"`
+}
+
+// reverseSale handles "cancelSale" and "returnItems" events. The sale is
+// identified by its CorrelationID, and the weights are put back on items
+// as recorded in SaleLedger. Cancelled sales cannot be cancelled or returned
+// again, and returns cannot exceed the sold weight.
+func reverseSale(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	collection *mongo.Collection,
+	event *model.Event,
+	tenant uuuid.UUID,
+) *model.Document {
+	reversal := &saleReversal{}
+	err := json.Unmarshal(event.Data, reversal)
+	if err != nil {
+		err = errors.Wrap(err, "SaleReversal-Event: Error unmarshalling reversal-data")
+		log.Println(err)
+		return reversalErrorDoc(event, err, InternalError)
+	}
+	if reversal.SaleCorrelationID == (uuuid.UUID{}) {
+		err = errors.New("SaleReversal-Event: missing saleCorrelationID")
+		log.Println(err)
+		return reversalErrorDoc(event, err, UserError)
+	}
+
+	lines, err := findSaleLines(reversal.SaleCorrelationID, uuuid.UUID{})
+	if err != nil {
+		err = errors.Wrap(err, "SaleReversal-Event")
+		log.Println(err)
+		return reversalErrorDoc(event, err, DatabaseError)
+	}
+	if len(lines) == 0 {
+		err = fmt.Errorf(
+			"SaleReversal-Event: no sale found with CorrelationID %s",
+			reversal.SaleCorrelationID,
+		)
+		log.Println(err)
+		return reversalErrorDoc(event, err, UserError)
+	}
+
+	result := []ReversalItemResult{}
+	if event.ServiceAction == "cancelSale" {
+		cancelled := true
+		for _, line := range lines {
+			cancelled = cancelled && line.Cancelled
+		}
+		if cancelled {
+			err = fmt.Errorf(
+				"SaleReversal-Event: sale %s is already cancelled",
+				reversal.SaleCorrelationID,
+			)
+			log.Println(err)
+			return reversalErrorDoc(event, err, UserError)
+		}
+		for _, line := range lines {
+			if line.Cancelled {
+				continue
+			}
+			r := reverseSaleLine(
+				ctx, etcd, collection, event, tenant,
+				reversal.SaleCorrelationID, line.ItemID, nil, reversal.ToWaste,
+			)
+			result = append(result, r)
+		}
+	} else {
+		if len(reversal.Items) == 0 {
+			err = errors.New("SaleReversal-Event: no items to return")
+			log.Println(err)
+			return reversalErrorDoc(event, err, UserError)
+		}
+		soldItems := map[uuuid.UUID]*SaleLine{}
+		for _, line := range lines {
+			soldItems[line.ItemID] = line
+		}
+		for _, item := range reversal.Items {
+			r := returnSaleItem(
+				ctx, etcd, collection, event, tenant, reversal, item, soldItems,
+			)
+			result = append(result, r)
+		}
+	}
+
+	for _, r := range result {
+		reportLineError(ctx, r.ErrorCode)
+	}
+	marshalResult, err := json.Marshal(SaleReversalResp{
+		SaleCorrelationID: reversal.SaleCorrelationID,
+		Result:            result,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "SaleReversal-Event: Error marshalling result")
+		log.Println(err)
+		return reversalErrorDoc(event, err, InternalError)
+	}
+
+	if PublishSaleEvents {
+		err = publishReversalEvent(ctx, event, marshalResult)
+		if err != nil {
+			// Reversal is already applied, so only the error is logged
+			err = errors.Wrap(err, "SaleReversal-Event: Error publishing reversal-event")
+			log.Println(err)
+		}
+	}
+
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        marshalResult,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+// returnSaleItem validates the return-line, and puts back its weight.
+func returnSaleItem(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	collection *mongo.Collection,
+	event *model.Event,
+	tenant uuuid.UUID,
+	reversal *saleReversal,
+	item returnLine,
+	soldItems map[uuuid.UUID]*SaleLine,
+) ReversalItemResult {
+	itemResult := ReversalItemResult{ItemID: item.ItemID}
+	if soldItems[item.ItemID] == nil {
+		err := fmt.Errorf(
+			"item %s is not part of sale %s", item.ItemID, reversal.SaleCorrelationID,
+		)
+		err = errors.Wrap(err, "SaleReversal-Event")
+		log.Println(err)
+		itemResult.Error = err.Error()
+		itemResult.ErrorCode = UserError
+		return itemResult
+	}
+	if !item.Weight.GreaterThan(Decimal{}) {
+		err := fmt.Errorf("return-weight must be positive, got %s", item.Weight)
+		err = errors.Wrap(err, "SaleReversal-Event")
+		log.Println(err)
+		itemResult.Error = err.Error()
+		itemResult.ErrorCode = UserError
+		return itemResult
+	}
+
+	weight := &returnWeight{weight: item.Weight}
+	if item.Unit != "" {
+		unit, err := ParseUnit(item.Unit)
+		if err != nil {
+			err = errors.Wrap(err, "SaleReversal-Event")
+			log.Println(err)
+			itemResult.Error = err.Error()
+			itemResult.ErrorCode = UserError
+			return itemResult
+		}
+		weight.unit = unit
+	}
+	toWaste := reversal.ToWaste
+	if item.ToWaste != nil {
+		toWaste = *item.ToWaste
+	}
+	return reverseSaleLine(
+		ctx, etcd, collection, event, tenant,
+		reversal.SaleCorrelationID, item.ItemID, weight, toWaste,
+	)
+}
+
+// returnWeight is the weight to return, in the specified unit.
+// Blank unit is the item's unit.
+type returnWeight struct {
+	weight Decimal
+	unit   Unit
+}
+
+// reverseSaleLine puts back the weight sold from item in the sale. All
+// remaining sold weight is put back, and the sale-line is cancelled, if
+// weight is nil. The returned weight is added to item's waste-weight if
+// toWaste is true.
+func reverseSaleLine(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	collection *mongo.Collection,
+	event *model.Event,
+	tenant uuuid.UUID,
+	saleCorrelationID uuuid.UUID,
+	itemID uuuid.UUID,
+	weight *returnWeight,
+	toWaste bool,
+) ReversalItemResult {
+	itemResult := ReversalItemResult{
+		ItemID:  itemID,
+		ToWaste: toWaste,
+	}
+	fail := func(err error, errCode int) ReversalItemResult {
+		err = errors.Wrap(err, "SaleReversal-Event")
+		log.Println(err)
+		itemResult.Error = err.Error()
+		itemResult.ErrorCode = errCode
+		return itemResult
+	}
+
+	unlock, err := lockItem(ctx, etcd, itemID.String())
+	if err != nil {
+		if isContextError(err) {
+			return fail(err, Timeout)
+		}
+		return fail(err, Unavailable)
+	}
+	defer unlock()
+
+	// Sale-line is read again while item is locked,
+	// so concurrent reversals cannot put back the same weight
+	lines, err := findSaleLines(saleCorrelationID, itemID)
+	if err != nil {
+		return fail(err, DatabaseError)
+	}
+	if len(lines) == 0 {
+		return fail(errors.New("sale-line not found"), UserError)
+	}
+	line := lines[0]
+	if line.Cancelled {
+		return fail(errors.New("sale is already cancelled"), UserError)
+	}
+
+	findResult, err := collection.FindOne(map[string]interface{}{
+		"itemID": itemID.String(),
+	})
+	if err != nil {
+		errCode := mongoErrorCode(err)
+		err = errors.Wrap(err, "Error getting Item from database")
+		return fail(err, errCode)
+	}
+	inv, assertOK := findResult.(*Inventory)
+	if !assertOK {
+		err = errors.New("error asserting database-result to Inventory-Item")
+		return fail(err, InternalError)
+	}
+	err = checkItemTenant(event, tenant, inv)
+	if err != nil {
+		return fail(err, TenantError)
+	}
+
+	itemUnit := inv.WeightUnit()
+	itemResult.Unit = itemUnit
+	remaining, err := line.RemainingWeight()
+	if err != nil {
+		err = errors.Wrap(err, "Error calculating sold weight not yet returned")
+		return fail(err, UserError)
+	}
+	returned := remaining
+	if weight != nil {
+		returned = weight.weight
+		if weight.unit != "" {
+			returned, err = ConvertWeight(weight.weight, weight.unit, itemUnit)
+			if err != nil {
+				err = errors.Wrap(err, "Error converting weight")
+				return fail(err, UserError)
+			}
+		}
+		// Sale-lines record the weight applied by each delivery of the sale,
+		// so the return-weight is applied the same way
+		returned, err = deliveredWeight(returned)
+		if err != nil {
+			err = errors.Wrap(err, "Error calculating return-weight")
+			return fail(err, UserError)
+		}
+		if returned.GreaterThan(remaining) {
+			err = fmt.Errorf(
+				"return-weight %s exceeds the sold weight not yet returned (%s)",
+				returned, remaining,
+			)
+			return fail(err, UserError)
+		}
+	}
+
+	soldField := "soldWeight"
+	soldWeight := inv.SoldWeight
+	if line.ServiceAction == "createFlashSale" {
+		soldField = "flashSaleWeight"
+		soldWeight = inv.FlashSaleWeight
+	}
+	newSoldWeight, err := soldWeight.Sub(returned)
+	if err != nil {
+		err = errors.Wrap(err, "Error calculating new sold weight")
+		return fail(err, UserError)
+	}
+	if newSoldWeight.IsNegative() {
+		err = fmt.Errorf(
+			"return-weight %s exceeds the item's %s (%s)",
+			returned, soldField, soldWeight,
+		)
+		return fail(err, UserError)
+	}
+
+	updateArgs := map[string]interface{}{
+		soldField: newSoldWeight.Decimal128(),
+	}
+	wasteWeight := inv.WasteWeight
+	if toWaste {
+		wasteWeight, err = wasteWeight.Add(returned)
+		if err != nil {
+			err = errors.Wrap(err, "Error calculating new waste weight")
+			return fail(err, UserError)
+		}
+		updateArgs["wasteWeight"] = wasteWeight.Decimal128()
+	}
+	// Returned weight never exceeds sold weight, so this cannot go out of range
+	totalReturned, _ := line.ReturnedWeight.Add(returned)
+	err = checkContext(ctx)
+	if err != nil {
+		return fail(err, Timeout)
+	}
+	if !returned.IsZero() {
+		_, err = collection.UpdateMany(
+			map[string]interface{}{
+				"itemID": itemID.String(),
+			},
+			updateArgs,
+		)
+		if err != nil {
+			err = errors.Wrap(err, "Error writing new weight to database")
+			return fail(err, DatabaseError)
+		}
+	}
+
+	lineUpdate := map[string]interface{}{
+		"returnedWeight": totalReturned.Decimal128(),
+	}
+	if weight == nil {
+		lineUpdate["cancelled"] = true
+	}
+	_, err = SaleLedger.UpdateMany(
+		map[string]interface{}{
+			"correlationID": line.CorrelationID.String(),
+			"itemID":        itemID.String(),
+		},
+		lineUpdate,
+	)
+	if err != nil {
+		err = errors.Wrapf(
+			err,
+			"Error updating sale-line after putting back %s on item %s",
+			returned, itemID,
+		)
+		// Reversal is undone so it can be retried
+		if !returned.IsZero() {
+			restoreErr := restoreItemWeights(collection, inv, updateArgs)
+			if restoreErr != nil {
+				// Item is updated without sale-line, so this must be fixed manually
+				restoreErr = errors.Wrap(restoreErr, "SaleReversal-Event")
+				log.Println(restoreErr)
+			}
+		}
+		return fail(err, DatabaseError)
+	}
+
+	itemResult.ReturnedWeight = returned
+	itemResult.TotalReturnedWeight = totalReturned
+	itemResult.TotalSoldWeight = newSoldWeight
+	itemResult.TotalWasteWeight = wasteWeight
+	return itemResult
+}
+
+// publishReversalEvent publishes the reversal-result to Sale Aggregate.
+func publishReversalEvent(ctx context.Context, event *model.Event, result []byte) error {
+	uuid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating UUID for reversal-event")
+		return err
+	}
+	return publishEvent(ctx, os.Getenv("KAFKA_PRODUCER_EVENT_TOPIC"), &model.Event{
+		AggregateID:   SaleAggregateID,
+		CorrelationID: event.CorrelationID,
+		Data:          result,
+		EventAction:   "update",
+		NanoTime:      time.Now().UnixNano(),
+		ServiceAction: event.ServiceAction,
+		UUID:          uuid,
+		UserUUID:      event.UserUUID,
+		YearBucket:    EventYearBucket,
+	})
+}
+
+// reversalErrorDoc returns the Document for failed sale-reversals.
+func reversalErrorDoc(event *model.Event, err error, errCode int16) *model.Document {
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		Error:         err.Error(),
+		ErrorCode:     errCode,
+		EventAction:   event.EventAction,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
@@ -0,0 +1,282 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	util "github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	"github.com/pkg/errors"
+)
+
+// SaleLedger is the collection where applied sale-lines are recorded, so the
+// sales can later be cancelled or returned. Its SchemaStruct must be SaleLine.
+// Sale-lines are not recorded if this is nil.
+var SaleLedger *mongo.Collection
+
+// SaleLine is the weight sold from an item in a sale, identified by
+// the sale's CorrelationID. Weights are as applied to the item, which is
+// the deliveredWeight of each delivery of the sale.
+type SaleLine struct {
+	ID             objectid.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	CorrelationID  uuuid.UUID        `bson:"correlationID" json:"correlationID"`
+	ItemID         uuuid.UUID        `bson:"itemID" json:"itemID"`
+	ServiceAction  string            `bson:"serviceAction" json:"serviceAction"`
+	SoldWeight     Decimal           `bson:"soldWeight" json:"soldWeight"`
+	ReturnedWeight Decimal           `bson:"returnedWeight" json:"returnedWeight"`
+	Cancelled      bool              `bson:"cancelled" json:"cancelled"`
+	Timestamp      int64             `bson:"timestamp" json:"timestamp"`
+}
+
+// RemainingWeight returns the sold weight that's not returned yet.
+func (s *SaleLine) RemainingWeight() (Decimal, error) {
+	return s.SoldWeight.Sub(s.ReturnedWeight)
+}
+
+// MarshalBSON returns bytes of BSON-type.
+func (s SaleLine) MarshalBSON() ([]byte, error) {
+	in := map[string]interface{}{
+		"correlationID":  s.CorrelationID.String(),
+		"itemID":         s.ItemID.String(),
+		"serviceAction":  s.ServiceAction,
+		"soldWeight":     s.SoldWeight.Decimal128(),
+		"returnedWeight": s.ReturnedWeight.Decimal128(),
+		"cancelled":      s.Cancelled,
+		"timestamp":      s.Timestamp,
+	}
+	if s.ID != objectid.NilObjectID {
+		in["_id"] = s.ID
+	}
+	return bson.Marshal(in)
+}
+
+// UnmarshalBSON returns BSON-type from bytes.
+func (s *SaleLine) UnmarshalBSON(in []byte) error {
+	m := make(map[string]interface{})
+	err := bson.Unmarshal(in, m)
+	if err != nil {
+		err = errors.Wrap(err, "Unmarshal Error")
+		return err
+	}
+
+	var assertOK bool
+	if m["_id"] != nil {
+		s.ID, assertOK = m["_id"].(objectid.ObjectID)
+		if !assertOK {
+			return errors.New("Error while asserting ObjectID")
+		}
+	}
+	correlationID, _ := m["correlationID"].(string)
+	s.CorrelationID, err = uuuid.FromString(correlationID)
+	if err != nil {
+		err = errors.Wrap(err, "Error while asserting CorrelationID")
+		return err
+	}
+	itemID, _ := m["itemID"].(string)
+	s.ItemID, err = uuuid.FromString(itemID)
+	if err != nil {
+		err = errors.Wrap(err, "Error while asserting ItemID")
+		return err
+	}
+	s.ServiceAction, _ = m["serviceAction"].(string)
+	s.Cancelled, _ = m["cancelled"].(bool)
+	if m["soldWeight"] != nil {
+		s.SoldWeight, err = assertDecimal(m["soldWeight"])
+		if err != nil {
+			err = errors.Wrap(err, "Error while asserting SoldWeight")
+			return err
+		}
+	}
+	if m["returnedWeight"] != nil {
+		s.ReturnedWeight, err = assertDecimal(m["returnedWeight"])
+		if err != nil {
+			err = errors.Wrap(err, "Error while asserting ReturnedWeight")
+			return err
+		}
+	}
+	if m["timestamp"] != nil {
+		s.Timestamp, err = util.AssertInt64(m["timestamp"])
+		if err != nil {
+			err = errors.Wrap(err, "Error while asserting Timestamp")
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalJSON returns bytes of JSON-type.
+func (s *SaleLine) MarshalJSON() ([]byte, error) {
+	in := map[string]interface{}{
+		"correlationID":  s.CorrelationID.String(),
+		"itemID":         s.ItemID.String(),
+		"serviceAction":  s.ServiceAction,
+		"soldWeight":     s.SoldWeight,
+		"returnedWeight": s.ReturnedWeight,
+		"cancelled":      s.Cancelled,
+		"timestamp":      s.Timestamp,
+	}
+	if s.ID != objectid.NilObjectID {
+		in["_id"] = s.ID.Hex()
+	}
+	return json.Marshal(in)
+}
+
+// deliveredWeight returns the weight applied by each delivery of a sale or
+// reversal. This is a temporary fix for the duplicated message-issue, where
+// each message is delivered twice, so each delivery applies half the weight.
+func deliveredWeight(weight Decimal) (Decimal, error) {
+	return weight.Mul(decimalHalf)
+}
+
+// lineWeight returns the weight of a sale-line whose deliveries each apply
+// the delivered weight. This is the inverse of deliveredWeight.
+func lineWeight(delivered Decimal) (Decimal, error) {
+	return delivered.Div(decimalHalf)
+}
+
+// findSaleLines returns the recorded lines of the sale. If itemID is not
+// zero, only the line for that item is returned.
+func findSaleLines(correlationID uuuid.UUID, itemID uuuid.UUID) ([]*SaleLine, error) {
+	if SaleLedger == nil {
+		return nil, errors.New("sale-ledger is not configured")
+	}
+	filter := map[string]interface{}{
+		"correlationID": correlationID.String(),
+	}
+	if itemID != (uuuid.UUID{}) {
+		filter["itemID"] = itemID.String()
+	}
+	findResults, err := SaleLedger.Find(filter)
+	if err != nil {
+		err = errors.Wrap(err, "Error finding sale-lines")
+		return nil, err
+	}
+	lines := []*SaleLine{}
+	for _, r := range findResults {
+		line, assertOK := r.(*SaleLine)
+		if assertOK {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// recordSaleLine adds the weight sold from item to the sale's ledger-line.
+// The caller must hold the item-lock.
+func recordSaleLine(
+	correlationID uuuid.UUID,
+	itemID uuuid.UUID,
+	serviceAction string,
+	weight Decimal,
+) error {
+	if SaleLedger == nil {
+		return nil
+	}
+	lines, err := findSaleLines(correlationID, itemID)
+	if err != nil {
+		return err
+	}
+
+	if len(lines) == 0 {
+		_, err = SaleLedger.InsertOne(&SaleLine{
+			CorrelationID: correlationID,
+			ItemID:        itemID,
+			ServiceAction: serviceAction,
+			SoldWeight:    weight,
+			Timestamp:     time.Now().Unix(),
+		})
+		if err != nil {
+			err = errors.Wrap(err, "Error inserting sale-line")
+			return err
+		}
+		return nil
+	}
+
+	soldWeight, err := lines[0].SoldWeight.Add(weight)
+	if err != nil {
+		err = errors.Wrap(err, "Error calculating sale-line weight")
+		return err
+	}
+	_, err = SaleLedger.UpdateMany(
+		map[string]interface{}{
+			"correlationID": correlationID.String(),
+			"itemID":        itemID.String(),
+		},
+		map[string]interface{}{
+			"soldWeight": soldWeight.Decimal128(),
+		},
+	)
+	if err != nil {
+		err = errors.Wrap(err, "Error updating sale-line")
+		return err
+	}
+	return nil
+}
+
+// recordSaleLineWithRetry records the sale-line, retrying as per Retry if
+// it fails. No retries are made once ctx is cancelled.
+// The caller must hold the item-lock.
+func recordSaleLineWithRetry(
+	ctx context.Context,
+	correlationID uuuid.UUID,
+	itemID uuuid.UUID,
+	serviceAction string,
+	weight Decimal,
+) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = recordSaleLine(correlationID, itemID, serviceAction, weight)
+		if err == nil || attempt >= Retry.MaxAttempts {
+			return err
+		}
+		select {
+		case <-time.After(Retry.Delay(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// restoreItemWeights sets the item-fields in updateArgs back to their values
+// in inv, which is the item before the update. This undoes sales and
+// reversals whose sale-line cannot be written, so they can be retried.
+// The caller must hold the item-lock.
+func restoreItemWeights(
+	collection *mongo.Collection,
+	inv *Inventory,
+	updateArgs map[string]interface{},
+) error {
+	restoreArgs := map[string]interface{}{}
+	for field := range updateArgs {
+		switch field {
+		case "donateWeight":
+			restoreArgs[field] = inv.DonateWeight.Decimal128()
+		case "flashSaleWeight":
+			restoreArgs[field] = inv.FlashSaleWeight.Decimal128()
+		case "onFlashSale":
+			restoreArgs[field] = inv.OnFlashSale
+		case "soldWeight":
+			restoreArgs[field] = inv.SoldWeight.Decimal128()
+		case "wasteWeight":
+			restoreArgs[field] = inv.WasteWeight.Decimal128()
+		default:
+			return fmt.Errorf("cannot restore item-field %s", field)
+		}
+	}
+	_, err := collection.UpdateMany(
+		map[string]interface{}{
+			"itemID": inv.ItemID.String(),
+		},
+		restoreArgs,
+	)
+	if err != nil {
+		err = errors.Wrapf(err, "Error restoring weights of item %s", inv.ItemID)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package inventory
+
+import (
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SaleLedger", func() {
+	It("should marshal and unmarshal sale-lines as BSON", func() {
+		cid, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		itemID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+
+		line := &SaleLine{
+			CorrelationID:  cid,
+			ItemID:         itemID,
+			ServiceAction:  "createSale",
+			SoldWeight:     decimalFloat(6.12),
+			ReturnedWeight: decimalFloat(1.5),
+			Cancelled:      true,
+			Timestamp:      1540000000,
+		}
+		marshalLine, err := line.MarshalBSON()
+		Expect(err).ToNot(HaveOccurred())
+
+		unmarshalLine := &SaleLine{}
+		err = unmarshalLine.UnmarshalBSON(marshalLine)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unmarshalLine).To(Equal(line))
+		Expect(unmarshalLine.RemainingWeight()).To(Equal(decimalFloat(4.62)))
+	})
+	It("should record and return sold weight in the same units", func() {
+		// Each sale and reversal is delivered twice
+		sold, err := deliveredWeight(decimalInt(10))
+		Expect(err).ToNot(HaveOccurred())
+		line := &SaleLine{}
+		for i := 0; i < 2; i++ {
+			line.SoldWeight, err = line.SoldWeight.Add(sold)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(line.SoldWeight).To(Equal(decimalInt(10)))
+
+		returned, err := deliveredWeight(decimalInt(10))
+		Expect(err).ToNot(HaveOccurred())
+		for i := 0; i < 2; i++ {
+			remaining, err := line.RemainingWeight()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(returned.GreaterThan(remaining)).To(BeFalse())
+			line.ReturnedWeight, err = line.ReturnedWeight.Add(returned)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(line.RemainingWeight()).To(Equal(Decimal{}))
+	})
+
+	It("should only restore weight-fields of items", func() {
+		err := restoreItemWeights(nil, &Inventory{}, map[string]interface{}{
+			"lot": "test-lot",
+		})
+		Expect(err).To(HaveOccurred())
+	})
+})
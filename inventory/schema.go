@@ -0,0 +1,503 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// schemaRoot is the field-name used for errors on the event-data itself.
+const schemaRoot = "(root)"
+
+// Schema is a JSON Schema (draft-07). Only the keywords used by command-schemas
+// are supported, and schemas using any other keyword fail to compile, so
+// schemas don't silently validate less than they declare:
+//
+//   - Annotations: $id, $schema (draft-07 only), title, description.
+//   - References: $ref to root's definitions ("#/definitions/<name>"), and
+//     definitions. As in draft-07, keywords beside $ref are ignored.
+//   - Any type: type, enum, allOf, anyOf, if, then, else.
+//   - Objects: properties, required, additionalProperties (boolean only).
+//   - Arrays: items (a single schema only, not an array of schemas), minItems.
+//   - Strings: minLength, maxLength, pattern (Go RE2-syntax).
+//   - Numbers: minimum, exclusiveMinimum (a number, as in draft-07).
+type Schema struct {
+	ID          string             `json:"$id,omitempty"`
+	SchemaURI   string             `json:"$schema,omitempty"`
+	Ref         string             `json:"$ref,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Definitions map[string]*Schema `json:"definitions,omitempty"`
+
+	Type                 schemaTypes        `json:"type,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	If                   *Schema            `json:"if,omitempty"`
+	Then                 *Schema            `json:"then,omitempty"`
+	Else                 *Schema            `json:"else,omitempty"`
+
+	pattern *regexp.Regexp
+	ref     *Schema
+}
+
+// schemaTypes is the "type" keyword, which is either a type-name or
+// an array of type-names.
+type schemaTypes []string
+
+// UnmarshalJSON decodes a single type-name or an array of type-names.
+func (t *schemaTypes) UnmarshalJSON(in []byte) error {
+	name := ""
+	if json.Unmarshal(in, &name) == nil {
+		*t = schemaTypes{name}
+		return nil
+	}
+	names := []string{}
+	err := json.Unmarshal(in, &names)
+	if err != nil {
+		return errors.New("type must be a string or an array of strings")
+	}
+	*t = names
+	return nil
+}
+
+// MarshalJSON encodes a single type as type-name.
+func (t schemaTypes) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// schemaKeywords are the supported keywords of Schema.
+var schemaKeywords = map[string]bool{
+	"$id": true, "$schema": true, "$ref": true, "title": true,
+	"description": true, "definitions": true, "type": true, "enum": true,
+	"properties": true, "required": true, "additionalProperties": true,
+	"items": true, "minItems": true, "minLength": true, "maxLength": true,
+	"pattern": true, "minimum": true, "exclusiveMinimum": true,
+	"allOf": true, "anyOf": true, "if": true, "then": true, "else": true,
+}
+
+// CompileSchema parses the JSON Schema document, and resolves its references.
+// Returns an error if the schema uses keywords, or forms of keywords, that
+// are not supported (see Schema).
+func CompileSchema(doc []byte) (*Schema, error) {
+	var raw interface{}
+	err := json.Unmarshal(doc, &raw)
+	if err != nil {
+		err = errors.Wrap(err, "Schema Error: Error decoding schema")
+		return nil, err
+	}
+	err = checkSchemaKeywords("#", raw)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	dec.UseNumber()
+	s := &Schema{}
+	err = dec.Decode(s)
+	if err != nil {
+		err = errors.Wrap(err, "Schema Error: Error decoding schema")
+		return nil, err
+	}
+	err = s.compile(s)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// checkSchemaKeywords checks that the decoded schema at path, and its
+// subschemas, only use supported keywords.
+func checkSchemaKeywords(path string, v interface{}) error {
+	m, isObject := v.(map[string]interface{})
+	if !isObject {
+		return fmt.Errorf("Schema Error: schema at %s must be an object", path)
+	}
+	keywords := make([]string, 0, len(m))
+	for k := range m {
+		keywords = append(keywords, k)
+	}
+	// Sorted, so errors are reported consistently
+	sort.Strings(keywords)
+
+	for _, k := range keywords {
+		if !schemaKeywords[k] {
+			return fmt.Errorf("Schema Error: unsupported keyword %s at %s", k, path)
+		}
+		value := m[k]
+		keywordPath := path + "/" + k
+		var err error
+		switch k {
+		case "$schema":
+			uri, _ := value.(string)
+			if strings.TrimSuffix(uri, "#") != "http://json-schema.org/draft-07/schema" {
+				err = fmt.Errorf(
+					"Schema Error: unsupported $schema %v at %s, must be draft-07",
+					value, path,
+				)
+			}
+		case "additionalProperties":
+			if _, isBool := value.(bool); !isBool {
+				err = fmt.Errorf(
+					"Schema Error: %s must be a boolean, schemas are not supported",
+					keywordPath,
+				)
+			}
+		case "items", "if", "then", "else":
+			err = checkSchemaKeywords(keywordPath, value)
+		case "allOf", "anyOf":
+			subschemas, isArray := value.([]interface{})
+			if !isArray {
+				return fmt.Errorf("Schema Error: %s must be an array", keywordPath)
+			}
+			for i, sub := range subschemas {
+				err = checkSchemaKeywords(fmt.Sprintf("%s/%d", keywordPath, i), sub)
+				if err != nil {
+					break
+				}
+			}
+		case "properties", "definitions":
+			subschemas, isMap := value.(map[string]interface{})
+			if !isMap {
+				return fmt.Errorf("Schema Error: %s must be an object", keywordPath)
+			}
+			names := make([]string, 0, len(subschemas))
+			for name := range subschemas {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				err = checkSchemaKeywords(keywordPath+"/"+name, subschemas[name])
+				if err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compile compiles patterns, and resolves references against definitions
+// of root. Only references to root's definitions ("#/definitions/<name>")
+// are supported.
+func (s *Schema) compile(root *Schema) error {
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/definitions/")
+		if name == s.Ref || root.Definitions[name] == nil {
+			return fmt.Errorf("Schema Error: unresolved reference %s", s.Ref)
+		}
+		s.ref = root.Definitions[name]
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			err = errors.Wrapf(err, "Schema Error: invalid pattern %s", s.Pattern)
+			return err
+		}
+		s.pattern = pattern
+	}
+	for _, t := range s.Type {
+		switch t {
+		case "object", "array", "string", "number", "integer", "boolean", "null":
+		default:
+			return fmt.Errorf("Schema Error: unknown type %s", t)
+		}
+	}
+
+	children := []*Schema{s.Items, s.If, s.Then, s.Else}
+	children = append(children, s.AllOf...)
+	children = append(children, s.AnyOf...)
+	for _, p := range s.Properties {
+		children = append(children, p)
+	}
+	for _, d := range s.Definitions {
+		children = append(children, d)
+	}
+	for _, c := range children {
+		if c == nil {
+			continue
+		}
+		err := c.compile(root)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateJSON validates the JSON-document against schema. Each violation is
+// returned as a FieldError, with fields named by their path, such as
+// "items[0].weight".
+func (s *Schema) ValidateJSON(doc []byte) ValidationErrors {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	if err == nil {
+		if _, tokenErr := dec.Token(); tokenErr != io.EOF {
+			err = errors.New("unexpected data after JSON-value")
+		}
+	}
+	if err != nil {
+		return ValidationErrors{
+			FieldError{Field: schemaRoot, Message: "invalid JSON: " + err.Error()},
+		}
+	}
+
+	return s.validateValue(v)
+}
+
+// validateValue validates the decoded JSON-value against schema. Numbers are
+// json.Number, or int64 for values from Protobuf messages.
+func (s *Schema) validateValue(v interface{}) ValidationErrors {
+	errs := s.validate("", v)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (s *Schema) validate(path string, v interface{}) ValidationErrors {
+	if s.ref != nil {
+		return s.ref.validate(path, v)
+	}
+
+	errs := ValidationErrors{}
+	fail := func(format string, args ...interface{}) {
+		field := path
+		if field == "" {
+			field = schemaRoot
+		}
+		errs = append(errs, FieldError{
+			Field:   field,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if len(s.Type) > 0 && !s.matchesType(v) {
+		fail("must be %s, got %s", strings.Join(s.Type, " or "), schemaValueType(v))
+		return errs
+	}
+	if len(s.Enum) > 0 && !s.matchesEnum(v) {
+		fail("must be one of %s", s.enumString())
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		errs = append(errs, s.validateObject(path, value)...)
+	case []interface{}:
+		if s.MinItems != nil && len(value) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.Items != nil {
+			for i, item := range value {
+				itemPath := fmt.Sprintf("%s[%d]", path, i)
+				errs = append(errs, s.Items.validate(itemPath, item)...)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(value)
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("must have at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("must have at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(value) {
+			fail("must match pattern %s", s.Pattern)
+		}
+	case json.Number:
+		n, err := value.Float64()
+		if err != nil {
+			fail("invalid number %s", value)
+			break
+		}
+		s.validateNumber(n, fail)
+	case int64:
+		s.validateNumber(float64(value), fail)
+	}
+
+	for _, a := range s.AllOf {
+		errs = append(errs, a.validate(path, v)...)
+	}
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, a := range s.AnyOf {
+			if len(a.validate(path, v)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			msg := "must match at least one of the allowed forms"
+			if s.Description != "" {
+				msg += ": " + s.Description
+			}
+			fail("%s", msg)
+		}
+	}
+
+	if s.If != nil {
+		if len(s.If.validate(path, v)) == 0 {
+			if s.Then != nil {
+				errs = append(errs, s.Then.validate(path, v)...)
+			}
+		} else if s.Else != nil {
+			errs = append(errs, s.Else.validate(path, v)...)
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateNumber(
+	n float64, fail func(format string, args ...interface{}),
+) {
+	if s.Minimum != nil && n < *s.Minimum {
+		fail("must be at least %v", *s.Minimum)
+	}
+	if s.ExclusiveMinimum != nil && n <= *s.ExclusiveMinimum {
+		fail("must be greater than %v", *s.ExclusiveMinimum)
+	}
+}
+
+func (s *Schema) validateObject(path string, m map[string]interface{}) ValidationErrors {
+	errs := ValidationErrors{}
+	prefix := path
+	if prefix != "" {
+		prefix += "."
+	}
+
+	for _, field := range s.Required {
+		if _, exists := m[field]; !exists {
+			errs = append(errs, FieldError{
+				Field:   prefix + field,
+				Message: "is required",
+			})
+		}
+	}
+
+	// Fields are validated in sorted order, so errors are reported consistently
+	fields := make([]string, 0, len(m))
+	for field := range m {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		p, exists := s.Properties[field]
+		if exists {
+			errs = append(errs, p.validate(prefix+field, m[field])...)
+			continue
+		}
+		if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+			errs = append(errs, FieldError{
+				Field:   prefix + field,
+				Message: "is not allowed",
+			})
+		}
+	}
+	return errs
+}
+
+func (s *Schema) matchesType(v interface{}) bool {
+	for _, t := range s.Type {
+		switch value := v.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			}
+			if t == "integer" {
+				n, err := value.Float64()
+				if err == nil && !math.IsInf(n, 0) && n == math.Trunc(n) {
+					return true
+				}
+			}
+		case int64:
+			if t == "number" || t == "integer" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Schema) matchesEnum(v interface{}) bool {
+	for _, e := range s.Enum {
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) enumString() string {
+	values := make([]string, len(s.Enum))
+	for i, e := range s.Enum {
+		values[i] = fmt.Sprintf("%q", fmt.Sprint(e))
+	}
+	return strings.Join(values, ", ")
+}
+
+// schemaValueType returns the JSON-type of decoded value, for error-messages.
+func schemaValueType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number, int64:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return fmt.Sprintf("%T", v)
+}
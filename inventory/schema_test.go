@@ -0,0 +1,162 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schema", func() {
+	var itemID string
+
+	BeforeEach(func() {
+		id, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		itemID = id.String()
+	})
+
+	fields := func(errs ValidationErrors) []string {
+		f := []string{}
+		for _, e := range errs {
+			f = append(f, e.Field)
+		}
+		return f
+	}
+
+	It("should compile every command-schema source", func() {
+		Expect(CheckCommandSchemas()).To(Succeed())
+		for key, source := range commandSchemaSources {
+			s, err := newCommandSchema(key, source)
+			Expect(err).ToNot(HaveOccurred(), "schema %v", key)
+			Expect(FindCommandSchema(key.eventAction, key.serviceAction)).To(Equal(s))
+		}
+		Expect(CommandSchemas()).To(HaveLen(len(commandSchemaSources)))
+	})
+
+	It("should compile all command-schemas as self-contained documents", func() {
+		schemas := CommandSchemas()
+		Expect(schemas).ToNot(BeEmpty())
+		for _, s := range schemas {
+			_, err := CompileSchema(s.Document)
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
+	It("should fall back to schema for any ServiceAction", func() {
+		s := FindCommandSchema("update", "someAction")
+		Expect(s).ToNot(BeNil())
+		Expect(s.ServiceAction).To(Equal("*"))
+		Expect(s.FileName()).To(Equal("update.schema.json"))
+
+		s = FindCommandSchema("update", "createSale")
+		Expect(s.FileName()).To(Equal("update.createSale.schema.json"))
+
+		Expect(FindCommandSchema("unknown", "createSale")).To(BeNil())
+	})
+
+	It("should return error for unknown keywords and references", func() {
+		_, err := CompileSchema([]byte(`{"type": "object", "format": "uuid"}`))
+		Expect(err).To(MatchError(ContainSubstring("unsupported keyword format at #")))
+		_, err = CompileSchema([]byte(`{"$ref": "#/definitions/missing"}`))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return error for unsupported keywords in subschemas", func() {
+		unsupported := map[string]string{
+			`{"properties": {"a": {"maximum": 1}}}`:  "#/properties/a",
+			`{"items": {"uniqueItems": true}}`:       "#/items",
+			`{"anyOf": [{}, {"oneOf": []}]}`:         "#/anyOf/1",
+			`{"definitions": {"d": {"const": 1}}}`:   "#/definitions/d",
+			`{"if": {}, "then": {"not": {}}}`:        "#/then",
+			`{"allOf": [{"patternProperties": {}}]}`: "#/allOf/0",
+		}
+		for doc, path := range unsupported {
+			_, err := CompileSchema([]byte(doc))
+			Expect(err).To(MatchError(ContainSubstring("at "+path)), doc)
+		}
+	})
+
+	It("should return error for unsupported forms of keywords", func() {
+		docs := []string{
+			`{"additionalProperties": {"type": "string"}}`,
+			`{"items": [{"type": "string"}]}`,
+			`{"$schema": "http://json-schema.org/draft-04/schema#"}`,
+			`{"exclusiveMinimum": true}`,
+		}
+		for _, doc := range docs {
+			_, err := CompileSchema([]byte(doc))
+			Expect(err).To(HaveOccurred(), doc)
+		}
+		_, err := CompileSchema(
+			[]byte(`{"$schema": "http://json-schema.org/draft-07/schema#"}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should validate single and bulk inserts", func() {
+		s := FindCommandSchema("insert", "")
+		item := `{"itemID": "` + itemID + `", "totalWeight": 12.5, "timestamp": 10}`
+		Expect(s.Validate([]byte(item))).To(BeNil())
+		Expect(s.Validate([]byte(`[` + item + `]`))).To(BeNil())
+		Expect(s.Validate([]byte(`{"items": [` + item + `], "ordered": true}`))).To(BeNil())
+
+		errs := s.Validate([]byte(`{"items": [{"lot": "a", "timestamp": "10"}]}`))
+		Expect(fields(errs)).To(ConsistOf("items[0].itemID", "items[0].timestamp"))
+	})
+
+	It("should return field-errors for sale-lines", func() {
+		s := FindCommandSchema("update", "createSale")
+		data := `{"items": [
+			{"itemID": "` + itemID + `", "weight": "1.5", "upc": "036000291452"},
+			{"barcode": "212345005996"},
+			{"sku": "test-sku"},
+			{"weight": 2},
+			{"itemID": "not-a-uuid", "weight": 1, "unit": "kgs"}
+		]}`
+		errs := s.Validate([]byte(data))
+		Expect(fields(errs)).To(ConsistOf(
+			"items[2].weight",
+			"items[3]",
+			"items[4].itemID",
+			"items[4].unit",
+		))
+	})
+
+	It("should return error for malformed JSON", func() {
+		errs := FindCommandSchema("delete", "").Validate([]byte(`{"itemID": 1} {}`))
+		Expect(fields(errs)).To(Equal([]string{"(root)"}))
+	})
+
+	It("should reject events not matching schema before handling", func() {
+		mockEvent := &model.Event{
+			EventAction:   "update",
+			ServiceAction: "transferInventory",
+			AggregateID:   2,
+			Data:          []byte(`{"itemID": "` + itemID + `"}`),
+		}
+		kr := Update(context.Background(), nil, nil, mockEvent)
+		Expect(kr.ErrorCode).To(Equal(int16(ValidationError)))
+
+		result := validationResult{}
+		err := json.Unmarshal(kr.Result, &result)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fields(result.FieldErrors)).To(Equal([]string{"toLocation"}))
+	})
+
+	It("should not check schema if SchemaValidation is disabled", func() {
+		SchemaValidation = false
+		defer func() {
+			SchemaValidation = true
+		}()
+		mockEvent := &model.Event{
+			EventAction:   "update",
+			ServiceAction: "transferInventory",
+			Data:          []byte(`{}`),
+		}
+		Expect(checkSchema(context.Background(), "update", mockEvent)).To(BeNil())
+	})
+})
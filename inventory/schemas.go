@@ -0,0 +1,423 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/pkg/errors"
+)
+
+// SchemaValidation enables checking command event-data against the
+// command-schemas, before the command is handled.
+var SchemaValidation = true
+
+// anyServiceAction is the ServiceAction of schemas used for ServiceActions
+// without their own schema.
+const anyServiceAction = "*"
+
+// schemaDefinitions are shared by all command-schemas. These are included in
+// each schema-document, so exported schemas are self-contained.
+const schemaDefinitions = `{
+  "uuid": {
+    "type": "string",
+    "pattern": "^[0-9a-fA-F]{8}(-?[0-9a-fA-F]{4}){3}-?[0-9a-fA-F]{12}$"
+  },
+  "objectID": {
+    "type": "string",
+    "pattern": "^[0-9a-fA-F]{24}$"
+  },
+  "decimal": {
+    "description": "JSON-number, or decimal-string with up to 6 decimal-places",
+    "type": ["number", "string"],
+    "maxLength": 64,
+    "pattern": "^[+-]?([0-9]+\\.?[0-9]*|\\.[0-9]+)([eE][+-]?[0-9]+)?$"
+  },
+  "unit": {
+    "description": "Unit of weights, blank for item's unit",
+    "type": "string",
+    "enum": ["", "kg", "g", "lb", "oz"]
+  },
+  "timestamp": {
+    "description": "Unix-timestamp in seconds",
+    "type": "integer"
+  },
+  "inventory": {
+    "type": "object",
+    "properties": {
+      "_id": {"$ref": "#/definitions/objectID"},
+      "itemID": {"$ref": "#/definitions/uuid"},
+      "parentItemID": {"$ref": "#/definitions/uuid"},
+      "deviceID": {"$ref": "#/definitions/uuid"},
+      "rsCustomerID": {"$ref": "#/definitions/uuid"},
+      "dateArrived": {"$ref": "#/definitions/timestamp"},
+      "dateSold": {"$ref": "#/definitions/timestamp"},
+      "timestamp": {"$ref": "#/definitions/timestamp"},
+      "flashSaleTimestamp": {"$ref": "#/definitions/timestamp"},
+      "projectedDate": {"$ref": "#/definitions/timestamp"},
+      "schemaVersion": {"type": "integer"},
+      "donateWeight": {"$ref": "#/definitions/decimal"},
+      "flashSaleWeight": {"$ref": "#/definitions/decimal"},
+      "price": {"$ref": "#/definitions/decimal"},
+      "soldWeight": {"$ref": "#/definitions/decimal"},
+      "totalWeight": {"$ref": "#/definitions/decimal"},
+      "wasteWeight": {"$ref": "#/definitions/decimal"},
+      "location": {"type": "string"},
+      "lot": {"type": "string"},
+      "name": {"type": "string"},
+      "origin": {"type": "string"},
+      "gtin": {"type": "string"},
+      "sku": {"type": "string"},
+      "upc": {"type": "string"},
+      "unit": {"$ref": "#/definitions/unit"},
+      "onFlashSale": {"type": "boolean"}
+    }
+  },
+  "newInventory": {
+    "type": "object",
+    "required": ["itemID"],
+    "allOf": [{"$ref": "#/definitions/inventory"}]
+  },
+  "saleLine": {
+    "description": "sale-line needs one of itemID, barcode, or sku",
+    "type": "object",
+    "properties": {
+      "itemID": {"$ref": "#/definitions/uuid"},
+      "barcode": {"type": "string"},
+      "sku": {"type": "string"},
+      "lot": {"type": "string"},
+      "weight": {"$ref": "#/definitions/decimal"},
+      "unit": {"$ref": "#/definitions/unit"}
+    },
+    "anyOf": [
+      {"required": ["itemID"]},
+      {"required": ["barcode"]},
+      {"required": ["sku"]}
+    ],
+    "if": {"required": ["barcode"]},
+    "else": {"required": ["weight"]}
+  },
+  "itemWeight": {
+    "type": "object",
+    "required": ["itemID", "weight"],
+    "properties": {
+      "itemID": {"$ref": "#/definitions/uuid"},
+      "weight": {"$ref": "#/definitions/decimal"},
+      "unit": {"$ref": "#/definitions/unit"}
+    }
+  }
+}`
+
+// schemaKey identifies the command of a schema.
+type schemaKey struct {
+	eventAction   string
+	serviceAction string
+}
+
+// commandSchemaSources are the JSON Schemas for command event-data.
+var commandSchemaSources = map[schemaKey]string{
+	schemaKey{"insert", anyServiceAction}: `{
+  "title": "Insert Inventory",
+  "description": "Single item, or bulk-insert as array of items or {items, ordered}",
+  "type": ["object", "array"],
+  "if": {"type": "array"},
+  "then": {"items": {"$ref": "#/definitions/newInventory"}},
+  "else": {
+    "if": {"required": ["items"]},
+    "then": {
+      "properties": {
+        "items": {"type": "array", "items": {"$ref": "#/definitions/newInventory"}},
+        "ordered": {"type": "boolean"}
+      }
+    },
+    "else": {"$ref": "#/definitions/newInventory"}
+  }
+}`,
+
+	schemaKey{"update", anyServiceAction}: `{
+  "title": "Update Inventory",
+  "description": "Sets the fields in update on items matching filter",
+  "type": "object",
+  "required": ["filter", "update"],
+  "properties": {
+    "filter": {"type": "object"},
+    "update": {"$ref": "#/definitions/inventory"}
+  }
+}`,
+
+	schemaKey{"update", "createSale"}:      saleSchema("Create Sale"),
+	schemaKey{"update", "createFlashSale"}: saleSchema("Create Flash-Sale"),
+
+	schemaKey{"update", "createWaste"}:    disposalSchema("Create Waste"),
+	schemaKey{"update", "createDonation"}: disposalSchema("Create Donation"),
+
+	schemaKey{"update", "cancelSale"}: `{
+  "title": "Cancel Sale",
+  "type": "object",
+  "required": ["saleCorrelationID"],
+  "properties": {
+    "saleCorrelationID": {"$ref": "#/definitions/uuid"},
+    "toWaste": {"type": "boolean"}
+  }
+}`,
+
+	schemaKey{"update", "returnItems"}: `{
+  "title": "Return Sale-Items",
+  "type": "object",
+  "required": ["saleCorrelationID", "items"],
+  "properties": {
+    "saleCorrelationID": {"$ref": "#/definitions/uuid"},
+    "toWaste": {"type": "boolean"},
+    "items": {
+      "type": "array",
+      "minItems": 1,
+      "items": {
+        "type": "object",
+        "required": ["itemID", "weight"],
+        "properties": {
+          "itemID": {"$ref": "#/definitions/uuid"},
+          "weight": {"$ref": "#/definitions/decimal"},
+          "unit": {"$ref": "#/definitions/unit"},
+          "toWaste": {"type": "boolean"}
+        }
+      }
+    }
+  }
+}`,
+
+	schemaKey{"update", "reserveStock"}: `{
+  "title": "Reserve Stock",
+  "description": "reservationID defaults to event's CorrelationID",
+  "type": "object",
+  "required": ["items"],
+  "properties": {
+    "reservationID": {"$ref": "#/definitions/uuid"},
+    "ttlSec": {"type": "integer", "minimum": 0},
+    "items": {
+      "type": "array",
+      "minItems": 1,
+      "items": {"$ref": "#/definitions/itemWeight"}
+    }
+  }
+}`,
+
+	schemaKey{"update", "releaseReservation"}: `{
+  "title": "Release Reservation",
+  "description": "All reserved items are released if items are not specified",
+  "type": "object",
+  "properties": {
+    "reservationID": {"$ref": "#/definitions/uuid"},
+    "items": {"type": "array", "items": {"$ref": "#/definitions/itemWeight"}}
+  }
+}`,
+
+	schemaKey{"update", "transferInventory"}: `{
+  "title": "Transfer Inventory",
+  "description": "All remaining weight is transferred if weight is not specified",
+  "type": "object",
+  "required": ["itemID", "toLocation"],
+  "properties": {
+    "itemID": {"$ref": "#/definitions/uuid"},
+    "toLocation": {"type": "string", "minLength": 1},
+    "weight": {"$ref": "#/definitions/decimal"},
+    "unit": {"$ref": "#/definitions/unit"}
+  }
+}`,
+
+	schemaKey{"delete", anyServiceAction}: `{
+  "title": "Delete Inventory",
+  "description": "Filter for the items to delete",
+  "type": "object"
+}`,
+}
+
+func saleSchema(title string) string {
+	return `{
+  "title": "` + title + `",
+  "type": "object",
+  "required": ["items"],
+  "properties": {
+    "items": {"type": "array", "items": {"$ref": "#/definitions/saleLine"}},
+    "reservationID": {"$ref": "#/definitions/uuid"},
+    "partialFulfillment": {"type": "boolean"}
+  }
+}`
+}
+
+func disposalSchema(title string) string {
+	return `{
+  "title": "` + title + `",
+  "type": "object",
+  "required": ["items"],
+  "properties": {
+    "items": {"type": "array", "items": {"$ref": "#/definitions/saleLine"}}
+  }
+}`
+}
+
+// CommandSchema is the JSON Schema for event-data of a command.
+type CommandSchema struct {
+	EventAction string
+	// ServiceAction is "*" for the schema used by ServiceActions without
+	// their own schema.
+	ServiceAction string
+	// Document is the self-contained JSON Schema document.
+	Document []byte
+	schema   *Schema
+}
+
+// FileName is the name for exported schema-document, such as
+// "update.createSale.schema.json", or "insert.schema.json" for ServiceAction "*".
+func (c *CommandSchema) FileName() string {
+	if c.ServiceAction == anyServiceAction {
+		return c.EventAction + ".schema.json"
+	}
+	return c.EventAction + "." + c.ServiceAction + ".schema.json"
+}
+
+// Validate validates the event-data against schema.
+func (c *CommandSchema) Validate(data []byte) ValidationErrors {
+	return c.schema.ValidateJSON(data)
+}
+
+var commandSchemas = map[schemaKey]*CommandSchema{}
+
+// commandSchemaErrs are the errors compiling command-schemas, if any.
+var commandSchemaErrs []error
+
+func init() {
+	for key, source := range commandSchemaSources {
+		s, err := newCommandSchema(key, source)
+		if err != nil {
+			// Schemas are static, so this only fails if a schema is malformed.
+			// This is reported by CheckCommandSchemas, and the schema's test.
+			commandSchemaErrs = append(commandSchemaErrs, err)
+			continue
+		}
+		commandSchemas[key] = s
+	}
+}
+
+// CheckCommandSchemas returns an error if any command-schema failed to
+// compile. Commands must not be handled in that case, since their events
+// would be checked against the wrong schema, or none.
+func CheckCommandSchemas() error {
+	if len(commandSchemaErrs) == 0 {
+		return nil
+	}
+	msgs := []string{}
+	for _, err := range commandSchemaErrs {
+		msgs = append(msgs, err.Error())
+	}
+	sort.Strings(msgs)
+	return fmt.Errorf("Schema Error: %s", strings.Join(msgs, "; "))
+}
+
+// newCommandSchema builds the schema-document from source and definitions,
+// and compiles it.
+func newCommandSchema(key schemaKey, source string) (*CommandSchema, error) {
+	doc := map[string]interface{}{}
+	err := json.Unmarshal([]byte(source), &doc)
+	if err != nil {
+		err = errors.Wrapf(err, "Error decoding schema %v", key)
+		return nil, err
+	}
+	definitions := map[string]interface{}{}
+	err = json.Unmarshal([]byte(schemaDefinitions), &definitions)
+	if err != nil {
+		err = errors.Wrap(err, "Error decoding schema-definitions")
+		return nil, err
+	}
+	doc["$schema"] = "http://json-schema.org/draft-07/schema#"
+	doc["definitions"] = definitions
+
+	document, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		err = errors.Wrapf(err, "Error marshalling schema %v", key)
+		return nil, err
+	}
+	schema, err := CompileSchema(document)
+	if err != nil {
+		err = errors.Wrapf(err, "Error compiling schema %v", key)
+		return nil, err
+	}
+	return &CommandSchema{
+		EventAction:   key.eventAction,
+		ServiceAction: key.serviceAction,
+		Document:      document,
+		schema:        schema,
+	}, nil
+}
+
+// FindCommandSchema returns the schema for the command. The schema for
+// ServiceAction "*" is returned if ServiceAction has no schema of its own.
+// Nil is returned if EventAction has no schemas.
+func FindCommandSchema(eventAction string, serviceAction string) *CommandSchema {
+	s := commandSchemas[schemaKey{eventAction, serviceAction}]
+	if s == nil {
+		s = commandSchemas[schemaKey{eventAction, anyServiceAction}]
+	}
+	return s
+}
+
+// CommandSchemas returns all command-schemas, sorted by EventAction and
+// ServiceAction.
+func CommandSchemas() []*CommandSchema {
+	schemas := make([]*CommandSchema, 0, len(commandSchemas))
+	for _, s := range commandSchemas {
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		if schemas[i].EventAction != schemas[j].EventAction {
+			return schemas[i].EventAction < schemas[j].EventAction
+		}
+		return schemas[i].ServiceAction < schemas[j].ServiceAction
+	})
+	return schemas
+}
+
+// checkSchema validates the event-data against schema of the command handled
+// as eventAction. Protobuf event-data in ctx is checked in its JSON-form.
+// The Document with field-errors is returned if event-data is invalid,
+// otherwise nil.
+func checkSchema(
+	ctx context.Context, eventAction string, event *model.Event,
+) *model.Document {
+	if !SchemaValidation {
+		return nil
+	}
+	s := FindCommandSchema(eventAction, event.ServiceAction)
+	if s == nil {
+		return nil
+	}
+	var fieldErrs ValidationErrors
+	if request := protoRequestValue(ctx); request != nil {
+		fieldErrs = s.schema.validateValue(request)
+	} else {
+		fieldErrs = s.Validate(event.Data)
+	}
+	if fieldErrs == nil {
+		return nil
+	}
+
+	err := errors.Wrapf(
+		fieldErrs, "Schema: Event-data does not match schema %s", s.FileName(),
+	)
+	log.Println(err)
+	// Error is ignored since marshalling field-errors cannot fail
+	result, _ := json.Marshal(validationResult{fieldErrs})
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		Error:         err.Error(),
+		ErrorCode:     ValidationError,
+		EventAction:   event.EventAction,
+		Result:        result,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
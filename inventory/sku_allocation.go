@@ -0,0 +1,226 @@
+package inventory
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// AllocationPolicy is the order in which items of a SKU are drawn down
+// for SKU sale-lines.
+type AllocationPolicy string
+
+// Supported allocation-policies.
+const (
+	// FEFO draws down first-expiring items first, by ProjectedDate.
+	FEFO AllocationPolicy = "fefo"
+	// FIFO draws down first-arrived items first, by DateArrived.
+	FIFO AllocationPolicy = "fifo"
+)
+
+// DefaultAllocationPolicy is the policy for SKUs not in SKUPolicies.
+var DefaultAllocationPolicy = FIFO
+
+// SKUPolicies are the allocation-policies for specific SKUs.
+var SKUPolicies = map[string]AllocationPolicy{}
+
+// ParseAllocationPolicy returns the AllocationPolicy for the provided string.
+// Blank string returns FIFO.
+func ParseAllocationPolicy(policy string) (AllocationPolicy, error) {
+	switch AllocationPolicy(policy) {
+	case "":
+		return FIFO, nil
+	case FEFO, FIFO:
+		return AllocationPolicy(policy), nil
+	default:
+		return "", fmt.Errorf(
+			"unsupported allocation-policy %s, must be one of fefo, fifo", policy,
+		)
+	}
+}
+
+// PolicyForSKU returns the allocation-policy for the SKU.
+func PolicyForSKU(sku string) AllocationPolicy {
+	if policy, ok := SKUPolicies[sku]; ok {
+		return policy
+	}
+	return DefaultAllocationPolicy
+}
+
+// sortForAllocation orders the items in which they are drawn down. For FEFO,
+// items without ProjectedDate are drawn down last. Ties are drawn down by
+// DateArrived.
+func sortForAllocation(items []*Inventory, policy AllocationPolicy) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if policy == FEFO && a.ProjectedDate != b.ProjectedDate {
+			if a.ProjectedDate == 0 || b.ProjectedDate == 0 {
+				return b.ProjectedDate == 0
+			}
+			return a.ProjectedDate < b.ProjectedDate
+		}
+		return a.DateArrived < b.DateArrived
+	})
+}
+
+// itemAllocation is the weight drawn down from an item for a SKU sale-line.
+type itemAllocation struct {
+	inv    *Inventory
+	weight Decimal
+}
+
+// allocateWeight spreads the weight across items in order, drawing down
+// available weight from each item until the weight is allocated. The
+// allocated weight is less than weight if not enough weight is available.
+func allocateWeight(
+	items []*Inventory, available []Decimal, weight Decimal,
+) ([]itemAllocation, Decimal) {
+	allocations := []itemAllocation{}
+	allocated := Decimal{}
+	for i, inv := range items {
+		// Allocated weight never exceeds weight, so this cannot go out of range
+		remaining, _ := weight.Sub(allocated)
+		if !remaining.GreaterThan(Decimal{}) {
+			break
+		}
+		w := available[i]
+		if !w.GreaterThan(Decimal{}) {
+			continue
+		}
+		if w.GreaterThan(remaining) {
+			w = remaining
+		}
+		allocations = append(allocations, itemAllocation{inv: inv, weight: w})
+		allocated, _ = allocated.Add(w)
+	}
+	return allocations, allocated
+}
+
+// allocateSKULine spreads the weight of SKU sale-line across the SKU's items,
+// as per the SKU's allocation-policy. Returns the sale-lines for allocated
+// items, with their itemID, weight, and unit set. Items are limited to the
+// tenant's items if tenant is specified. An item's available weight is the
+// same as for itemID sale-lines of the service-action (see
+// lineAvailableWeight), so weight held by reservations other than the sale's
+// reservation, and flash-sale weight for flash-sales, is not available.
+// For partial fulfillment, only the available weight is allocated, and the
+// sale-line's weight is set to the allocated weight.
+func allocateSKULine(
+	collection *mongo.Collection,
+	itemMap map[string]interface{},
+	action string,
+	tenant uuuid.UUID,
+	opts saleOptions,
+	now int64,
+) ([]map[string]interface{}, int, error) {
+	sku, assertOK := itemMap["sku"].(string)
+	if !assertOK || sku == "" {
+		return nil, UserError, errors.New("error asserting sku to string")
+	}
+	if itemMap["weight"] == nil {
+		return nil, UserError, errors.New("missing weight")
+	}
+	weight, err := assertDecimal(itemMap["weight"])
+	if err != nil {
+		err = errors.Wrap(err, "error asserting sku-line weight")
+		return nil, UserError, err
+	}
+	if !weight.GreaterThan(Decimal{}) {
+		err = fmt.Errorf("sku-line weight must be positive, got %s", weight)
+		return nil, UserError, err
+	}
+
+	filter := map[string]interface{}{
+		"sku": sku,
+	}
+	if tenant != (uuuid.UUID{}) {
+		filter["rsCustomerID"] = tenant.String()
+	}
+	findResults, err := collection.Find(filter)
+	if err != nil {
+		err = errors.Wrapf(err, "error finding items for sku %s", sku)
+		return nil, DatabaseError, err
+	}
+	items := []*Inventory{}
+	for _, r := range findResults {
+		inv, assertOK := r.(*Inventory)
+		if assertOK {
+			items = append(items, inv)
+		}
+	}
+	if len(items) == 0 {
+		return nil, UserError, fmt.Errorf("no items found for sku %s", sku)
+	}
+
+	// Weight is in unit of SKU's items if no unit is specified
+	var lineUnit Unit
+	if itemMap["unit"] != nil {
+		unitStr, _ := itemMap["unit"].(string)
+		lineUnit, err = ParseUnit(unitStr)
+		if err != nil {
+			return nil, UserError, err
+		}
+	} else {
+		lineUnit = items[0].WeightUnit()
+		for _, inv := range items {
+			if inv.WeightUnit() != lineUnit {
+				err = fmt.Errorf("items of sku %s have different units, unit is required", sku)
+				return nil, UserError, err
+			}
+		}
+	}
+
+	sortForAllocation(items, PolicyForSKU(sku))
+	// Available weight is compared in unit of sku-line, the same way as
+	// itemID sale-lines. Items are not locked here, so each allocated line
+	// is checked again when its item is locked for the sale.
+	isSale := action == "createSale" || action == "createFlashSale"
+	available := []Decimal{}
+	for _, inv := range items {
+		reserved := Decimal{}
+		if isSale {
+			reserved, err = reservedWeight(inv.ItemID, now, opts.reservationID)
+			if err != nil {
+				return nil, DatabaseError, err
+			}
+		}
+		itemAvailable, err := lineAvailableWeight(inv, action, reserved)
+		if err != nil {
+			return nil, UserError, err
+		}
+		w, err := ConvertWeight(itemAvailable, inv.WeightUnit(), lineUnit)
+		if err != nil {
+			return nil, UserError, err
+		}
+		available = append(available, w)
+	}
+
+	allocations, allocated := allocateWeight(items, available, weight)
+	if opts.partial && allocated.GreaterThan(Decimal{}) {
+		if allocated.LessThan(weight) {
+			// Sale-event carries the fulfilled weight
+			itemMap["weight"] = allocated
+			itemMap["requestedWeight"] = weight
+		}
+	} else if allocated.LessThan(weight) {
+		err = fmt.Errorf(
+			"sale-weight %s exceeds the available weight %s for sku %s",
+			weight, allocated, sku,
+		)
+		return nil, UserError, err
+	}
+
+	lines := []map[string]interface{}{}
+	for _, a := range allocations {
+		lines = append(lines, map[string]interface{}{
+			"itemID": a.inv.ItemID.String(),
+			"sku":    sku,
+			"weight": a.weight.String(),
+			"unit":   string(lineUnit),
+		})
+	}
+	return lines, 0, nil
+}
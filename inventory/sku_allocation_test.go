@@ -0,0 +1,67 @@
+package inventory
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SKUAllocation", func() {
+	var items []*Inventory
+
+	BeforeEach(func() {
+		items = []*Inventory{
+			&Inventory{Lot: "a", DateArrived: 300, ProjectedDate: 900},
+			&Inventory{Lot: "b", DateArrived: 100, ProjectedDate: 0},
+			&Inventory{Lot: "c", DateArrived: 200, ProjectedDate: 700},
+		}
+	})
+
+	lots := func(items []*Inventory) []string {
+		l := []string{}
+		for _, inv := range items {
+			l = append(l, inv.Lot)
+		}
+		return l
+	}
+
+	It("should order items first-in-first-out for FIFO", func() {
+		sortForAllocation(items, FIFO)
+		Expect(lots(items)).To(Equal([]string{"b", "c", "a"}))
+	})
+
+	It("should order items first-expiring-first for FEFO", func() {
+		sortForAllocation(items, FEFO)
+		Expect(lots(items)).To(Equal([]string{"c", "a", "b"}))
+	})
+
+	It("should spread weight across items in order", func() {
+		available := []Decimal{
+			decimalInt(2), Decimal{}, decimalFloat(1.5),
+		}
+		allocations, allocated := allocateWeight(items, available, decimalInt(3))
+		Expect(allocated).To(Equal(decimalInt(3)))
+		Expect(allocations).To(HaveLen(2))
+		Expect(allocations[0].inv.Lot).To(Equal("a"))
+		Expect(allocations[0].weight).To(Equal(decimalInt(2)))
+		Expect(allocations[1].inv.Lot).To(Equal("c"))
+		Expect(allocations[1].weight).To(Equal(decimalInt(1)))
+	})
+
+	It("should allocate only the available weight", func() {
+		available := []Decimal{decimalInt(1), decimalInt(1), Decimal{}}
+		_, allocated := allocateWeight(items, available, decimalInt(5))
+		Expect(allocated).To(Equal(decimalInt(2)))
+	})
+
+	It("should use per-SKU policies", func() {
+		SKUPolicies = map[string]AllocationPolicy{"sku-1": FEFO}
+		defer func() {
+			SKUPolicies = map[string]AllocationPolicy{}
+		}()
+		Expect(PolicyForSKU("sku-1")).To(Equal(FEFO))
+		Expect(PolicyForSKU("sku-2")).To(Equal(DefaultAllocationPolicy))
+
+		_, err := ParseAllocationPolicy("lifo")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,174 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// TenantIsolation limits every event to the items of its tenant (RSCustomerID).
+var TenantIsolation = false
+
+// UserTenants maps the UserUUIDs to their tenant (RSCustomerID). Events from
+// these users are always scoped to the user's tenant.
+var UserTenants = map[uuuid.UUID]uuuid.UUID{}
+
+// DefaultTenant is the tenant of users not in UserTenants. Events from such
+// users are rejected if this is zero.
+var DefaultTenant uuuid.UUID
+
+// resolveTenant returns the tenant for the event, which is the user's tenant
+// as per UserTenants, or DefaultTenant for other users. Zero UUID is returned
+// if TenantIsolation is disabled. Events from users without a tenant, and
+// events whose "rsCustomerID" in event-data (either top-level or in "filter")
+// is not the user's tenant, are rejected and audited. Protobuf event-data in
+// ctx is read in its JSON-form.
+func resolveTenant(ctx context.Context, event *model.Event) (uuuid.UUID, error) {
+	if !TenantIsolation {
+		return uuuid.UUID{}, nil
+	}
+
+	request := protoRequestValue(ctx)
+	if request == nil {
+		err := json.Unmarshal(event.Data, &request)
+		if err != nil {
+			// Event-data that isn't an object (such as bulk-inserts) has no tenant
+			request = nil
+		}
+	}
+	dataTenant, err := requestTenant(request)
+	if err != nil {
+		err = errors.Wrap(err, "Error reading tenant from event-data")
+		return uuuid.UUID{}, err
+	}
+	userTenant, hasUserTenant := UserTenants[event.UserUUID]
+	if !hasUserTenant {
+		userTenant = DefaultTenant
+	}
+	if userTenant == (uuuid.UUID{}) {
+		reason := fmt.Sprintf("user %s has no tenant", event.UserUUID)
+		audit(event, auditNoTenant, dataTenant, reason)
+		return uuuid.UUID{}, errors.New(reason)
+	}
+	if dataTenant != (uuuid.UUID{}) && dataTenant != userTenant {
+		reason := fmt.Sprintf(
+			"user's tenant is %s, but event-data specifies tenant %s",
+			userTenant, dataTenant,
+		)
+		audit(event, auditCrossTenant, userTenant, reason)
+		return uuuid.UUID{}, errors.New(reason)
+	}
+	return userTenant, nil
+}
+
+// requestTenant returns the "rsCustomerID" from the decoded event-data, either
+// from top-level or from "filter". Zero UUID is returned if its not present.
+func requestTenant(m map[string]interface{}) (uuuid.UUID, error) {
+	tenant := m["rsCustomerID"]
+	if tenant == nil {
+		filter, _ := m["filter"].(map[string]interface{})
+		tenant = filter["rsCustomerID"]
+	}
+	if tenant == nil {
+		return uuuid.UUID{}, nil
+	}
+	tenantStr, assertOK := tenant.(string)
+	if !assertOK {
+		return uuuid.UUID{}, errors.New("rsCustomerID must be a string")
+	}
+	tenantID, err := uuuid.FromString(tenantStr)
+	if err != nil {
+		err = errors.Wrap(err, "Error parsing rsCustomerID")
+		return uuuid.UUID{}, err
+	}
+	return tenantID, nil
+}
+
+// scopeFilter limits the filter to the tenant's items. Filters that specify
+// any other tenant are rejected and audited.
+func scopeFilter(
+	event *model.Event,
+	tenant uuuid.UUID,
+	filter map[string]interface{},
+) error {
+	if tenant == (uuuid.UUID{}) {
+		return nil
+	}
+	if filter["rsCustomerID"] != nil && filter["rsCustomerID"] != tenant.String() {
+		reason := fmt.Sprintf(
+			"filter-rsCustomerID %v does not match tenant %s",
+			filter["rsCustomerID"], tenant,
+		)
+		audit(event, auditCrossTenant, tenant, reason)
+		return errors.New(reason)
+	}
+	filter["rsCustomerID"] = tenant.String()
+	return nil
+}
+
+// checkItemTenant checks if the item belongs to the tenant. Access to items
+// of other tenants is rejected and audited.
+func checkItemTenant(event *model.Event, tenant uuuid.UUID, inv *Inventory) error {
+	if tenant == (uuuid.UUID{}) || inv.RSCustomerID == tenant {
+		return nil
+	}
+	reason := fmt.Sprintf(
+		"item %s belongs to tenant %s, not %s", inv.ItemID, inv.RSCustomerID, tenant,
+	)
+	audit(event, auditCrossTenant, tenant, reason)
+	// The item's tenant is not disclosed in returned error
+	return fmt.Errorf("item %s does not belong to tenant %s", inv.ItemID, tenant)
+}
+
+// checkDuplicateTenant checks if the existing item, which has the same itemID
+// as the item being inserted, belongs to the tenant. Inserts of other
+// tenants' itemIDs are audited, and rejected without disclosing that the
+// itemID exists.
+func checkDuplicateTenant(
+	event *model.Event,
+	tenant uuuid.UUID,
+	existing *Inventory,
+) error {
+	if tenant == (uuuid.UUID{}) || existing.RSCustomerID == tenant {
+		return nil
+	}
+	reason := fmt.Sprintf(
+		"itemID %s belongs to tenant %s, not %s",
+		existing.ItemID, existing.RSCustomerID, tenant,
+	)
+	audit(event, auditCrossTenant, tenant, reason)
+	return fmt.Errorf("itemID %s cannot be inserted", existing.ItemID)
+}
+
+// assignItemTenant sets the tenant on item being inserted, if the item
+// doesn't specify one. Items for other tenants are rejected and audited.
+func assignItemTenant(event *model.Event, tenant uuuid.UUID, inv *Inventory) error {
+	if tenant == (uuuid.UUID{}) {
+		return nil
+	}
+	if inv.RSCustomerID == (uuuid.UUID{}) {
+		inv.RSCustomerID = tenant
+		return nil
+	}
+	return checkItemTenant(event, tenant, inv)
+}
+
+// tenantErrorDoc returns the Document for events rejected by tenant-isolation.
+func tenantErrorDoc(event *model.Event, err error) *model.Document {
+	err = errors.Wrap(err, "Tenant")
+	log.Println(err)
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		Error:         err.Error(),
+		ErrorCode:     TenantError,
+		EventAction:   event.EventAction,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
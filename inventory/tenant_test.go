@@ -0,0 +1,113 @@
+package inventory
+
+import (
+	"context"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tenant", func() {
+	var (
+		tenant      uuuid.UUID
+		otherTenant uuuid.UUID
+	)
+
+	BeforeEach(func() {
+		var err error
+		tenant, err = uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		otherTenant, err = uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		TenantIsolation = true
+	})
+
+	AfterEach(func() {
+		TenantIsolation = false
+		UserTenants = map[uuuid.UUID]uuuid.UUID{}
+		DefaultTenant = uuuid.UUID{}
+	})
+
+	It("should not scope events if isolation is disabled", func() {
+		TenantIsolation = false
+		resolved, err := resolveTenant(context.Background(), &model.Event{Data: []byte(`{}`)})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resolved).To(Equal(uuuid.UUID{}))
+	})
+
+	It("should resolve default tenant for users without tenant", func() {
+		DefaultTenant = tenant
+		event := &model.Event{
+			Data: []byte(`{"filter": {"rsCustomerID": "` + tenant.String() + `"}}`),
+		}
+		resolved, err := resolveTenant(context.Background(), event)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resolved).To(Equal(tenant))
+
+		event.Data = []byte(`{"filter": {"rsCustomerID": "` + otherTenant.String() + `"}}`)
+		_, err = resolveTenant(context.Background(), event)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject users without tenant claiming a tenant", func() {
+		event := &model.Event{
+			Data: []byte(`{"rsCustomerID": "` + tenant.String() + `"}`),
+		}
+		_, err := resolveTenant(context.Background(), event)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject events specifying tenant other than user's", func() {
+		userUUID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		UserTenants[userUUID] = tenant
+
+		event := &model.Event{
+			Data:     []byte(`{"rsCustomerID": "` + otherTenant.String() + `"}`),
+			UserUUID: userUUID,
+		}
+		_, err = resolveTenant(context.Background(), event)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject events without tenant", func() {
+		event := &model.Event{Data: []byte(`{"lot": "a"}`)}
+		_, err := resolveTenant(context.Background(), event)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should scope filters to tenant", func() {
+		filter := map[string]interface{}{"lot": "a"}
+		err := scopeFilter(&model.Event{}, tenant, filter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filter["rsCustomerID"]).To(Equal(tenant.String()))
+
+		filter = map[string]interface{}{"rsCustomerID": otherTenant.String()}
+		err = scopeFilter(&model.Event{}, tenant, filter)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject items of other tenants", func() {
+		inv := &Inventory{RSCustomerID: otherTenant}
+		Expect(checkItemTenant(&model.Event{}, tenant, inv)).To(HaveOccurred())
+
+		inv = &Inventory{}
+		Expect(assignItemTenant(&model.Event{}, tenant, inv)).To(Succeed())
+		Expect(inv.RSCustomerID).To(Equal(tenant))
+	})
+
+	It("should not disclose itemIDs of other tenants", func() {
+		itemID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		existing := &Inventory{ItemID: itemID, RSCustomerID: otherTenant}
+		err = checkDuplicateTenant(&model.Event{}, tenant, existing)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).ToNot(ContainSubstring("exists"))
+		Expect(err.Error()).ToNot(ContainSubstring(otherTenant.String()))
+
+		existing.RSCustomerID = tenant
+		Expect(checkDuplicateTenant(&model.Event{}, tenant, existing)).To(Succeed())
+	})
+})
@@ -0,0 +1,141 @@
+package inventory
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerProvider creates the spans of command-events. Spans are not recorded
+// by the default no-op provider, so tracing is disabled unless this is set to
+// an SDK-provider using NewIDGenerator.
+var TracerProvider = trace.NewNoopTracerProvider()
+
+// TraceParentHeader is the Kafka-header carrying the W3C trace-context
+// of published events.
+const TraceParentHeader = "traceparent"
+
+// traceIDKey is the context-key for the trace-ID of the command being traced.
+type traceIDKey struct{}
+
+func tracer() trace.Tracer {
+	return TracerProvider.Tracer("github.com/TerrexTech/agg-inventory-cmd/inventory")
+}
+
+// StartTrace starts the root span for processing the command-event, and returns
+// the context carrying it. The root span of each command has the command's
+// CorrelationID as its trace-ID (see NewIDGenerator), so all commands of a
+// request are part of the same trace.
+func StartTrace(ctx context.Context, event *model.Event) (context.Context, trace.Span) {
+	var traceID trace.TraceID
+	copy(traceID[:], event.CorrelationID.Bytes())
+	ctx = context.WithValue(ctx, traceIDKey{}, traceID)
+
+	return tracer().Start(
+		ctx,
+		fmt.Sprintf("%s/%s", event.EventAction, event.ServiceAction),
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("event.uuid", event.UUID.String()),
+			attribute.String("event.correlationID", event.CorrelationID.String()),
+			attribute.Int64("event.aggregateID", int64(event.AggregateID)),
+			attribute.String("event.eventAction", event.EventAction),
+			attribute.String("event.serviceAction", event.ServiceAction),
+			attribute.String("enduser.id", event.UserUUID.String()),
+		),
+	)
+}
+
+// StartSpan starts a child span of the span in ctx, and returns the context
+// carrying the child span.
+func StartSpan(
+	ctx context.Context, name string, attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan ends the span, and marks it as failed if err is not nil.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// eventIDGenerator generates random span-IDs, and root-spans with
+// the trace-ID set by StartTrace.
+type eventIDGenerator struct{}
+
+// NewIDGenerator returns the IDGenerator for SDK-providers of TracerProvider.
+// Trace-IDs are random for root-spans not started by StartTrace, or if
+// the command has no CorrelationID.
+func NewIDGenerator() sdktrace.IDGenerator {
+	return eventIDGenerator{}
+}
+
+func (eventIDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	traceID, _ := ctx.Value(traceIDKey{}).(trace.TraceID)
+	if !traceID.IsValid() {
+		rand.Read(traceID[:])
+	}
+	return traceID, newSpanID()
+}
+
+func (eventIDGenerator) NewSpanID(context.Context, trace.TraceID) trace.SpanID {
+	return newSpanID()
+}
+
+func newSpanID() trace.SpanID {
+	var id trace.SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// kafkaHeaderCarrier adapts Kafka-message headers for propagating
+// trace-context.
+type kafkaHeaderCarrier struct {
+	msg *sarama.ProducerMessage
+}
+
+func (c kafkaHeaderCarrier) Get(key string) string {
+	for _, h := range c.msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c kafkaHeaderCarrier) Set(key string, value string) {
+	c.msg.Headers = append(c.msg.Headers, sarama.RecordHeader{
+		Key:   []byte(key),
+		Value: []byte(value),
+	})
+}
+
+func (c kafkaHeaderCarrier) Keys() []string {
+	keys := []string{}
+	for _, h := range c.msg.Headers {
+		keys = append(keys, string(h.Key))
+	}
+	return keys
+}
+
+// injectTraceParent adds the W3C trace-context of the span in ctx to
+// Kafka-message headers. Nothing is added if the span is not recorded.
+// Kafka-headers require producers with version 0.11 or later.
+func injectTraceParent(ctx context.Context, msg *sarama.ProducerMessage) {
+	propagation.TraceContext{}.Inject(ctx, kafkaHeaderCarrier{msg})
+}
@@ -0,0 +1,130 @@
+package inventory
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ = Describe("Tracing", func() {
+	var (
+		event    *model.Event
+		recorder *tracetest.SpanRecorder
+	)
+
+	BeforeEach(func() {
+		eventUUID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		cid, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		event = &model.Event{
+			AggregateID:   2,
+			CorrelationID: cid,
+			EventAction:   "update",
+			ServiceAction: "createSale",
+			UUID:          eventUUID,
+		}
+		recorder = tracetest.NewSpanRecorder()
+		TracerProvider = sdktrace.NewTracerProvider(
+			sdktrace.WithSpanProcessor(recorder),
+			sdktrace.WithIDGenerator(NewIDGenerator()),
+		)
+	})
+
+	AfterEach(func() {
+		TracerProvider = trace.NewNoopTracerProvider()
+	})
+
+	It("should use CorrelationID as trace-ID", func() {
+		_, span := StartTrace(context.Background(), event)
+		traceID := span.SpanContext().TraceID()
+		Expect(traceID[:]).To(Equal(event.CorrelationID.Bytes()))
+		span.End()
+	})
+
+	It("should record child spans from context", func() {
+		ctx, span := StartTrace(context.Background(), event)
+		_, child := StartSpan(ctx, "authorize")
+		child.End()
+		_, lockSpan := StartSpan(ctx, "etcd.lock", attribute.String("itemID", "test-item"))
+		EndSpan(lockSpan, errors.New("lock unavailable"))
+		EndSpan(span, nil)
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(3))
+		Expect(spans[2].Name()).To(Equal("update/createSale"))
+		for _, s := range spans[:2] {
+			Expect(s.Parent().SpanID()).To(Equal(span.SpanContext().SpanID()))
+			Expect(s.SpanContext().TraceID()).To(Equal(span.SpanContext().TraceID()))
+		}
+		Expect(spans[1].Attributes()).To(ContainElement(
+			attribute.String("itemID", "test-item"),
+		))
+		Expect(spans[1].Status().Code).To(Equal(codes.Error))
+		Expect(spans[1].Status().Description).To(Equal("lock unavailable"))
+	})
+
+	It("should keep spans of concurrent deliveries of an event separate", func() {
+		ctx1, span1 := StartTrace(context.Background(), event)
+		ctx2, span2 := StartTrace(context.Background(), event)
+		Expect(span1.SpanContext().SpanID()).ToNot(Equal(span2.SpanContext().SpanID()))
+
+		_, child1 := StartSpan(ctx1, "handle")
+		_, child2 := StartSpan(ctx2, "handle")
+		child2.End()
+		span2.End()
+		child1.End()
+		span1.End()
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(4))
+		Expect(spans[0].Parent().SpanID()).To(Equal(span2.SpanContext().SpanID()))
+		Expect(spans[2].Parent().SpanID()).To(Equal(span1.SpanContext().SpanID()))
+	})
+
+	It("should add traceparent header to Kafka-messages", func() {
+		ctx, span := StartTrace(context.Background(), event)
+		msg := &sarama.ProducerMessage{}
+		injectTraceParent(ctx, msg)
+		Expect(msg.Headers).To(HaveLen(1))
+		Expect(string(msg.Headers[0].Key)).To(Equal(TraceParentHeader))
+		Expect(string(msg.Headers[0].Value)).To(HavePrefix(
+			"00-" + hex.EncodeToString(event.CorrelationID.Bytes()) + "-",
+		))
+
+		extracted := propagation.TraceContext{}.Extract(
+			context.Background(), kafkaHeaderCarrier{msg},
+		)
+		sc := trace.SpanContextFromContext(extracted)
+		Expect(sc.TraceID()).To(Equal(span.SpanContext().TraceID()))
+		Expect(sc.SpanID()).To(Equal(span.SpanContext().SpanID()))
+		span.End()
+	})
+
+	It("should be no-op when tracing is disabled", func() {
+		TracerProvider = trace.NewNoopTracerProvider()
+		ctx, span := StartTrace(context.Background(), event)
+		Expect(span.IsRecording()).To(BeFalse())
+
+		_, child := StartSpan(ctx, "handle")
+		EndSpan(child, errors.New("error"))
+		span.End()
+
+		msg := &sarama.ProducerMessage{}
+		injectTraceParent(ctx, msg)
+		Expect(msg.Headers).To(BeEmpty())
+		Expect(recorder.Ended()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,384 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// defaultTransferTopic is the topic for transfer-events if
+// KAFKA_PRODUCER_TRANSFER_TOPIC is not set.
+const defaultTransferTopic = "agg.inventory.transfer"
+
+// transferRequest is the event-data for "transferInventory".
+// All remaining weight is transferred if Weight is not specified.
+type transferRequest struct {
+	ItemID     uuuid.UUID `json:"itemID"`
+	ToLocation string     `json:"toLocation"`
+	Weight     *Decimal   `json:"weight,omitempty"`
+	Unit       string     `json:"unit,omitempty"`
+}
+
+// TransferResult is the result of transferring inventory between locations.
+// ChildItemID is set for partial transfers, and is the item created at
+// the new location with the transferred weight.
+type TransferResult struct {
+	ItemID       uuuid.UUID  `json:"itemID"`
+	ChildItemID  *uuuid.UUID `json:"childItemID,omitempty"`
+	FromLocation string      `json:"fromLocation"`
+	ToLocation   string      `json:"toLocation"`
+	Weight       Decimal     `json:"weight"`
+	Unit         Unit        `json:"unit"`
+	Partial      bool        `json:"partial"`
+}
+
+// RemainingWeight returns the item-weight that's not sold, wasted, or donated.
+func (i *Inventory) RemainingWeight() (Decimal, error) {
+	used, err := sumDecimals(i.SoldWeight, i.WasteWeight, i.DonateWeight)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return i.TotalWeight.Sub(used)
+}
+
+// PublishTransferEvents controls if applied transfers are published on the
+// transfer-topic. This is disabled when replaying events, since the events
+// were already published when they were first processed.
+var PublishTransferEvents = true
+
+// transferInventory handles "transferInventory" events. Transferring all
+// remaining weight changes the item's location. Partial transfers create a
+// child item at the new location, and reduce the parent's totalWeight.
+// Partial transfers cannot include weight held by reservations.
+// The child's ItemID is the event's UUID, so replaying events creates the
+// same child items, and a journaled transfer is completed, instead of being
+// applied again, when its event is processed again.
+func transferInventory(
+	ctx context.Context,
+	etcd *clientv3.Client,
+	collection *mongo.Collection,
+	event *model.Event,
+	tenant uuuid.UUID,
+) *model.Document {
+	req := &transferRequest{}
+	err := json.Unmarshal(event.Data, req)
+	if err != nil {
+		err = errors.Wrap(err, "Transfer-Event: Error unmarshalling transfer-data")
+		log.Println(err)
+		return transferErrorDoc(event, err, InternalError)
+	}
+	if req.ItemID == (uuuid.UUID{}) {
+		err = errors.New("Transfer-Event: missing itemID")
+		log.Println(err)
+		return transferErrorDoc(event, err, UserError)
+	}
+	if req.ToLocation == "" {
+		err = errors.New("Transfer-Event: missing toLocation")
+		log.Println(err)
+		return transferErrorDoc(event, err, UserError)
+	}
+	if req.Weight != nil && !req.Weight.GreaterThan(Decimal{}) {
+		err = fmt.Errorf("Transfer-Event: weight must be positive, got %s", req.Weight)
+		log.Println(err)
+		return transferErrorDoc(event, err, UserError)
+	}
+	var reqUnit Unit
+	if req.Unit != "" {
+		reqUnit, err = ParseUnit(req.Unit)
+		if err != nil {
+			err = errors.Wrap(err, "Transfer-Event")
+			log.Println(err)
+			return transferErrorDoc(event, err, UserError)
+		}
+	}
+
+	itemIDStr := req.ItemID.String()
+	unlock, err := lockItem(ctx, etcd, itemIDStr)
+	if err != nil {
+		err = errors.Wrap(err, "Transfer-Event")
+		log.Println(err)
+		if isContextError(err) {
+			return transferErrorDoc(event, err, Timeout)
+		}
+		return transferErrorDoc(event, err, Unavailable)
+	}
+	defer unlock()
+
+	findResult, err := collection.FindOne(map[string]interface{}{
+		"itemID": itemIDStr,
+	})
+	if err != nil {
+		errCode := mongoErrorCode(err)
+		err = errors.Wrap(err, "Transfer-Event: Error getting Item from database")
+		log.Println(err)
+		return transferErrorDoc(event, err, int16(errCode))
+	}
+	inv, assertOK := findResult.(*Inventory)
+	if !assertOK {
+		err = errors.New("error asserting database-result to Inventory-Item")
+		err = errors.Wrap(err, "Transfer-Event")
+		log.Println(err)
+		return transferErrorDoc(event, err, InternalError)
+	}
+	err = checkItemTenant(event, tenant, inv)
+	if err != nil {
+		err = errors.Wrap(err, "Transfer-Event")
+		return tenantErrorDoc(event, err)
+	}
+
+	itemUnit := inv.WeightUnit()
+	entry, err := findTransferEntry(event.UUID)
+	if err != nil {
+		err = errors.Wrap(err, "Transfer-Event")
+		log.Println(err)
+		return transferErrorDoc(event, err, DatabaseError)
+	}
+	if entry != nil {
+		return resumeTransfer(ctx, collection, event, inv, entry)
+	}
+
+	if inv.Location == req.ToLocation {
+		err = fmt.Errorf("Transfer-Event: item is already at location %s", req.ToLocation)
+		log.Println(err)
+		return transferErrorDoc(event, err, UserError)
+	}
+
+	remaining, err := inv.RemainingWeight()
+	if err != nil {
+		err = errors.Wrap(err, "Transfer-Event: Error calculating remaining weight")
+		log.Println(err)
+		return transferErrorDoc(event, err, UserError)
+	}
+	reserved, err := reservedWeight(inv.ItemID, eventTime(event), uuuid.UUID{})
+	if err != nil {
+		err = errors.Wrap(err, "Transfer-Event: Error getting reserved weight")
+		log.Println(err)
+		return transferErrorDoc(event, err, DatabaseError)
+	}
+	weight := remaining
+	if req.Weight != nil {
+		weight = *req.Weight
+		if reqUnit != "" {
+			weight, err = ConvertWeight(weight, reqUnit, itemUnit)
+			if err != nil {
+				err = errors.Wrap(err, "Transfer-Event: Error converting weight")
+				log.Println(err)
+				return transferErrorDoc(event, err, UserError)
+			}
+		}
+	}
+	if !weight.GreaterThan(Decimal{}) {
+		err = errors.New("Transfer-Event: item has no remaining weight to transfer")
+		log.Println(err)
+		return transferErrorDoc(event, err, UserError)
+	}
+	if weight.GreaterThan(remaining) {
+		err = fmt.Errorf(
+			"Transfer-Event: transfer-weight %s exceeds the remaining weight %s",
+			weight, remaining,
+		)
+		log.Println(err)
+		return transferErrorDoc(event, err, UserError)
+	}
+	// Reservations stay with the parent item, so partial transfers can only
+	// take the weight not reserved
+	if weight.LessThan(remaining) && !reserved.IsZero() {
+		available, err := remaining.Sub(reserved)
+		if err != nil {
+			err = errors.Wrap(err, "Transfer-Event: Error calculating available weight")
+			log.Println(err)
+			return transferErrorDoc(event, err, UserError)
+		}
+		if weight.GreaterThan(available) {
+			err = fmt.Errorf(
+				"Transfer-Event: transfer-weight %s exceeds the weight not reserved %s",
+				weight, available,
+			)
+			log.Println(err)
+			return transferErrorDoc(event, err, UserError)
+		}
+	}
+
+	err = checkContext(ctx)
+	if err != nil {
+		err = errors.Wrap(err, "Transfer-Event")
+		log.Println(err)
+		return transferErrorDoc(event, err, Timeout)
+	}
+
+	transferResult := TransferResult{
+		ItemID:       inv.ItemID,
+		FromLocation: inv.Location,
+		ToLocation:   req.ToLocation,
+		Weight:       weight,
+		Unit:         itemUnit,
+		Partial:      weight.LessThan(remaining),
+	}
+	if transferResult.Partial {
+		childItemID, err := transferPartial(
+			collection, event, inv, req.ToLocation, weight,
+		)
+		if err != nil {
+			err = errors.Wrap(err, "Transfer-Event")
+			log.Println(err)
+			return transferErrorDoc(event, err, DatabaseError)
+		}
+		transferResult.ChildItemID = &childItemID
+	} else {
+		_, err = collection.UpdateMany(
+			map[string]interface{}{
+				"itemID": itemIDStr,
+			},
+			map[string]interface{}{
+				"location": req.ToLocation,
+			},
+		)
+		if err != nil {
+			err = errors.Wrap(err, "Transfer-Event: Error updating item-location")
+			log.Println(err)
+			return transferErrorDoc(event, err, DatabaseError)
+		}
+	}
+
+	return transferDoc(ctx, event, transferResult)
+}
+
+// resumeTransfer completes the journaled partial transfer if it's not yet
+// applied, and returns its result. The caller must hold the parent's
+// item-lock.
+func resumeTransfer(
+	ctx context.Context,
+	collection *mongo.Collection,
+	event *model.Event,
+	parent *Inventory,
+	entry *TransferEntry,
+) *model.Document {
+	if !entry.Applied {
+		err := applyTransfer(collection, entry, parent)
+		if err != nil {
+			err = errors.Wrap(err, "Transfer-Event: Error completing journaled transfer")
+			log.Println(err)
+			return transferErrorDoc(event, err, DatabaseError)
+		}
+	}
+	childItemID := entry.TransferID
+	return transferDoc(ctx, event, TransferResult{
+		ItemID:       parent.ItemID,
+		ChildItemID:  &childItemID,
+		FromLocation: parent.Location,
+		ToLocation:   entry.ToLocation,
+		Weight:       entry.Weight,
+		Unit:         parent.WeightUnit(),
+		Partial:      true,
+	})
+}
+
+// transferDoc publishes the applied transfer, and returns its Document.
+func transferDoc(
+	ctx context.Context,
+	event *model.Event,
+	transferResult TransferResult,
+) *model.Document {
+	result, err := json.Marshal(transferResult)
+	if err != nil {
+		err = errors.Wrap(err, "Transfer-Event: Error marshalling result")
+		log.Println(err)
+		return transferErrorDoc(event, err, InternalError)
+	}
+
+	if PublishTransferEvents {
+		err = publishTransferEvent(ctx, event, result)
+		if err != nil {
+			// Transfer is already applied, so only the error is logged
+			err = errors.Wrap(err, "Transfer-Event: Error publishing transfer-event")
+			log.Println(err)
+		}
+	}
+
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		EventAction:   event.EventAction,
+		Result:        result,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
+
+// transferPartial journals the partial transfer, and applies it. The child
+// item has the event's UUID as ItemID. The caller must hold the parent's
+// item-lock.
+func transferPartial(
+	collection *mongo.Collection,
+	event *model.Event,
+	parent *Inventory,
+	toLocation string,
+	weight Decimal,
+) (uuuid.UUID, error) {
+	entry := &TransferEntry{
+		TransferID:   event.UUID,
+		ItemID:       parent.ItemID,
+		ToLocation:   toLocation,
+		ParentWeight: parent.TotalWeight,
+		Weight:       weight,
+		Timestamp:    time.Now().Unix(),
+	}
+	if TransferJournal != nil {
+		_, err := TransferJournal.InsertOne(entry)
+		if err != nil {
+			err = errors.Wrap(err, "Error journaling transfer")
+			return uuuid.UUID{}, err
+		}
+	}
+	err := applyTransfer(collection, entry, parent)
+	if err != nil {
+		return uuuid.UUID{}, err
+	}
+	return entry.TransferID, nil
+}
+
+// publishTransferEvent publishes the transfer-result on the transfer-topic.
+func publishTransferEvent(ctx context.Context, event *model.Event, result []byte) error {
+	topic := os.Getenv("KAFKA_PRODUCER_TRANSFER_TOPIC")
+	if topic == "" {
+		topic = defaultTransferTopic
+	}
+	uuid, err := uuuid.NewV4()
+	if err != nil {
+		err = errors.Wrap(err, "Error generating UUID for transfer-event")
+		return err
+	}
+	return publishEvent(ctx, topic, &model.Event{
+		AggregateID:   AggregateID,
+		CorrelationID: event.CorrelationID,
+		Data:          result,
+		EventAction:   "update",
+		NanoTime:      time.Now().UnixNano(),
+		ServiceAction: "inventoryTransferred",
+		UUID:          uuid,
+		UserUUID:      event.UserUUID,
+		YearBucket:    event.YearBucket,
+	})
+}
+
+// transferErrorDoc returns the Document for failed transfers.
+func transferErrorDoc(event *model.Event, err error, errCode int16) *model.Document {
+	return &model.Document{
+		AggregateID:   event.AggregateID,
+		CorrelationID: event.CorrelationID,
+		Error:         err.Error(),
+		ErrorCode:     errCode,
+		EventAction:   event.EventAction,
+		ServiceAction: event.ServiceAction,
+		UUID:          event.UUID,
+	}
+}
@@ -0,0 +1,95 @@
+package inventory
+
+import (
+	"encoding/json"
+
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TransferInventory", func() {
+	It("should calculate remaining weight", func() {
+		inv := &Inventory{
+			TotalWeight:  decimalInt(100),
+			SoldWeight:   decimalFloat(20.5),
+			WasteWeight:  decimalInt(10),
+			DonateWeight: decimalInt(4),
+		}
+		Expect(inv.RemainingWeight()).To(Equal(decimalFloat(65.5)))
+	})
+
+	It("should marshal location and parent-item", func() {
+		parentItemID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		inv := &Inventory{
+			Location:     "warehouse-2",
+			ParentItemID: parentItemID,
+		}
+		marshalInv, err := json.Marshal(inv)
+		Expect(err).ToNot(HaveOccurred())
+
+		unmarshalInv := &Inventory{}
+		err = json.Unmarshal(marshalInv, unmarshalInv)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unmarshalInv.Location).To(Equal("warehouse-2"))
+		Expect(unmarshalInv.ParentItemID).To(Equal(parentItemID))
+	})
+	It("should marshal transfer-entries to BSON", func() {
+		transferID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		itemID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		entry := &TransferEntry{
+			TransferID:   transferID,
+			ItemID:       itemID,
+			ToLocation:   "warehouse-2",
+			ParentWeight: decimalInt(100),
+			Weight:       decimalFloat(12.5),
+			Timestamp:    10,
+		}
+		marshalEntry, err := bson.Marshal(entry)
+		Expect(err).ToNot(HaveOccurred())
+
+		unmarshalEntry := &TransferEntry{}
+		err = bson.Unmarshal(marshalEntry, unmarshalEntry)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unmarshalEntry).To(Equal(entry))
+	})
+
+	It("should create child-items without sale and flash-sale weights", func() {
+		transferID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		parent := &Inventory{
+			Lot:             "test-lot",
+			Location:        "warehouse-1",
+			TotalWeight:     decimalInt(100),
+			SoldWeight:      decimalInt(20),
+			FlashSaleWeight: decimalInt(5),
+			OnFlashSale:     true,
+		}
+		child := transferChild(&TransferEntry{
+			TransferID: transferID,
+			ToLocation: "warehouse-2",
+			Weight:     decimalInt(30),
+		}, parent)
+		Expect(child.ItemID).To(Equal(transferID))
+		Expect(child.Lot).To(Equal("test-lot"))
+		Expect(child.Location).To(Equal("warehouse-2"))
+		Expect(child.TotalWeight).To(Equal(decimalInt(30)))
+		Expect(child.SoldWeight.IsZero()).To(BeTrue())
+		Expect(child.FlashSaleWeight.IsZero()).To(BeTrue())
+		Expect(child.OnFlashSale).To(BeFalse())
+	})
+
+	It("should not apply transfers to parents changed since journaling", func() {
+		entry := &TransferEntry{
+			ParentWeight: decimalInt(100),
+			Weight:       decimalInt(30),
+		}
+		parent := &Inventory{TotalWeight: decimalInt(90)}
+		err := applyTransfer(nil, entry, parent)
+		Expect(err).To(HaveOccurred())
+	})
+})
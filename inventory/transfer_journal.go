@@ -0,0 +1,284 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	util "github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	"github.com/mongodb/mongo-go-driver/bson"
+	"github.com/mongodb/mongo-go-driver/bson/objectid"
+	"github.com/pkg/errors"
+)
+
+// TransferJournal is the collection where partial transfers are recorded
+// before being applied, so a transfer interrupted midway is completed when
+// its event is processed again. Its SchemaStruct must be TransferEntry.
+// Partial transfers are not journaled if this is nil.
+var TransferJournal *mongo.Collection
+
+// TransferEntry is a partial transfer of weight from an item to a child item,
+// identified by the transfer-event's UUID, which is also the child's ItemID.
+type TransferEntry struct {
+	ID           objectid.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	TransferID   uuuid.UUID        `bson:"transferID" json:"transferID"`
+	ItemID       uuuid.UUID        `bson:"itemID" json:"itemID"`
+	ToLocation   string            `bson:"toLocation" json:"toLocation"`
+	ParentWeight Decimal           `bson:"parentWeight" json:"parentWeight"`
+	Weight       Decimal           `bson:"weight" json:"weight"`
+	Applied      bool              `bson:"applied" json:"applied"`
+	Timestamp    int64             `bson:"timestamp" json:"timestamp"`
+}
+
+// MarshalBSON returns bytes of BSON-type.
+func (t TransferEntry) MarshalBSON() ([]byte, error) {
+	in := map[string]interface{}{
+		"transferID":   t.TransferID.String(),
+		"itemID":       t.ItemID.String(),
+		"toLocation":   t.ToLocation,
+		"parentWeight": t.ParentWeight.Decimal128(),
+		"weight":       t.Weight.Decimal128(),
+		"applied":      t.Applied,
+		"timestamp":    t.Timestamp,
+	}
+	if t.ID != objectid.NilObjectID {
+		in["_id"] = t.ID
+	}
+	return bson.Marshal(in)
+}
+
+// UnmarshalBSON returns BSON-type from bytes.
+func (t *TransferEntry) UnmarshalBSON(in []byte) error {
+	m := make(map[string]interface{})
+	err := bson.Unmarshal(in, m)
+	if err != nil {
+		err = errors.Wrap(err, "Unmarshal Error")
+		return err
+	}
+
+	var assertOK bool
+	if m["_id"] != nil {
+		t.ID, assertOK = m["_id"].(objectid.ObjectID)
+		if !assertOK {
+			return errors.New("Error while asserting ObjectID")
+		}
+	}
+	transferID, _ := m["transferID"].(string)
+	t.TransferID, err = uuuid.FromString(transferID)
+	if err != nil {
+		err = errors.Wrap(err, "Error while asserting TransferID")
+		return err
+	}
+	itemID, _ := m["itemID"].(string)
+	t.ItemID, err = uuuid.FromString(itemID)
+	if err != nil {
+		err = errors.Wrap(err, "Error while asserting ItemID")
+		return err
+	}
+	t.ToLocation, _ = m["toLocation"].(string)
+	t.Applied, _ = m["applied"].(bool)
+	t.ParentWeight, err = assertDecimal(m["parentWeight"])
+	if err != nil {
+		err = errors.Wrap(err, "Error while asserting ParentWeight")
+		return err
+	}
+	t.Weight, err = assertDecimal(m["weight"])
+	if err != nil {
+		err = errors.Wrap(err, "Error while asserting Weight")
+		return err
+	}
+	if m["timestamp"] != nil {
+		t.Timestamp, err = util.AssertInt64(m["timestamp"])
+		if err != nil {
+			err = errors.Wrap(err, "Error while asserting Timestamp")
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalJSON returns bytes of JSON-type.
+func (t *TransferEntry) MarshalJSON() ([]byte, error) {
+	in := map[string]interface{}{
+		"transferID":   t.TransferID.String(),
+		"itemID":       t.ItemID.String(),
+		"toLocation":   t.ToLocation,
+		"parentWeight": t.ParentWeight,
+		"weight":       t.Weight,
+		"applied":      t.Applied,
+		"timestamp":    t.Timestamp,
+	}
+	if t.ID != objectid.NilObjectID {
+		in["_id"] = t.ID.Hex()
+	}
+	return json.Marshal(in)
+}
+
+// findTransferEntry returns the journaled transfer, or nil if the transfer
+// is not journaled.
+func findTransferEntry(transferID uuuid.UUID) (*TransferEntry, error) {
+	if TransferJournal == nil {
+		return nil, nil
+	}
+	findResults, err := TransferJournal.Find(map[string]interface{}{
+		"transferID": transferID.String(),
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error finding transfer-entry")
+		return nil, err
+	}
+	for _, r := range findResults {
+		entry, assertOK := r.(*TransferEntry)
+		if assertOK {
+			return entry, nil
+		}
+	}
+	return nil, nil
+}
+
+// setTransferApplied marks the journaled transfer as applied.
+func setTransferApplied(entry *TransferEntry) error {
+	entry.Applied = true
+	if TransferJournal == nil {
+		return nil
+	}
+	_, err := TransferJournal.UpdateMany(
+		map[string]interface{}{
+			"transferID": entry.TransferID.String(),
+		},
+		map[string]interface{}{
+			"applied": true,
+		},
+	)
+	if err != nil {
+		err = errors.Wrap(err, "Error marking transfer-entry as applied")
+		return err
+	}
+	return nil
+}
+
+// applyTransfer applies the partial transfer. The parent's totalWeight is
+// reduced first, guarded on the weight it had before the transfer, so the
+// transfer is never applied twice, and concurrent changes fail the transfer.
+// The child item is then inserted unless it already exists. If the child
+// cannot be inserted, the parent's weight is restored.
+// The caller must hold the parent's item-lock.
+func applyTransfer(
+	collection *mongo.Collection,
+	entry *TransferEntry,
+	parent *Inventory,
+) error {
+	parentWeight, err := entry.ParentWeight.Sub(entry.Weight)
+	if err != nil {
+		err = errors.Wrap(err, "Error calculating parent-item weight")
+		return err
+	}
+	decremented := false
+	if parent.TotalWeight.Cmp(entry.ParentWeight) == 0 {
+		updateResult, err := collection.UpdateMany(
+			map[string]interface{}{
+				"itemID":      parent.ItemID.String(),
+				"totalWeight": entry.ParentWeight.Decimal128(),
+			},
+			map[string]interface{}{
+				"totalWeight": parentWeight.Decimal128(),
+			},
+		)
+		if err != nil {
+			err = errors.Wrap(err, "Error updating parent-item weight")
+			return err
+		}
+		if updateResult.MatchedCount < 1 {
+			return errors.New("parent-item weight changed during transfer")
+		}
+		decremented = true
+	} else if parent.TotalWeight.Cmp(parentWeight) != 0 {
+		// Otherwise the parent was already reduced before the transfer
+		// was interrupted
+		return fmt.Errorf(
+			"parent-item totalWeight %s matches neither %s before nor %s after transfer",
+			parent.TotalWeight, entry.ParentWeight, parentWeight,
+		)
+	}
+
+	existing, err := findExistingItem(collection, entry.TransferID)
+	if err == nil && existing == nil {
+		child := transferChild(entry, parent)
+		_, err = collection.InsertOne(child)
+		err = errors.Wrap(err, "Error inserting child-item")
+	}
+	if err != nil {
+		if decremented {
+			restoreErr := restoreParentWeight(collection, entry, parentWeight)
+			if restoreErr != nil {
+				// The journal-entry remains pending, so the transfer is
+				// completed when the event is processed again
+				restoreErr = errors.Wrapf(
+					restoreErr,
+					"Error restoring weight of parent-item %s after failed transfer",
+					parent.ItemID,
+				)
+				log.Println(restoreErr)
+			}
+		}
+		return err
+	}
+	return setTransferApplied(entry)
+}
+
+// restoreParentWeight undoes the parent's weight-reduction of the transfer,
+// and removes the transfer's journal-entry.
+func restoreParentWeight(
+	collection *mongo.Collection,
+	entry *TransferEntry,
+	parentWeight Decimal,
+) error {
+	updateResult, err := collection.UpdateMany(
+		map[string]interface{}{
+			"itemID":      entry.ItemID.String(),
+			"totalWeight": parentWeight.Decimal128(),
+		},
+		map[string]interface{}{
+			"totalWeight": entry.ParentWeight.Decimal128(),
+		},
+	)
+	if err == nil && updateResult.MatchedCount < 1 {
+		err = errors.New("parent-item weight changed during transfer")
+	}
+	if err != nil {
+		return err
+	}
+	if TransferJournal == nil {
+		return nil
+	}
+	_, err = TransferJournal.DeleteMany(map[string]interface{}{
+		"transferID": entry.TransferID.String(),
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error deleting transfer-entry")
+		return err
+	}
+	return nil
+}
+
+// transferChild returns the child item that receives the transferred weight.
+func transferChild(entry *TransferEntry, parent *Inventory) *Inventory {
+	child := *parent
+	child.ID = objectid.NilObjectID
+	child.ItemID = entry.TransferID
+	child.ParentItemID = parent.ItemID
+	child.Location = entry.ToLocation
+	child.TotalWeight = entry.Weight
+	child.DateSold = 0
+	child.DonateWeight = Decimal{}
+	child.FlashSaleWeight = Decimal{}
+	child.OnFlashSale = false
+	child.FlashSaleTimestamp = 0
+	child.SoldWeight = Decimal{}
+	child.WasteWeight = Decimal{}
+	child.Timestamp = time.Now().Unix()
+	return &child
+}
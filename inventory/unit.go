@@ -0,0 +1,72 @@
+package inventory
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Unit is the unit-of-measure for weights.
+type Unit string
+
+// Supported weight-units.
+const (
+	Kilogram Unit = "kg"
+	Gram     Unit = "g"
+	Pound    Unit = "lb"
+	Ounce    Unit = "oz"
+)
+
+// DefaultUnit is the unit for items that don't specify one.
+const DefaultUnit = Kilogram
+
+// gramsPerUnit are the exact conversion-factors from each unit to grams.
+// The pound is defined as exactly 0.45359237 kg, and ounce as 1/16 pound.
+var gramsPerUnit = map[Unit]*big.Rat{
+	Gram:     big.NewRat(1, 1),
+	Kilogram: big.NewRat(1000, 1),
+	Pound:    big.NewRat(45359237, 100000),
+	Ounce:    big.NewRat(45359237, 1600000),
+}
+
+// ParseUnit returns the Unit for the provided string. Blank string
+// returns DefaultUnit.
+func ParseUnit(unit string) (Unit, error) {
+	if unit == "" {
+		return DefaultUnit, nil
+	}
+	u := Unit(unit)
+	if gramsPerUnit[u] == nil {
+		return "", fmt.Errorf("unsupported unit %s, must be one of kg, g, lb, oz", unit)
+	}
+	return u, nil
+}
+
+// ConvertWeight converts the weight between units. The conversion is done
+// using exact rational arithmetic, and the result is rounded to the
+// precision of Decimal.
+func ConvertWeight(weight Decimal, from Unit, to Unit) (Decimal, error) {
+	if from == to {
+		return weight, nil
+	}
+	fromFactor := gramsPerUnit[from]
+	if fromFactor == nil {
+		return Decimal{}, fmt.Errorf("unsupported unit %s", from)
+	}
+	toFactor := gramsPerUnit[to]
+	if toFactor == nil {
+		return Decimal{}, fmt.Errorf("unsupported unit %s", to)
+	}
+
+	w := weight.Rat()
+	w.Mul(w, fromFactor)
+	w.Quo(w, toFactor)
+	return decimalFromRat(w)
+}
+
+// WeightUnit returns the unit for Inventory weights.
+func (i *Inventory) WeightUnit() Unit {
+	if i.Unit == "" {
+		return DefaultUnit
+	}
+	return i.Unit
+}
@@ -0,0 +1,36 @@
+package inventory
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unit", func() {
+	It("should convert weights between units", func() {
+		w, err := ConvertWeight(decimalInt(1), Pound, Gram)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w.String()).To(Equal("453.59237"))
+
+		w, err = ConvertWeight(decimalInt(16), Ounce, Pound)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w).To(Equal(decimalInt(1)))
+
+		w, err = ConvertWeight(decimalFloat(2.5), Kilogram, Gram)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(w).To(Equal(decimalInt(2500)))
+	})
+
+	It("should default blank unit to kg", func() {
+		u, err := ParseUnit("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(u).To(Equal(Kilogram))
+		Expect((&Inventory{}).WeightUnit()).To(Equal(Kilogram))
+	})
+
+	It("should return error for unsupported units", func() {
+		_, err := ParseUnit("stone")
+		Expect(err).To(HaveOccurred())
+		_, err = ConvertWeight(decimalInt(1), Unit("stone"), Gram)
+		Expect(err).To(HaveOccurred())
+	})
+})
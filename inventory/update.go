@@ -1,11 +1,13 @@
 package inventory
 
 import (
+	"context"
 	"log"
 
 	"github.com/TerrexTech/go-eventstore-models/model"
 	"github.com/TerrexTech/go-mongoutils/mongo"
-	"github.com/coreos/etcd/clientv3"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 type inventoryUpdate struct {
@@ -18,17 +20,41 @@ type updateResult struct {
 	ModifiedCount int64 `json:"modifiedCount,omitempty"`
 }
 
-// Update handles "update" events.
+// Update handles "update" events. Cancelling ctx stops waiting for item-locks
+// and producers, and stops the command before its next write.
+// Event-data is first checked against the command-schema for ServiceAction.
 func Update(
+	ctx context.Context,
 	etcd *clientv3.Client,
 	collection *mongo.Collection,
 	event *model.Event,
 ) *model.Document {
 	log.Println(event.ServiceAction)
+	errDoc := checkSchema(ctx, "update", event)
+	if errDoc != nil {
+		return errDoc
+	}
+
+	tenant, err := resolveTenant(ctx, event)
+	if err != nil {
+		err = errors.Wrap(err, "Update")
+		return tenantErrorDoc(event, err)
+	}
+
 	switch event.ServiceAction {
 	case "createSale", "createFlashSale":
-		return createSale(etcd, collection, event)
+		return createSale(ctx, etcd, collection, event, tenant)
+	case "createWaste", "createDonation":
+		return createDisposal(ctx, etcd, collection, event, tenant)
+	case "cancelSale", "returnItems":
+		return reverseSale(ctx, etcd, collection, event, tenant)
+	case "reserveStock":
+		return reserveStock(ctx, etcd, collection, event, tenant)
+	case "releaseReservation":
+		return releaseReservation(ctx, collection, event, tenant)
+	case "transferInventory":
+		return transferInventory(ctx, etcd, collection, event, tenant)
 	default:
-		return updateInventory(collection, event)
+		return updateInventory(ctx, collection, event, tenant)
 	}
 }
@@ -1,19 +1,31 @@
 package inventory
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 
+	"github.com/TerrexTech/agg-inventory-cmd/pb"
 	"github.com/TerrexTech/go-eventstore-models/model"
 	"github.com/TerrexTech/go-mongoutils/mongo"
 	"github.com/TerrexTech/uuuid"
 	"github.com/pkg/errors"
 )
 
-func updateInventory(coll *mongo.Collection, event *model.Event) *model.Document {
+func updateInventory(
+	ctx context.Context,
+	coll *mongo.Collection,
+	event *model.Event,
+	tenant uuuid.UUID,
+) *model.Document {
 	invUpdate := &inventoryUpdate{}
-
-	err := json.Unmarshal(event.Data, invUpdate)
+	var err error
+	if cmd, isProto := protoRequest(ctx).(*pb.UpdateCommand); isProto {
+		invUpdate = updateFromProto(cmd)
+	} else {
+		err = json.Unmarshal(event.Data, invUpdate)
+	}
 	if err != nil {
 		err = errors.Wrap(err, "Update: Error while unmarshalling Event-data")
 		log.Println(err)
@@ -71,6 +83,45 @@ func updateInventory(coll *mongo.Collection, event *model.Event) *model.Document
 		}
 	}
 
+	err = scopeFilter(event, tenant, invUpdate.Filter)
+	if err != nil {
+		err = errors.Wrap(err, "Update")
+		return tenantErrorDoc(event, err)
+	}
+	if tenant != (uuuid.UUID{}) && invUpdate.Update["rsCustomerID"] != nil &&
+		invUpdate.Update["rsCustomerID"] != tenant.String() {
+		reason := fmt.Sprintf(
+			"update cannot move items to tenant %v", invUpdate.Update["rsCustomerID"],
+		)
+		audit(event, auditCrossTenant, tenant, reason)
+		err = errors.Wrap(errors.New(reason), "Update")
+		return tenantErrorDoc(event, err)
+	}
+
+	validationErrs := normalizeUpdate(invUpdate.Update)
+	if validationErrs != nil {
+		err = errors.Wrap(validationErrs, "Update")
+		log.Println(err)
+		// Error is ignored since marshalling field-errors cannot fail
+		result, _ := json.Marshal(validationResult{validationErrs})
+		return &model.Document{
+			AggregateID:   event.AggregateID,
+			CorrelationID: event.CorrelationID,
+			Error:         err.Error(),
+			ErrorCode:     ValidationError,
+			EventAction:   event.EventAction,
+			Result:        result,
+			ServiceAction: event.ServiceAction,
+			UUID:          event.UUID,
+		}
+	}
+
+	err = checkContext(ctx)
+	if err != nil {
+		err = errors.Wrap(err, "Update")
+		log.Println(err)
+		return timeoutDoc(event, err)
+	}
 	updateStats, err := coll.UpdateMany(invUpdate.Filter, invUpdate.Update)
 	if err != nil {
 		err = errors.Wrap(err, "Update: Error in UpdateMany")
@@ -0,0 +1,274 @@
+package inventory
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TerrexTech/uuuid"
+)
+
+// FieldError is the validation-error for an Inventory field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors are all the field-errors found when validating Inventory.
+type ValidationErrors []FieldError
+
+// Error returns all field-errors as a single string.
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, fe := range v {
+		msgs[i] = fmt.Sprintf("%s: %s", fe.Field, fe.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// validationResult is the Document-result when validation fails.
+type validationResult struct {
+	FieldErrors ValidationErrors `json:"fieldErrors"`
+}
+
+// ValidationConfig defines the rules used for validating Inventory.
+type ValidationConfig struct {
+	// AllowZeroPrice allows items with zero price (such as donated items).
+	// Negative prices are never allowed.
+	AllowZeroPrice bool
+	// MaxArrivalSkew is how far in the future DateArrived can be, to allow
+	// for clock-differences between devices.
+	MaxArrivalSkew time.Duration
+	// MaxTotalWeight is the maximum allowed TotalWeight. Zero means no limit.
+	MaxTotalWeight Decimal
+	// RequiredFields are the JSON field-names that must be set,
+	// in addition to itemID.
+	RequiredFields []string
+}
+
+// DefaultValidationConfig returns the ValidationConfig used when no
+// deployment-specific rules are provided.
+func DefaultValidationConfig() *ValidationConfig {
+	return &ValidationConfig{
+		MaxArrivalSkew: 5 * time.Minute,
+	}
+}
+
+// ValidationRules are the rules used when validating Inventory.
+var ValidationRules = DefaultValidationConfig()
+
+// InsertValidation enables validating inserted Inventory against
+// ValidationRules. It's disabled when rebuilding projection, since replayed
+// inserts were valid when first processed, even if the rules changed since.
+var InsertValidation = true
+
+// validateInsert validates the Inventory being inserted, if InsertValidation
+// is enabled.
+func validateInsert(inv *Inventory) ValidationErrors {
+	if !InsertValidation {
+		return nil
+	}
+	return inv.Validate(ValidationRules)
+}
+
+// Validate checks the Inventory against the provided rules, and returns all
+// field-errors found. Returns nil if Inventory is valid.
+func (i *Inventory) Validate(rules *ValidationConfig) ValidationErrors {
+	if rules == nil {
+		rules = DefaultValidationConfig()
+	}
+	errs := ValidationErrors{}
+	addErr := func(field string, format string, args ...interface{}) {
+		errs = append(errs, FieldError{
+			Field:   field,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if i.ItemID == (uuuid.UUID{}) {
+		addErr("itemID", "is required")
+	}
+	for _, field := range rules.RequiredFields {
+		// Fields are checked with CheckRequiredFields when loading rules
+		if blank, _ := i.isFieldBlank(field); blank {
+			addErr(field, "is required")
+		}
+	}
+
+	weights := []struct {
+		Field  string
+		Weight Decimal
+	}{
+		{"totalWeight", i.TotalWeight},
+		{"soldWeight", i.SoldWeight},
+		{"wasteWeight", i.WasteWeight},
+		{"donateWeight", i.DonateWeight},
+		{"flashSaleWeight", i.FlashSaleWeight},
+	}
+	for _, w := range weights {
+		if w.Weight.IsNegative() {
+			addErr(w.Field, "cannot be negative, got %s", w.Weight)
+			continue
+		}
+		if w.Field != "totalWeight" && w.Weight.GreaterThan(i.TotalWeight) {
+			addErr(w.Field, "cannot be greater than totalWeight (%s)", i.TotalWeight)
+		}
+	}
+	usedWeight, err := sumDecimals(i.SoldWeight, i.WasteWeight, i.DonateWeight)
+	if err != nil {
+		addErr("totalWeight", "sum of sold, waste, and donate weights: %s", err)
+	} else if usedWeight.GreaterThan(i.TotalWeight) {
+		addErr(
+			"totalWeight",
+			"must be at least the sum of sold, waste, and donate weights (%s)",
+			usedWeight,
+		)
+	}
+	if !rules.MaxTotalWeight.IsZero() && i.TotalWeight.GreaterThan(rules.MaxTotalWeight) {
+		addErr("totalWeight", "cannot be greater than %s", rules.MaxTotalWeight)
+	}
+
+	if i.Price.IsNegative() || (i.Price.IsZero() && !rules.AllowZeroPrice) {
+		addErr("price", "must be greater than zero, got %s", i.Price)
+	}
+
+	errs = append(errs, validateCodes(i.UPC, i.SKU)...)
+
+	_, err = ParseUnit(string(i.Unit))
+	if err != nil {
+		addErr("unit", "%s", err.Error())
+	}
+
+	maxArrival := time.Now().Add(rules.MaxArrivalSkew).Unix()
+	if i.DateArrived > maxArrival {
+		addErr("dateArrived", "cannot be in the future")
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// isFieldBlank checks if the field, specified by its JSON-name, has zero-value.
+// The field isn't known if it can't be required, such as "_id" or
+// "onFlashSale".
+func (i *Inventory) isFieldBlank(field string) (blank bool, known bool) {
+	switch field {
+	case "itemID":
+		return i.ItemID == (uuuid.UUID{}), true
+	case "parentItemID":
+		return i.ParentItemID == (uuuid.UUID{}), true
+	case "dateArrived":
+		return i.DateArrived == 0, true
+	case "dateSold":
+		return i.DateSold == 0, true
+	case "deviceID":
+		return i.DeviceID == (uuuid.UUID{}), true
+	case "location":
+		return i.Location == "", true
+	case "lot":
+		return i.Lot == "", true
+	case "name":
+		return i.Name == "", true
+	case "origin":
+		return i.Origin == "", true
+	case "price":
+		return i.Price.IsZero(), true
+	case "rsCustomerID":
+		return i.RSCustomerID == (uuuid.UUID{}), true
+	case "gtin":
+		return i.GTIN == "", true
+	case "sku":
+		return i.SKU == "", true
+	case "timestamp":
+		return i.Timestamp == 0, true
+	case "flashSaleTimestamp":
+		return i.FlashSaleTimestamp == 0, true
+	case "totalWeight":
+		return i.TotalWeight.IsZero(), true
+	case "soldWeight":
+		return i.SoldWeight.IsZero(), true
+	case "wasteWeight":
+		return i.WasteWeight.IsZero(), true
+	case "donateWeight":
+		return i.DonateWeight.IsZero(), true
+	case "flashSaleWeight":
+		return i.FlashSaleWeight.IsZero(), true
+	case "unit":
+		return i.Unit == "", true
+	case "upc":
+		return i.UPC == "", true
+	case "projectedDate":
+		return i.ProjectedDate == 0, true
+	default:
+		return false, false
+	}
+}
+
+// CheckRequiredFields returns error if any of RequiredFields isn't an
+// Inventory field that can be required, so misspelled fields aren't
+// silently ignored.
+func (c *ValidationConfig) CheckRequiredFields() error {
+	unknown := []string{}
+	for _, field := range c.RequiredFields {
+		_, known := (&Inventory{}).isFieldBlank(field)
+		if !known {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown required fields: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// decimalFields are the Inventory fields stored as Decimal.
+var decimalFields = []string{
+	"donateWeight",
+	"flashSaleWeight",
+	"price",
+	"soldWeight",
+	"totalWeight",
+	"wasteWeight",
+}
+
+// normalizeUpdate validates the fields in update-args, and sets any
+// fields derived from them.
+func normalizeUpdate(update map[string]interface{}) ValidationErrors {
+	errs := ValidationErrors{}
+	if update["unit"] != nil {
+		unit, assertOK := update["unit"].(string)
+		if !assertOK {
+			unit = fmt.Sprintf("%v", update["unit"])
+		}
+		_, err := ParseUnit(unit)
+		if err != nil {
+			errs = append(errs, FieldError{
+				Field:   "unit",
+				Message: err.Error(),
+			})
+		}
+	}
+	for _, field := range decimalFields {
+		if update[field] == nil {
+			continue
+		}
+		d, err := assertDecimal(update[field])
+		if err != nil {
+			errs = append(errs, FieldError{
+				Field:   field,
+				Message: err.Error(),
+			})
+			continue
+		}
+		// Stored as Decimal128, same as inserted documents
+		update[field] = d.Decimal128()
+	}
+	errs = append(errs, normalizeUpdateCodes(update)...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
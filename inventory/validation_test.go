@@ -0,0 +1,105 @@
+package inventory
+
+import (
+	"time"
+
+	"github.com/TerrexTech/uuuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validation", func() {
+	var inv *Inventory
+
+	BeforeEach(func() {
+		itemID, err := uuuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		inv = &Inventory{
+			ItemID:      itemID,
+			DateArrived: time.Now().Unix(),
+			Price:       decimalFloat(13.4),
+			TotalWeight: decimalInt(300),
+			SoldWeight:  decimalInt(20),
+			WasteWeight: decimalInt(12),
+		}
+	})
+
+	It("should pass valid Inventory", func() {
+		Expect(inv.Validate(DefaultValidationConfig())).To(BeNil())
+	})
+
+	It("should collect all field-errors", func() {
+		inv.ItemID = uuuid.UUID{}
+		inv.Price = Decimal{}
+		inv.DonateWeight = decimalInt(-1)
+		inv.DateArrived = time.Now().Add(time.Hour).Unix()
+
+		errs := inv.Validate(DefaultValidationConfig())
+		fields := []string{}
+		for _, fe := range errs {
+			fields = append(fields, fe.Field)
+		}
+		Expect(fields).To(ConsistOf("itemID", "price", "donateWeight", "dateArrived"))
+	})
+
+	It("should reject used weights exceeding totalWeight", func() {
+		inv.SoldWeight = decimalInt(200)
+		inv.WasteWeight = decimalInt(80)
+		inv.DonateWeight = decimalInt(40)
+
+		errs := inv.Validate(DefaultValidationConfig())
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("totalWeight"))
+	})
+
+	It("should apply configured rules", func() {
+		inv.Price = Decimal{}
+		rules := &ValidationConfig{
+			AllowZeroPrice: true,
+			MaxTotalWeight: decimalInt(100),
+			RequiredFields: []string{"sku"},
+		}
+
+		errs := inv.Validate(rules)
+		fields := []string{}
+		for _, fe := range errs {
+			fields = append(fields, fe.Field)
+		}
+		Expect(fields).To(ConsistOf("sku", "totalWeight"))
+	})
+
+	It("should require any Inventory field", func() {
+		rules := &ValidationConfig{
+			AllowZeroPrice: true,
+			RequiredFields: []string{"flashSaleWeight", "parentItemID"},
+		}
+		Expect(rules.CheckRequiredFields()).To(Succeed())
+
+		errs := inv.Validate(rules)
+		fields := []string{}
+		for _, fe := range errs {
+			fields = append(fields, fe.Field)
+		}
+		Expect(fields).To(ConsistOf("flashSaleWeight", "parentItemID"))
+	})
+
+	It("should reject unknown required fields", func() {
+		rules := &ValidationConfig{
+			RequiredFields: []string{"price", "skuu", "onFlashSale"},
+		}
+		err := rules.CheckRequiredFields()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("skuu, onFlashSale"))
+	})
+
+	It("should not validate inserts if InsertValidation is disabled", func() {
+		inv.DateArrived = time.Now().Add(time.Hour).Unix()
+		Expect(validateInsert(inv)).ToNot(BeEmpty())
+
+		InsertValidation = false
+		defer func() {
+			InsertValidation = true
+		}()
+		Expect(validateInsert(inv)).To(BeNil())
+	})
+})
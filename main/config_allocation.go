@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/pkg/errors"
+)
+
+// loadAllocationPolicies reads the default allocation-policy for SKU
+// sale-lines from ALLOCATION_POLICY, and the policies for specific SKUs from
+// the JSON-file specified by SKU_POLICIES_FILE, as an object of SKUs to
+// policies. FIFO is used if the env-vars are not set.
+func loadAllocationPolicies() (
+	inventory.AllocationPolicy, map[string]inventory.AllocationPolicy, error,
+) {
+	defaultPolicy, err := inventory.ParseAllocationPolicy(
+		os.Getenv("ALLOCATION_POLICY"),
+	)
+	if err != nil {
+		err = errors.Wrap(err, "Error parsing ALLOCATION_POLICY")
+		return "", nil, err
+	}
+
+	policies := map[string]inventory.AllocationPolicy{}
+	policiesFile := os.Getenv("SKU_POLICIES_FILE")
+	if policiesFile == "" {
+		return defaultPolicy, policies, nil
+	}
+	data, err := ioutil.ReadFile(policiesFile)
+	if err != nil {
+		err = errors.Wrap(err, "Error reading SKU_POLICIES_FILE")
+		return "", nil, err
+	}
+	skuPolicies := map[string]string{}
+	err = json.Unmarshal(data, &skuPolicies)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling sku-policies")
+		return "", nil, err
+	}
+	for sku, p := range skuPolicies {
+		policy, err := inventory.ParseAllocationPolicy(p)
+		if err != nil {
+			err = errors.Wrapf(err, "Invalid allocation-policy for sku %s", sku)
+			return "", nil, err
+		}
+		policies[sku] = policy
+	}
+	return defaultPolicy, policies, nil
+}
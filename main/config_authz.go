@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// loadAuthzStore creates the PermissionStore as specified by AUTHZ_STORE:
+// "file" reads permissions from AUTHZ_FILE, and "mongo" reads them from
+// MONGO_AUTHZ_COLLECTION. Commands are not authorized if AUTHZ_STORE is
+// not set.
+func loadAuthzStore(conn *mongo.ConnectionConfig) (inventory.PermissionStore, error) {
+	switch storeType := os.Getenv("AUTHZ_STORE"); storeType {
+	case "":
+		return nil, nil
+
+	case "file":
+		authzFile := os.Getenv("AUTHZ_FILE")
+		if authzFile == "" {
+			return nil, errors.New("AUTHZ_FILE is required for file authz-store")
+		}
+		store, err := inventory.NewFilePermissionStore(authzFile)
+		if err != nil {
+			err = errors.Wrap(err, "Error creating file authz-store")
+			return nil, err
+		}
+		return store, nil
+
+	case "mongo":
+		collName := os.Getenv("MONGO_AUTHZ_COLLECTION")
+		if collName == "" {
+			collName = "agg_inventory_permissions"
+		}
+		cacheSec := 30
+		cacheSecStr := os.Getenv("AUTHZ_CACHE_SEC")
+		if cacheSecStr != "" {
+			var err error
+			cacheSec, err = strconv.Atoi(cacheSecStr)
+			if err != nil {
+				err = errors.Wrap(err, "Error converting AUTHZ_CACHE_SEC to integer")
+				return nil, err
+			}
+		}
+
+		c := &mongo.Collection{
+			Connection:   conn,
+			Database:     os.Getenv("MONGO_DATABASE"),
+			Name:         collName,
+			SchemaStruct: &inventory.PermissionDoc{},
+		}
+		coll, err := mongo.EnsureCollection(c)
+		if err != nil {
+			err = errors.Wrap(err, "Error creating authz-collection")
+			return nil, err
+		}
+		store := inventory.NewMongoPermissionStore(
+			coll, time.Duration(cacheSec)*time.Second,
+		)
+		return store, nil
+
+	default:
+		return nil, fmt.Errorf(
+			"unsupported AUTHZ_STORE %s, must be file or mongo", storeType,
+		)
+	}
+}
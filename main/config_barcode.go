@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/pkg/errors"
+)
+
+// loadBarcodeRules reads variable-measure barcode-rules from the JSON-file
+// specified by BARCODE_RULES_FILE. Default rules are used if the env-var
+// is not set.
+func loadBarcodeRules() ([]inventory.BarcodeRule, error) {
+	rulesFile := os.Getenv("BARCODE_RULES_FILE")
+	if rulesFile == "" {
+		return inventory.DefaultBarcodeRules(), nil
+	}
+
+	data, err := ioutil.ReadFile(rulesFile)
+	if err != nil {
+		err = errors.Wrap(err, "Error reading BARCODE_RULES_FILE")
+		return nil, err
+	}
+	rules := []inventory.BarcodeRule{}
+	err = json.Unmarshal(data, &rules)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling barcode-rules")
+		return nil, err
+	}
+	for i, r := range rules {
+		err = r.Validate()
+		if err != nil {
+			err = errors.Wrapf(err, "Invalid barcode-rule at index %d", i)
+			return nil, err
+		}
+	}
+	return rules, nil
+}
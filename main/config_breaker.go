@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/pkg/errors"
+)
+
+// loadBreakerConfig reads the circuit-breaker config for Mongo and etcd from
+// env-vars. Default values are used for env-vars that are not set.
+func loadBreakerConfig() (inventory.BreakerConfig, error) {
+	config := inventory.DefaultBreakerConfig()
+
+	envInts := []struct {
+		name  string
+		apply func(v int)
+	}{
+		{"BREAKER_FAILURE_THRESHOLD", func(v int) { config.FailureThreshold = v }},
+		{"BREAKER_OPEN_SEC", func(v int) {
+			config.OpenTimeout = time.Duration(v) * time.Second
+		}},
+		{"BREAKER_HALF_OPEN_PROBES", func(v int) { config.HalfOpenProbes = v }},
+	}
+	for _, e := range envInts {
+		str := os.Getenv(e.name)
+		if str == "" {
+			continue
+		}
+		v, err := strconv.Atoi(str)
+		if err != nil {
+			err = errors.Wrapf(err, "Error converting %s to integer", e.name)
+			return config, err
+		}
+		if v < 1 {
+			return config, fmt.Errorf("%s must be positive, got %d", e.name, v)
+		}
+		e.apply(v)
+	}
+	return config, nil
+}
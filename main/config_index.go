@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/pkg/errors"
+)
+
+// loadIndexConfig reads the index-specs for aggregate-collection from the
+// JSON-file specified by INDEX_CONFIG_FILE, and whether indexes not in config
+// are dropped at startup from INDEX_DROP_EXTRA. Default indexes are used if
+// INDEX_CONFIG_FILE is not set.
+func loadIndexConfig() ([]inventory.IndexSpec, bool, error) {
+	dropExtra := false
+	dropExtraStr := os.Getenv("INDEX_DROP_EXTRA")
+	if dropExtraStr != "" {
+		var err error
+		dropExtra, err = strconv.ParseBool(dropExtraStr)
+		if err != nil {
+			err = errors.Wrap(err, "Error converting INDEX_DROP_EXTRA to bool")
+			log.Println(err)
+			log.Println("Extra indexes will only be reported")
+			dropExtra = false
+		}
+	}
+
+	configFile := os.Getenv("INDEX_CONFIG_FILE")
+	if configFile == "" {
+		return inventory.DefaultIndexes(), dropExtra, nil
+	}
+
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		err = errors.Wrap(err, "Error reading INDEX_CONFIG_FILE")
+		return nil, false, err
+	}
+	specs := []inventory.IndexSpec{}
+	err = json.Unmarshal(data, &specs)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling index-specs")
+		return nil, false, err
+	}
+	err = inventory.ValidateIndexSpecs(specs)
+	if err != nil {
+		return nil, false, err
+	}
+	return specs, dropExtra, nil
+}
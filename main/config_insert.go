@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/pkg/errors"
+)
+
+// loadInsertMode reads the InsertMode for existing itemIDs from INSERT_MODE.
+// Items are rejected with Conflict error if the env-var is not set.
+func loadInsertMode() (inventory.InsertMode, error) {
+	mode, err := inventory.ParseInsertMode(os.Getenv("INSERT_MODE"))
+	if err != nil {
+		err = errors.Wrap(err, "Error parsing INSERT_MODE")
+		return "", err
+	}
+	return mode, nil
+}
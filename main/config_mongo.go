@@ -1,66 +1,29 @@
 package main
 
 import (
-	"log"
 	"os"
-	"strconv"
 
+	"github.com/TerrexTech/agg-inventory-cmd/config"
 	"github.com/TerrexTech/agg-inventory-cmd/inventory"
-
-	"github.com/TerrexTech/go-commonutils/commonutil"
 	"github.com/TerrexTech/go-eventspoll/poll"
 	"github.com/TerrexTech/go-mongoutils/mongo"
 	"github.com/pkg/errors"
 )
 
-func loadMongoConfig() (*poll.MongoConfig, error) {
-	hosts := *commonutil.ParseHosts(
-		os.Getenv("MONGO_HOSTS"),
-	)
-
+func loadMongoConfig(indexes []inventory.IndexSpec) (*poll.MongoConfig, error) {
 	database := os.Getenv("MONGO_DATABASE")
 	aggCollection := os.Getenv("MONGO_AGG_COLLECTION")
 	metaCollection := os.Getenv("MONGO_META_COLLECTION")
 
-	username := os.Getenv("MONGO_USERNAME")
-	password := os.Getenv("MONGO_PASSWORD")
-	connTimeoutStr := os.Getenv("MONGO_CONNECTION_TIMEOUT_MS")
-	connTimeout, err := strconv.Atoi(connTimeoutStr)
-	if err != nil {
-		err = errors.Wrap(err, "Error converting MONGO_CONNECTION_TIMEOUT_MS to integer")
-		log.Println(err)
-		log.Println("A defalt value of 3000 will be used for MONGO_CONNECTION_TIMEOUT_MS")
-		connTimeout = 3000
-	}
-
-	mongoConfig := mongo.ClientConfig{
-		Hosts:               hosts,
-		Username:            username,
-		Password:            password,
-		TimeoutMilliseconds: uint32(connTimeout),
-	}
-
-	// MongoDB Client
-	client, err := mongo.NewClient(mongoConfig)
-	if err != nil {
-		err = errors.Wrap(err, "Error creating MongoClient")
-		log.Fatalln(err)
-	}
-
-	resTimeoutStr := os.Getenv("MONGO_CONNECTION_TIMEOUT_MS")
-	resTimeout, err := strconv.Atoi(resTimeoutStr)
+	conn, err := config.MongoConnection()
 	if err != nil {
-		err = errors.Wrap(err, "Error converting MONGO_RESOURCE_TIMEOUT_MS to integer")
-		log.Println(err)
-		log.Println("A defalt value of 5000 will be used for MONGO_RESOURCE_TIMEOUT_MS")
-		connTimeout = 5000
-	}
-	conn := &mongo.ConnectionConfig{
-		Client:  client,
-		Timeout: uint32(resTimeout),
+		err = errors.Wrap(err, "Error creating MongoConnection")
+		return nil, err
 	}
 
-	aggMongoCollection, err := createMongoCollection(conn, database, aggCollection)
+	aggMongoCollection, err := config.AggCollection(
+		conn, database, aggCollection, indexes,
+	)
 	if err != nil {
 		err = errors.Wrap(err, "Error creating MongoCollection")
 		return nil, err
@@ -75,42 +38,111 @@ func loadMongoConfig() (*poll.MongoConfig, error) {
 	}, nil
 }
 
-func createMongoCollection(
+// createSaleLedger creates the collection for recording sale-lines.
+func createSaleLedger(
 	conn *mongo.ConnectionConfig, db string, coll string,
 ) (*mongo.Collection, error) {
-	// Index Configuration
-	indexConfigs := []mongo.IndexConfig{
-		mongo.IndexConfig{
-			ColumnConfig: []mongo.IndexColumnConfig{
-				mongo.IndexColumnConfig{
-					Name: "itemID",
+	c := &mongo.Collection{
+		Connection:   conn,
+		Database:     db,
+		Name:         coll,
+		SchemaStruct: &inventory.SaleLine{},
+		Indexes: []mongo.IndexConfig{
+			mongo.IndexConfig{
+				ColumnConfig: []mongo.IndexColumnConfig{
+					mongo.IndexColumnConfig{
+						Name: "correlationID",
+					},
+					mongo.IndexColumnConfig{
+						Name: "itemID",
+					},
 				},
+				IsUnique: true,
+				Name:     "correlationID_itemID_index",
 			},
-			IsUnique: true,
-			Name:     "itemID_index",
 		},
-		mongo.IndexConfig{
-			ColumnConfig: []mongo.IndexColumnConfig{
-				mongo.IndexColumnConfig{
-					Name:        "timestamp",
-					IsDescOrder: true,
+	}
+	collection, err := mongo.EnsureCollection(c)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating sale-ledger collection")
+		return nil, err
+	}
+	return collection, nil
+}
+
+// createReservations creates the collection for recording stock-reservations.
+func createReservations(
+	conn *mongo.ConnectionConfig, db string, coll string,
+) (*mongo.Collection, error) {
+	c := &mongo.Collection{
+		Connection:   conn,
+		Database:     db,
+		Name:         coll,
+		SchemaStruct: &inventory.Reservation{},
+		Indexes: []mongo.IndexConfig{
+			mongo.IndexConfig{
+				ColumnConfig: []mongo.IndexColumnConfig{
+					mongo.IndexColumnConfig{
+						Name: "reservationID",
+					},
+					mongo.IndexColumnConfig{
+						Name: "itemID",
+					},
 				},
+				IsUnique: true,
+				Name:     "reservationID_itemID_index",
+			},
+			mongo.IndexConfig{
+				ColumnConfig: []mongo.IndexColumnConfig{
+					mongo.IndexColumnConfig{
+						Name: "itemID",
+					},
+					mongo.IndexColumnConfig{
+						Name: "released",
+					},
+					mongo.IndexColumnConfig{
+						Name: "consumed",
+					},
+					mongo.IndexColumnConfig{
+						Name: "expiresAt",
+					},
+				},
+				Name: "itemID_active_expiresAt_index",
 			},
-			Name: "timestamp_index",
 		},
 	}
+	collection, err := mongo.EnsureCollection(c)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating reservations collection")
+		return nil, err
+	}
+	return collection, nil
+}
 
-	// Create New Collection
+// createTransferJournal creates the collection for journaling partial transfers.
+func createTransferJournal(
+	conn *mongo.ConnectionConfig, db string, coll string,
+) (*mongo.Collection, error) {
 	c := &mongo.Collection{
 		Connection:   conn,
 		Database:     db,
 		Name:         coll,
-		SchemaStruct: &inventory.Inventory{},
-		Indexes:      indexConfigs,
+		SchemaStruct: &inventory.TransferEntry{},
+		Indexes: []mongo.IndexConfig{
+			mongo.IndexConfig{
+				ColumnConfig: []mongo.IndexColumnConfig{
+					mongo.IndexColumnConfig{
+						Name: "transferID",
+					},
+				},
+				IsUnique: true,
+				Name:     "transferID_index",
+			},
+		},
 	}
 	collection, err := mongo.EnsureCollection(c)
 	if err != nil {
-		err = errors.Wrap(err, "Error creating MongoCollection")
+		err = errors.Wrap(err, "Error creating transfer-journal collection")
 		return nil, err
 	}
 	return collection, nil
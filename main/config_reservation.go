@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/pkg/errors"
+)
+
+// loadReservationTTL reads the default reservation-TTL from RESERVATION_TTL_SEC.
+// The inventory default is used if the env-var is not set.
+func loadReservationTTL() (time.Duration, error) {
+	ttlStr := os.Getenv("RESERVATION_TTL_SEC")
+	if ttlStr == "" {
+		return inventory.ReservationTTL, nil
+	}
+	ttl, err := strconv.Atoi(ttlStr)
+	if err != nil {
+		err = errors.Wrap(err, "Error converting RESERVATION_TTL_SEC to integer")
+		return 0, err
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("RESERVATION_TTL_SEC must be positive, got %d", ttl)
+	}
+	return time.Duration(ttl) * time.Second, nil
+}
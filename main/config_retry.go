@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/pkg/errors"
+)
+
+// loadRetryPolicy reads the policy for retrying failed commands from env-vars.
+// Default values are used for env-vars that are not set.
+func loadRetryPolicy() (inventory.RetryPolicy, error) {
+	policy := inventory.DefaultRetryPolicy()
+
+	envInts := []struct {
+		name  string
+		apply func(v int)
+	}{
+		{"RETRY_MAX_ATTEMPTS", func(v int) { policy.MaxAttempts = v }},
+		{"RETRY_BASE_DELAY_MS", func(v int) {
+			policy.BaseDelay = time.Duration(v) * time.Millisecond
+		}},
+		{"RETRY_MAX_DELAY_MS", func(v int) {
+			policy.MaxDelay = time.Duration(v) * time.Millisecond
+		}},
+	}
+	for _, e := range envInts {
+		str := os.Getenv(e.name)
+		if str == "" {
+			continue
+		}
+		v, err := strconv.Atoi(str)
+		if err != nil {
+			err = errors.Wrapf(err, "Error converting %s to integer", e.name)
+			return policy, err
+		}
+		if v < 1 {
+			return policy, fmt.Errorf("%s must be positive, got %d", e.name, v)
+		}
+		e.apply(v)
+	}
+	if policy.BaseDelay > policy.MaxDelay {
+		return policy, errors.New("RETRY_BASE_DELAY_MS cannot exceed RETRY_MAX_DELAY_MS")
+	}
+	return policy, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/pkg/errors"
+)
+
+// loadSchemaValidation reads whether command event-data is checked against
+// command-schemas from SCHEMA_VALIDATION. Validation is enabled by default.
+func loadSchemaValidation() (bool, error) {
+	validationStr := os.Getenv("SCHEMA_VALIDATION")
+	if validationStr == "" {
+		return true, nil
+	}
+	validation, err := strconv.ParseBool(validationStr)
+	if err != nil {
+		err = errors.Wrap(err, "Error converting SCHEMA_VALIDATION to bool")
+		return false, err
+	}
+	return validation, nil
+}
+
+// exportSchemas writes the command-schemas as JSON Schema documents to dir,
+// so producers can validate event-data before publishing commands.
+func exportSchemas(dir string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating schema-directory")
+		return err
+	}
+	for _, s := range inventory.CommandSchemas() {
+		path := filepath.Join(dir, s.FileName())
+		err = ioutil.WriteFile(path, append(s.Document, '\n'), 0644)
+		if err != nil {
+			err = errors.Wrapf(err, "Error writing schema %s", path)
+			return err
+		}
+		log.Printf(
+			"Schemas: Exported schema for %s/%s to %s",
+			s.EventAction, s.ServiceAction, path,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/TerrexTech/uuuid"
+	"github.com/pkg/errors"
+)
+
+// loadTenantConfig reads TENANT_ISOLATION, the tenant of unmapped users from
+// TENANT_DEFAULT, and the user-tenants from the JSON-file specified by
+// TENANT_USERS_FILE. The file is an object mapping UserUUIDs to their
+// RSCustomerIDs.
+func loadTenantConfig() (bool, map[uuuid.UUID]uuuid.UUID, uuuid.UUID, error) {
+	userTenants := map[uuuid.UUID]uuuid.UUID{}
+
+	isolationStr := os.Getenv("TENANT_ISOLATION")
+	if isolationStr == "" {
+		return false, userTenants, uuuid.UUID{}, nil
+	}
+	isolation, err := strconv.ParseBool(isolationStr)
+	if err != nil {
+		err = errors.Wrap(err, "Error converting TENANT_ISOLATION to bool")
+		return false, nil, uuuid.UUID{}, err
+	}
+
+	var defaultTenant uuuid.UUID
+	defaultTenantStr := os.Getenv("TENANT_DEFAULT")
+	if defaultTenantStr != "" {
+		defaultTenant, err = uuuid.FromString(defaultTenantStr)
+		if err != nil {
+			err = errors.Wrap(err, "Error parsing TENANT_DEFAULT")
+			return false, nil, uuuid.UUID{}, err
+		}
+	}
+
+	usersFile := os.Getenv("TENANT_USERS_FILE")
+	if usersFile == "" {
+		return isolation, userTenants, defaultTenant, nil
+	}
+	data, err := ioutil.ReadFile(usersFile)
+	if err != nil {
+		err = errors.Wrap(err, "Error reading TENANT_USERS_FILE")
+		return false, nil, uuuid.UUID{}, err
+	}
+	users := map[string]string{}
+	err = json.Unmarshal(data, &users)
+	if err != nil {
+		err = errors.Wrap(err, "Error unmarshalling user-tenants")
+		return false, nil, uuuid.UUID{}, err
+	}
+	for userStr, tenantStr := range users {
+		userUUID, err := uuuid.FromString(userStr)
+		if err != nil {
+			err = errors.Wrapf(err, "Error parsing UserUUID %s", userStr)
+			return false, nil, uuuid.UUID{}, err
+		}
+		tenant, err := uuuid.FromString(tenantStr)
+		if err != nil {
+			err = errors.Wrapf(err, "Error parsing RSCustomerID for user %s", userStr)
+			return false, nil, uuuid.UUID{}, err
+		}
+		userTenants[userUUID] = tenant
+	}
+	return isolation, userTenants, defaultTenant, nil
+}
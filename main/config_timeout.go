@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/pkg/errors"
+)
+
+// loadCommandTimeouts reads the default command-deadline from
+// COMMAND_TIMEOUT_MS, and the per-action deadlines from ACTION_TIMEOUTS_MS.
+// ACTION_TIMEOUTS_MS is a comma-separated list of <action>=<milliseconds>,
+// such as "createSale=10000,transferInventory=20000".
+func loadCommandTimeouts() (time.Duration, map[string]time.Duration, error) {
+	timeout := inventory.CommandTimeout
+	actionTimeouts := map[string]time.Duration{}
+
+	timeoutStr := os.Getenv("COMMAND_TIMEOUT_MS")
+	if timeoutStr != "" {
+		ms, err := strconv.Atoi(timeoutStr)
+		if err != nil {
+			err = errors.Wrap(err, "Error converting COMMAND_TIMEOUT_MS to integer")
+			return 0, nil, err
+		}
+		if ms < 1 {
+			return 0, nil, fmt.Errorf("COMMAND_TIMEOUT_MS must be positive, got %d", ms)
+		}
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+
+	actionsStr := os.Getenv("ACTION_TIMEOUTS_MS")
+	if actionsStr == "" {
+		return timeout, actionTimeouts, nil
+	}
+	for _, entry := range strings.Split(actionsStr, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), "=")
+		if len(parts) != 2 || parts[0] == "" {
+			return 0, nil, fmt.Errorf(
+				"ACTION_TIMEOUTS_MS entries must be <action>=<milliseconds>, got %s", entry,
+			)
+		}
+		ms, err := strconv.Atoi(parts[1])
+		if err != nil {
+			err = errors.Wrapf(err, "Error converting timeout for action %s", parts[0])
+			return 0, nil, err
+		}
+		if ms < 1 {
+			return 0, nil, fmt.Errorf(
+				"timeout for action %s must be positive, got %d", parts[0], ms,
+			)
+		}
+		actionTimeouts[parts[0]] = time.Duration(ms) * time.Millisecond
+	}
+	return timeout, actionTimeouts, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// loadTracerProvider creates the OpenTelemetry tracer-provider with the
+// span-exporter as per TRACE_EXPORTER env-var. Tracing is disabled (no-op
+// provider) if TRACE_EXPORTER is not set.
+func loadTracerProvider() (trace.TracerProvider, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch os.Getenv("TRACE_EXPORTER") {
+	case "":
+		return trace.NewNoopTracerProvider(), nil
+
+	case "otlp":
+		// Collector-endpoint and other options are read from the
+		// OTEL_EXPORTER_OTLP_* env-vars
+		exporter, err = otlptracehttp.New(context.Background())
+		if err != nil {
+			err = errors.Wrap(err, "Error creating OTLP-exporter")
+			return nil, err
+		}
+
+	case "file":
+		path := os.Getenv("TRACE_FILE")
+		if path == "" {
+			path = "./traces.jsonl"
+		}
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			err = errors.Wrap(err, "Error opening trace-file")
+			return nil, err
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			err = errors.Wrap(err, "Error creating file-exporter")
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf(
+			`TRACE_EXPORTER must be "otlp" or "file", got "%s"`,
+			os.Getenv("TRACE_EXPORTER"),
+		)
+	}
+
+	res := resource.NewWithAttributes(
+		semconv.SchemaURL, semconv.ServiceName(os.Getenv("SERVICE_NAME")),
+	)
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithIDGenerator(inventory.NewIDGenerator()),
+		sdktrace.WithResource(res),
+	), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/pkg/errors"
+)
+
+// loadValidationConfig reads the Inventory validation-rules from env-vars.
+// Default rules are used for env-vars that are not set.
+func loadValidationConfig() (*inventory.ValidationConfig, error) {
+	config := inventory.DefaultValidationConfig()
+
+	allowZeroPriceStr := os.Getenv("VALIDATION_ALLOW_ZERO_PRICE")
+	if allowZeroPriceStr != "" {
+		allowZeroPrice, err := strconv.ParseBool(allowZeroPriceStr)
+		if err != nil {
+			err = errors.Wrap(err, "Error converting VALIDATION_ALLOW_ZERO_PRICE to bool")
+			return nil, err
+		}
+		config.AllowZeroPrice = allowZeroPrice
+	}
+
+	arrivalSkewStr := os.Getenv("VALIDATION_MAX_ARRIVAL_SKEW_SEC")
+	if arrivalSkewStr != "" {
+		arrivalSkew, err := strconv.Atoi(arrivalSkewStr)
+		if err != nil {
+			err = errors.Wrap(
+				err, "Error converting VALIDATION_MAX_ARRIVAL_SKEW_SEC to integer",
+			)
+			return nil, err
+		}
+		config.MaxArrivalSkew = time.Duration(arrivalSkew) * time.Second
+	}
+
+	maxWeightStr := os.Getenv("VALIDATION_MAX_TOTAL_WEIGHT")
+	if maxWeightStr != "" {
+		maxWeight, err := inventory.ParseDecimal(maxWeightStr)
+		if err != nil {
+			err = errors.Wrap(
+				err, "Error converting VALIDATION_MAX_TOTAL_WEIGHT to decimal",
+			)
+			return nil, err
+		}
+		config.MaxTotalWeight = maxWeight
+	}
+
+	requiredFieldsStr := os.Getenv("VALIDATION_REQUIRED_FIELDS")
+	if requiredFieldsStr != "" {
+		for _, field := range strings.Split(requiredFieldsStr, ",") {
+			field = strings.TrimSpace(field)
+			if field != "" {
+				config.RequiredFields = append(config.RequiredFields, field)
+			}
+		}
+		err := config.CheckRequiredFields()
+		if err != nil {
+			err = errors.Wrap(err, "Error in VALIDATION_REQUIRED_FIELDS")
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
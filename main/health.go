@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/pkg/errors"
+)
+
+// startHealthServer serves the service-health on /health, and metrics in
+// Prometheus text-format on /metrics. Health is "degraded", with status 503,
+// while any circuit-breaker is open.
+func startHealthServer(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/metrics", metricsHandler)
+
+	go func() {
+		log.Printf("Serving health and metrics on %s", addr)
+		err := http.ListenAndServe(addr, mux)
+		if err != nil {
+			err = errors.Wrap(err, "Error serving health and metrics")
+			log.Println(err)
+		}
+	}()
+}
+
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	status := "ok"
+	statusCode := http.StatusOK
+	breakers := []inventory.BreakerStats{}
+	for _, b := range inventory.Breakers() {
+		stats := b.Stats()
+		if b.State() == inventory.BreakerOpen {
+			status = "degraded"
+			statusCode = http.StatusServiceUnavailable
+		}
+		breakers = append(breakers, stats)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   status,
+		"breakers": breakers,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error writing health-response")
+		log.Println(err)
+	}
+}
+
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	metrics := []struct {
+		name  string
+		help  string
+		kind  string
+		value func(s inventory.BreakerStats, state inventory.BreakerState) float64
+	}{
+		{
+			"inventory_breaker_state",
+			"Circuit-breaker state: 0 closed, 1 open, 2 half-open.",
+			"gauge",
+			func(s inventory.BreakerStats, state inventory.BreakerState) float64 {
+				return float64(state)
+			},
+		},
+		{
+			"inventory_breaker_successes_total",
+			"Calls reported successful to circuit-breaker.",
+			"counter",
+			func(s inventory.BreakerStats, _ inventory.BreakerState) float64 {
+				return float64(s.Successes)
+			},
+		},
+		{
+			"inventory_breaker_failures_total",
+			"Calls reported failed to circuit-breaker.",
+			"counter",
+			func(s inventory.BreakerStats, _ inventory.BreakerState) float64 {
+				return float64(s.Failures)
+			},
+		},
+		{
+			"inventory_breaker_rejected_total",
+			"Calls failed fast by circuit-breaker.",
+			"counter",
+			func(s inventory.BreakerStats, _ inventory.BreakerState) float64 {
+				return float64(s.Rejected)
+			},
+		},
+		{
+			"inventory_breaker_opens_total",
+			"Times the circuit-breaker opened.",
+			"counter",
+			func(s inventory.BreakerStats, _ inventory.BreakerState) float64 {
+				return float64(s.Opens)
+			},
+		},
+	}
+
+	breakers := inventory.Breakers()
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+		for _, b := range breakers {
+			fmt.Fprintf(
+				w, "%s{backend=%q} %g\n", m.name, b.Name, m.value(b.Stats(), b.State()),
+			)
+		}
+	}
+}
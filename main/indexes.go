@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-eventspoll/poll"
+	"github.com/mongodb/mongo-go-driver/bson"
+	mgo "github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/pkg/errors"
+)
+
+// mongoIndexManager manages indexes using mongo-driver directly, since
+// go-mongoutils only creates indexes when ensuring collection.
+type mongoIndexManager struct {
+	indexes mgo.IndexView
+	timeout time.Duration
+}
+
+// newIndexManager connects a mongo-driver client as configured by MONGO_*
+// env-vars. The returned func disconnects the client.
+func newIndexManager(
+	database string, coll string, timeout time.Duration,
+) (*mongoIndexManager, func(), error) {
+	uri := &url.URL{
+		Scheme: "mongodb",
+		Host:   strings.Join(*commonutil.ParseHosts(os.Getenv("MONGO_HOSTS")), ","),
+	}
+	if os.Getenv("MONGO_USERNAME") != "" {
+		uri.User = url.UserPassword(
+			os.Getenv("MONGO_USERNAME"), os.Getenv("MONGO_PASSWORD"),
+		)
+	}
+	client, err := mgo.NewClient(uri.String())
+	if err != nil {
+		err = errors.Wrap(err, "Error creating mongo-driver client")
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err = client.Connect(ctx)
+	if err != nil {
+		err = errors.Wrap(err, "Error connecting mongo-driver client")
+		return nil, nil, err
+	}
+	disconnect := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		err := client.Disconnect(ctx)
+		if err != nil {
+			err = errors.Wrap(err, "Error disconnecting mongo-driver client")
+			log.Println(err)
+		}
+	}
+
+	return &mongoIndexManager{
+		indexes: client.Database(database).Collection(coll).Indexes(),
+		timeout: timeout,
+	}, disconnect, nil
+}
+
+// List returns the indexes on collection.
+func (m *mongoIndexManager) List() ([]inventory.IndexSpec, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	cur, err := m.indexes.List(ctx)
+	if err != nil {
+		err = errors.Wrap(err, "Error listing indexes")
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	specs := []inventory.IndexSpec{}
+	for cur.Next(ctx) {
+		doc := bson.NewDocument()
+		err = cur.Decode(doc)
+		if err != nil {
+			err = errors.Wrap(err, "Error decoding index")
+			return nil, err
+		}
+		specs = append(specs, indexSpecFromDocument(doc))
+	}
+	err = cur.Err()
+	if err != nil {
+		err = errors.Wrap(err, "Error reading indexes")
+		return nil, err
+	}
+	return specs, nil
+}
+
+// indexSpecFromDocument converts an index as listed by MongoDB to IndexSpec.
+// Key-directions that aren't numeric, such as "text", are read as ascending;
+// such indexes are reported as mismatched if configured.
+func indexSpecFromDocument(doc *bson.Document) inventory.IndexSpec {
+	spec := inventory.IndexSpec{}
+	iter := doc.Iterator()
+	for iter.Next() {
+		elem := iter.Element()
+		switch elem.Key() {
+		case "name":
+			if elem.Value().Type() == bson.TypeString {
+				spec.Name = elem.Value().StringValue()
+			}
+		case "unique":
+			if elem.Value().Type() == bson.TypeBoolean {
+				spec.Unique = elem.Value().Boolean()
+			}
+		case "key":
+			if elem.Value().Type() != bson.TypeEmbeddedDocument {
+				continue
+			}
+			keyIter := elem.Value().MutableDocument().Iterator()
+			for keyIter.Next() {
+				keyElem := keyIter.Element()
+				desc := false
+				switch keyElem.Value().Type() {
+				case bson.TypeInt32:
+					desc = keyElem.Value().Int32() < 0
+				case bson.TypeInt64:
+					desc = keyElem.Value().Int64() < 0
+				case bson.TypeDouble:
+					desc = keyElem.Value().Double() < 0
+				}
+				spec.Keys = append(spec.Keys, inventory.IndexKey{
+					Field: keyElem.Key(),
+					Desc:  desc,
+				})
+			}
+		}
+	}
+	return spec
+}
+
+// Create builds the index in background, so collection isn't locked
+// while the index is built.
+func (m *mongoIndexManager) Create(spec inventory.IndexSpec) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	keys := bson.NewDocument()
+	for _, k := range spec.Keys {
+		order := int32(1)
+		if k.Desc {
+			order = -1
+		}
+		keys.Append(bson.EC.Int32(k.Field, order))
+	}
+	options := bson.NewDocument(
+		bson.EC.String("name", spec.Name),
+		bson.EC.Boolean("background", true),
+	)
+	if spec.Unique {
+		options.Append(bson.EC.Boolean("unique", true))
+	}
+
+	_, err := m.indexes.CreateOne(ctx, mgo.IndexModel{
+		Keys:    keys,
+		Options: options,
+	})
+	return err
+}
+
+// Drop drops the index with specified name.
+func (m *mongoIndexManager) Drop(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	_, err := m.indexes.DropOne(ctx, name)
+	return err
+}
+
+// reconcileIndexes reconciles the indexes on collection collName, such as the
+// aggregate-collection, with specs, and logs the differences. Nothing is
+// changed if dryRun is true.
+func reconcileIndexes(
+	mc *poll.MongoConfig,
+	collName string,
+	specs []inventory.IndexSpec,
+	dropExtra bool,
+	dryRun bool,
+) error {
+	timeout := time.Duration(mc.Connection.Timeout) * time.Millisecond
+	manager, disconnect, err := newIndexManager(
+		mc.MetaDatabaseName, collName, timeout,
+	)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	report, err := inventory.ReconcileIndexes(manager, specs, dropExtra, dryRun)
+	if report != nil {
+		logIndexReport(collName, report, dryRun)
+	}
+	if err != nil {
+		err = errors.Wrap(err, "Error reconciling indexes")
+		return err
+	}
+	return nil
+}
+
+func logIndexReport(coll string, report *inventory.IndexReport, dryRun bool) {
+	if len(report.Missing) > 0 {
+		log.Printf("Indexes: Missing on %s: %v", coll, report.Missing)
+	}
+	if len(report.Created) > 0 {
+		log.Printf("Indexes: Created on %s: %v", coll, report.Created)
+	}
+	if len(report.Mismatched) > 0 {
+		log.Printf(
+			"Indexes: Differ from config on %s, and must be rebuilt manually: %v",
+			coll, report.Mismatched,
+		)
+	}
+	if len(report.Extra) > 0 {
+		log.Printf("Indexes: Not in config on %s: %v", coll, report.Extra)
+	}
+	if len(report.Dropped) > 0 {
+		log.Printf("Indexes: Dropped on %s: %v", coll, report.Dropped)
+	}
+	if dryRun {
+		log.Println("Indexes: Dry-run, no indexes were changed")
+	}
+}
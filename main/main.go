@@ -1,19 +1,22 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"os"
-	"time"
 
 	"github.com/TerrexTech/go-agg-framer/framer"
 	"github.com/TerrexTech/go-kafkautils/kafka"
 
+	"github.com/TerrexTech/agg-inventory-cmd/config"
 	"github.com/TerrexTech/agg-inventory-cmd/inventory"
 	"github.com/TerrexTech/go-commonutils/commonutil"
 	"github.com/TerrexTech/go-eventspoll/poll"
-	"github.com/coreos/etcd/clientv3"
+	"github.com/TerrexTech/go-eventstore-models/model"
 	"github.com/joho/godotenv"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func validateEnv() error {
@@ -45,6 +48,51 @@ func validateEnv() error {
 	return nil
 }
 
+// handleEvent decodes the command-event's content, authorizes it and runs its
+// handler with retries, and sends the resulting Document, encoded in the
+// event's content-type, to framer. Each stage is traced as a span
+// of the event's trace. The handler's context is cancelled when the command
+// passes its deadline, or when parent is cancelled at shutdown.
+func handleEvent(
+	parent context.Context,
+	docs chan<- *model.Document,
+	event *model.Event,
+	handle func(ctx context.Context) *model.Document,
+) {
+	ctx, span := inventory.StartTrace(parent, event)
+	ctx, cancel := inventory.CommandContext(ctx, event)
+	defer cancel()
+
+	ctx, contentType, doc := inventory.DecodeEventContent(ctx, event)
+	// Authorized on each attempt, so permissions that can't be loaded are
+	// retried, and dead-lettered like other backend-failures
+	authorizedHandle := func(ctx context.Context) *model.Document {
+		_, authzSpan := inventory.StartSpan(ctx, "authorize")
+		authzDoc := inventory.Authorize(event)
+		authzSpan.End()
+		if authzDoc != nil {
+			return authzDoc
+		}
+		return handle(ctx)
+	}
+	if doc == nil {
+		handleCtx, handleSpan := inventory.StartSpan(ctx, "handle")
+		doc = inventory.HandleWithRetry(handleCtx, event, authorizedHandle)
+		handleSpan.End()
+	}
+	doc = inventory.EncodeDocumentContent(event, contentType, doc)
+	var docErr error
+	if doc != nil && doc.Error != "" {
+		span.SetAttributes(attribute.Int("document.errorCode", int(doc.ErrorCode)))
+		docErr = errors.New(doc.Error)
+	}
+
+	_, sendSpan := inventory.StartSpan(ctx, "framer.send")
+	docs <- doc
+	sendSpan.End()
+	inventory.EndSpan(span, docErr)
+}
+
 func main() {
 	log.Println("Reading environment file")
 	err := godotenv.Load("./.env")
@@ -55,21 +103,235 @@ func main() {
 		log.Println(err)
 	}
 
+	rebuild := flag.Bool(
+		"rebuild", false,
+		"Rebuild projection by replaying all events from EventStore, and exit",
+	)
+	rebuildColl := flag.String(
+		"rebuild-collection", "",
+		"Collection to rebuild projection into (default: <agg-collection>_rebuild)",
+	)
+	migrate := flag.Bool(
+		"migrate", false,
+		"Migrate Inventory documents to current schema-version, and exit",
+	)
+	migrateDryRun := flag.Bool(
+		"migrate-dry-run", false,
+		"Only count the documents to migrate, when used with -migrate",
+	)
+	migrateBatch := flag.Int64(
+		"migrate-batch", 500,
+		"Number of documents read per batch, when used with -migrate",
+	)
+	reconcile := flag.Bool(
+		"indexes", false,
+		"Reconcile indexes on aggregate-collection with index-config, and exit",
+	)
+	dropExtraIndexes := flag.Bool(
+		"drop-extra-indexes", false,
+		"Drop indexes not in index-config, when used with -indexes or -migrate",
+	)
+	schemaDir := flag.String(
+		"export-schemas", "",
+		"Write command-schemas as JSON Schema files to specified directory, and exit",
+	)
+	flag.Parse()
+
+	err = inventory.CheckCommandSchemas()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if *schemaDir != "" {
+		err = exportSchemas(*schemaDir)
+		if err != nil {
+			err = errors.Wrap(err, "Error exporting schemas")
+			log.Fatalln(err)
+		}
+		return
+	}
+
 	err = validateEnv()
 	if err != nil {
 		log.Fatalln(err)
 	}
+	inventory.ValidationRules, err = loadValidationConfig()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	inventory.SchemaValidation, err = loadSchemaValidation()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	inventory.BarcodeRules, err = loadBarcodeRules()
+	if err != nil {
+		err = errors.Wrap(err, "Error loading barcode-rules")
+		log.Fatalln(err)
+	}
+	inventory.DuplicateInsertMode, err = loadInsertMode()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	inventory.Retry, err = loadRetryPolicy()
+	if err != nil {
+		err = errors.Wrap(err, "Error loading retry-policy")
+		log.Fatalln(err)
+	}
+	inventory.DeadLetterTopic = os.Getenv("KAFKA_PRODUCER_DEAD_LETTER_TOPIC")
+	breakerConfig, err := loadBreakerConfig()
+	if err != nil {
+		err = errors.Wrap(err, "Error loading breaker-config")
+		log.Fatalln(err)
+	}
+	inventory.MongoBreaker = inventory.NewBreaker("mongo", breakerConfig)
+	inventory.EtcdBreaker = inventory.NewBreaker("etcd", breakerConfig)
+	inventory.ReservationTTL, err = loadReservationTTL()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	inventory.DefaultAllocationPolicy, inventory.SKUPolicies, err =
+		loadAllocationPolicies()
+	if err != nil {
+		err = errors.Wrap(err, "Error loading allocation-policies")
+		log.Fatalln(err)
+	}
+	inventory.CommandTimeout, inventory.ActionTimeouts, err = loadCommandTimeouts()
+	if err != nil {
+		err = errors.Wrap(err, "Error loading command-timeouts")
+		log.Fatalln(err)
+	}
+	inventory.TracerProvider, err = loadTracerProvider()
+	if err != nil {
+		err = errors.Wrap(err, "Error loading tracer-provider")
+		log.Fatalln(err)
+	}
+	inventory.TenantIsolation, inventory.UserTenants, inventory.DefaultTenant, err =
+		loadTenantConfig()
+	if err != nil {
+		err = errors.Wrap(err, "Error loading tenant-config")
+		log.Fatalln(err)
+	}
 
 	kc, err := loadKafkaConfig()
 	if err != nil {
 		err = errors.Wrap(err, "Error in KafkaConfig")
 		log.Fatalln(err)
 	}
-	mc, err := loadMongoConfig()
+	indexes, dropExtra, err := loadIndexConfig()
+	if err != nil {
+		err = errors.Wrap(err, "Error loading index-config")
+		log.Fatalln(err)
+	}
+	mc, err := loadMongoConfig(indexes)
 	if err != nil {
 		err = errors.Wrap(err, "Error in MongoConfig")
 		log.Fatalln(err)
 	}
+
+	if *migrate {
+		err = migrateDocuments(mc, *migrateBatch, *migrateDryRun)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		err = reconcileIndexes(
+			mc, mc.AggCollection.Name, indexes, *dropExtraIndexes, *migrateDryRun,
+		)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	if *reconcile {
+		err = reconcileIndexes(
+			mc, mc.AggCollection.Name, indexes, *dropExtraIndexes, false,
+		)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+	// Only the itemID-index is created with the aggregate-collection. Other
+	// indexes failing to reconcile at startup, such as unique-indexes on
+	// duplicated data, are logged and don't stop the service
+	err = reconcileIndexes(mc, mc.AggCollection.Name, indexes, dropExtra, false)
+	if err != nil {
+		log.Println(err)
+	}
+
+	ledgerColl := os.Getenv("MONGO_SALE_LEDGER_COLLECTION")
+	if ledgerColl == "" {
+		ledgerColl = os.Getenv("MONGO_AGG_COLLECTION") + "_sales"
+	}
+	reservationColl := os.Getenv("MONGO_RESERVATION_COLLECTION")
+	if reservationColl == "" {
+		reservationColl = os.Getenv("MONGO_AGG_COLLECTION") + "_reservations"
+	}
+	transferColl := os.Getenv("MONGO_TRANSFER_JOURNAL_COLLECTION")
+	if transferColl == "" {
+		transferColl = os.Getenv("MONGO_AGG_COLLECTION") + "_transfers"
+	}
+
+	if *rebuild {
+		etcd, err := config.EtcdClient()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		collName := *rebuildColl
+		if collName == "" {
+			collName = os.Getenv("MONGO_AGG_COLLECTION") + "_rebuild"
+		}
+		inventory.SaleLedger, err = createSaleLedger(
+			mc.Connection, mc.MetaDatabaseName, ledgerColl+"_rebuild",
+		)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		inventory.Reservations, err = createReservations(
+			mc.Connection, mc.MetaDatabaseName, reservationColl+"_rebuild",
+		)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		inventory.TransferJournal, err = createTransferJournal(
+			mc.Connection, mc.MetaDatabaseName, transferColl+"_rebuild",
+		)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		err = rebuildProjection(mc, etcd, collName, indexes)
+		if err != nil {
+			err = errors.Wrap(err, "Error rebuilding projection")
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	inventory.SaleLedger, err = createSaleLedger(
+		mc.Connection, mc.MetaDatabaseName, ledgerColl,
+	)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	inventory.Reservations, err = createReservations(
+		mc.Connection, mc.MetaDatabaseName, reservationColl,
+	)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	inventory.TransferJournal, err = createTransferJournal(
+		mc.Connection, mc.MetaDatabaseName, transferColl,
+	)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	inventory.Authz, err = loadAuthzStore(mc.Connection)
+	if err != nil {
+		err = errors.Wrap(err, "Error loading authz-store")
+		log.Fatalln(err)
+	}
+	if os.Getenv("HEALTH_ADDR") != "" {
+		startHealthServer(os.Getenv("HEALTH_ADDR"))
+	}
+
 	ioConfig := poll.IOConfig{
 		ReadConfig: poll.ReadConfig{
 			EnableInsert: true,
@@ -85,22 +347,8 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	etcdHostsStr := os.Getenv("ETCD_HOSTS")
-	etcdConfig := clientv3.Config{
-		DialTimeout: 5 * time.Second,
-		Endpoints:   *commonutil.ParseHosts(etcdHostsStr),
-	}
-	etcdUsername := os.Getenv("ETCD_USERNAME")
-	etcdPassword := os.Getenv("ETCD_PASSWORD")
-	if etcdUsername != "" {
-		etcdConfig.Username = etcdUsername
-	}
-	if etcdPassword != "" {
-		etcdConfig.Password = etcdPassword
-	}
-	etcd, err := clientv3.New(etcdConfig)
+	etcd, err := config.EtcdClient()
 	if err != nil {
-		err = errors.Wrap(err, "Failed to connect to ETCD")
 		log.Fatalln(err)
 	}
 	log.Println("ETCD Ready")
@@ -135,9 +383,17 @@ func main() {
 				if err != nil {
 					err = errors.Wrap(err, "Error in Delete-EventResponse")
 					log.Println(err)
+					inventory.DeadLetterMalformedEvent(&eventResp.Event, err)
 					return
 				}
-				frm.Document <- inventory.Delete(mc.AggCollection, &eventResp.Event)
+				handleEvent(
+					eventPoll.Context(),
+					frm.Document,
+					&eventResp.Event,
+					func(ctx context.Context) *model.Document {
+						return inventory.Delete(ctx, mc.AggCollection, &eventResp.Event)
+					},
+				)
 			}(eventResp)
 
 		case eventResp := <-eventPoll.Insert():
@@ -149,9 +405,17 @@ func main() {
 				if err != nil {
 					err = errors.Wrap(eventResp.Error, "Error in Insert-EventResponse")
 					log.Println(err)
+					inventory.DeadLetterMalformedEvent(&eventResp.Event, err)
 					return
 				}
-				frm.Document <- inventory.Insert(mc.AggCollection, &eventResp.Event)
+				handleEvent(
+					eventPoll.Context(),
+					frm.Document,
+					&eventResp.Event,
+					func(ctx context.Context) *model.Document {
+						return inventory.Insert(ctx, etcd, mc.AggCollection, &eventResp.Event)
+					},
+				)
 			}(eventResp)
 
 		case eventResp := <-eventPoll.Update():
@@ -163,9 +427,17 @@ func main() {
 				if err != nil {
 					err = errors.Wrap(err, "Error in Update-EventResponse")
 					log.Println(err)
+					inventory.DeadLetterMalformedEvent(&eventResp.Event, err)
 					return
 				}
-				frm.Document <- inventory.Update(etcd, mc.AggCollection, &eventResp.Event)
+				handleEvent(
+					eventPoll.Context(),
+					frm.Document,
+					&eventResp.Event,
+					func(ctx context.Context) *model.Document {
+						return inventory.Update(ctx, etcd, mc.AggCollection, &eventResp.Event)
+					},
+				)
 			}(eventResp)
 		}
 	}
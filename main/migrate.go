@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log"
+	"sort"
+
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/TerrexTech/go-eventspoll/poll"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/pkg/errors"
+)
+
+// migrateDocuments migrates the documents in aggregate-collection to current
+// schema-version. Documents are only counted if dryRun is true.
+func migrateDocuments(mc *poll.MongoConfig, batchSize int64, dryRun bool) error {
+	coll, err := mongo.EnsureCollection(&mongo.Collection{
+		Connection:   mc.Connection,
+		Database:     mc.AggCollection.Database,
+		Name:         mc.AggCollection.Name,
+		SchemaStruct: &inventory.StoredDocument{},
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error creating collection for migration")
+		return err
+	}
+
+	log.Printf(
+		"Migration: Migrating documents in %s to schema-version %d (dry-run: %t)",
+		mc.AggCollection.Name, inventory.CurrentSchemaVersion, dryRun,
+	)
+	stats, err := inventory.MigrateDocuments(coll, batchSize, dryRun)
+	if err != nil {
+		err = errors.Wrap(err, "Error migrating documents")
+		return err
+	}
+
+	versions := []int{}
+	for v := range stats.FromVersions {
+		versions = append(versions, int(v))
+	}
+	sort.Ints(versions)
+	for _, v := range versions {
+		log.Printf(
+			"Migration: %d documents at schema-version %d",
+			stats.FromVersions[int64(v)], v,
+		)
+	}
+	log.Printf(
+		"Migration: Scanned %d, migrated %d, conflicted %d, failed %d documents",
+		stats.Scanned, stats.Migrated, stats.Conflicted, stats.Failed,
+	)
+	if stats.Conflicted > 0 {
+		log.Println(
+			"Migration: Conflicted documents were changed while migrating, " +
+				"run the migration again to migrate them",
+		)
+	}
+	return nil
+}
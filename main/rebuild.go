@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/TerrexTech/agg-inventory-cmd/config"
+	"github.com/TerrexTech/agg-inventory-cmd/inventory"
+	"github.com/TerrexTech/go-commonutils/commonutil"
+	"github.com/TerrexTech/go-eventspoll/poll"
+	"github.com/TerrexTech/go-eventstore-models/model"
+	"github.com/TerrexTech/go-kafkautils/kafka"
+	"github.com/TerrexTech/go-mongoutils/mongo"
+	"github.com/TerrexTech/uuuid"
+	mgo "github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// rebuildQueryTimeout is how long to wait for a response from EventStore-Query
+// before the query is sent again.
+const rebuildQueryTimeout = 30 * time.Second
+
+// rebuildQueryAttempts is the number of times a query is sent before giving up.
+const rebuildQueryAttempts = 3
+
+// rebuildStats tracks the progress of projection-rebuild.
+type rebuildStats struct {
+	Applied int
+	Failed  int
+	Skipped int
+	Version int64
+}
+
+// queryRespHandler forwards EventStore-Query responses to the provided channel.
+type queryRespHandler struct {
+	docChan chan<- *model.Document
+}
+
+func (*queryRespHandler) Setup(sarama.ConsumerGroupSession) error {
+	log.Println("Initializing Rebuild Query-Response Handler")
+	return nil
+}
+
+func (*queryRespHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	log.Println("Closing Rebuild Query-Response Handler")
+	return nil
+}
+
+func (h *queryRespHandler) ConsumeClaim(
+	session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim,
+) error {
+	for msg := range claim.Messages() {
+		session.MarkMessage(msg, "")
+
+		doc := &model.Document{}
+		err := json.Unmarshal(msg.Value, doc)
+		if err != nil {
+			err = errors.Wrap(err, "Rebuild: Error unmarshalling Query-Response")
+			log.Println(err)
+			continue
+		}
+		select {
+		case h.docChan <- doc:
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// checkEmpty returns error if the collection has any documents. Errors reading
+// the collection are returned too, since it can't be told if its empty.
+func checkEmpty(coll *mongo.Collection, kind string) error {
+	_, err := coll.FindOne(map[string]interface{}{})
+	if err == nil {
+		return fmt.Errorf("%s %s is not empty", kind, coll.Name)
+	}
+	if errors.Cause(err) != mgo.ErrNoDocuments {
+		err = errors.Wrapf(err, "Error reading %s %s", kind, coll.Name)
+		return err
+	}
+	return nil
+}
+
+// rebuildProjection replays all Inventory events from EventStore into a fresh
+// collection, using the same handlers that process live events. The
+// aggregate-version in meta-collection is reset to the last replayed version
+// once all events are applied.
+func rebuildProjection(
+	mc *poll.MongoConfig,
+	etcd *clientv3.Client,
+	collName string,
+	indexes []inventory.IndexSpec,
+) error {
+	coll, err := config.AggCollection(
+		mc.Connection, mc.MetaDatabaseName, collName, indexes,
+	)
+	if err != nil {
+		err = errors.Wrap(err, "Rebuild: Error creating target collection")
+		return err
+	}
+	err = checkEmpty(coll, "target collection")
+	if err != nil {
+		err = errors.Wrap(err, "Rebuild")
+		return err
+	}
+	targets := map[string]*mongo.Collection{
+		"target sale-ledger":      inventory.SaleLedger,
+		"target reservations":     inventory.Reservations,
+		"target transfer-journal": inventory.TransferJournal,
+	}
+	for kind, target := range targets {
+		if target == nil {
+			continue
+		}
+		err = checkEmpty(target, kind)
+		if err != nil {
+			err = errors.Wrap(err, "Rebuild")
+			return err
+		}
+	}
+	err = reconcileIndexes(mc, collName, indexes, false, false)
+	if err != nil {
+		err = errors.Wrap(err, "Rebuild: Error creating indexes on target collection")
+		return err
+	}
+
+	kafkaBrokers := *commonutil.ParseHosts(
+		os.Getenv("KAFKA_BROKERS"),
+	)
+	reqTopic := os.Getenv("KAFKA_PRODUCER_EVENT_QUERY_TOPIC")
+	resTopic := fmt.Sprintf(
+		"%s.%d",
+		os.Getenv("KAFKA_CONSUMER_EVENT_QUERY_TOPIC"),
+		inventory.AggregateID,
+	)
+	// A separate group so the live service (if any) doesn't steal responses
+	resGroup := os.Getenv("KAFKA_CONSUMER_EVENT_QUERY_GROUP") + ".rebuild"
+
+	producer, err := kafka.NewProducer(&kafka.ProducerConfig{
+		KafkaBrokers: kafkaBrokers,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Rebuild: Error creating Query-Producer")
+		return err
+	}
+	defer producer.Close()
+
+	consumer, err := kafka.NewConsumer(&kafka.ConsumerConfig{
+		KafkaBrokers: kafkaBrokers,
+		GroupName:    resGroup,
+		Topics:       []string{resTopic},
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Rebuild: Error creating Query-Response Consumer")
+		return err
+	}
+	defer consumer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	docChan := make(chan *model.Document, 16)
+	go func() {
+		for ctx.Err() == nil {
+			err := consumer.Consume(ctx, &queryRespHandler{docChan})
+			if err != nil {
+				err = errors.Wrap(err, "Rebuild: Error consuming Query-Responses")
+				log.Println(err)
+			}
+		}
+	}()
+
+	// Sale, transfer, and audit-events were already published when these
+	// events were first processed
+	inventory.PublishSaleEvents = false
+	inventory.PublishTransferEvents = false
+	inventory.PublishAuditEvents = false
+	// Events are replayed as accepted when first processed, even if they
+	// predate current command-schemas or validation-rules
+	schemaValidation := inventory.SchemaValidation
+	inventory.SchemaValidation = false
+	insertValidation := inventory.InsertValidation
+	inventory.InsertValidation = false
+	defer func() {
+		inventory.PublishSaleEvents = true
+		inventory.PublishTransferEvents = true
+		inventory.PublishAuditEvents = true
+		inventory.SchemaValidation = schemaValidation
+		inventory.InsertValidation = insertValidation
+	}()
+
+	stats := &rebuildStats{}
+	seenEvents := map[uuuid.UUID]bool{}
+	for {
+		events, err := queryEvents(producer, docChan, reqTopic, stats.Version)
+		if err != nil {
+			err = errors.Wrap(err, "Rebuild")
+			return err
+		}
+
+		newEvents := []model.Event{}
+		for _, e := range events {
+			if !seenEvents[e.UUID] {
+				seenEvents[e.UUID] = true
+				newEvents = append(newEvents, e)
+			}
+		}
+		if len(newEvents) == 0 {
+			break
+		}
+
+		sort.SliceStable(newEvents, func(i, j int) bool {
+			if newEvents[i].Version == newEvents[j].Version {
+				return newEvents[i].NanoTime < newEvents[j].NanoTime
+			}
+			return newEvents[i].Version < newEvents[j].Version
+		})
+		for i := range newEvents {
+			applyRebuildEvent(etcd, coll, &newEvents[i], stats)
+		}
+
+		log.Printf(
+			"Rebuild: Replayed up to version %d: %d applied, %d failed, %d skipped",
+			stats.Version, stats.Applied, stats.Failed, stats.Skipped,
+		)
+	}
+
+	err = resetAggregateVersion(mc, stats.Version)
+	if err != nil {
+		err = errors.Wrap(err, "Rebuild")
+		return err
+	}
+
+	log.Printf(
+		"Rebuild complete: %d applied, %d failed, %d skipped. "+
+			"Aggregate-version was reset to %d. "+
+			"Set MONGO_AGG_COLLECTION to %s to use the rebuilt projection.",
+		stats.Applied, stats.Failed, stats.Skipped, stats.Version, collName,
+	)
+	return nil
+}
+
+// queryEvents requests events newer than the specified version from
+// EventStore-Query and waits for the response.
+func queryEvents(
+	producer *kafka.Producer,
+	docChan <-chan *model.Document,
+	reqTopic string,
+	version int64,
+) ([]model.Event, error) {
+	for attempt := 1; attempt <= rebuildQueryAttempts; attempt++ {
+		cid, err := uuuid.NewV4()
+		if err != nil {
+			err = errors.Wrap(err, "Error generating CorrelationID for EventStoreQuery")
+			return nil, err
+		}
+		uuid, err := uuuid.NewV4()
+		if err != nil {
+			err = errors.Wrap(err, "Error generating UUID for EventStoreQuery")
+			return nil, err
+		}
+		query, err := json.Marshal(model.EventStoreQuery{
+			AggregateID:      inventory.AggregateID,
+			AggregateVersion: version,
+			CorrelationID:    cid,
+			UUID:             uuid,
+			YearBucket:       inventory.EventYearBucket,
+		})
+		if err != nil {
+			err = errors.Wrap(err, "Error marshalling EventStoreQuery")
+			return nil, err
+		}
+		producer.Input() <- kafka.CreateMessage(reqTopic, query)
+
+		timeout := time.After(rebuildQueryTimeout)
+	waitLoop:
+		for {
+			select {
+			case <-timeout:
+				log.Printf(
+					"Rebuild: Timed out querying events after version %d (attempt %d/%d)",
+					version, attempt, rebuildQueryAttempts,
+				)
+				break waitLoop
+
+			case doc := <-docChan:
+				if doc.CorrelationID != cid {
+					continue
+				}
+				if doc.Error != "" {
+					err = fmt.Errorf(
+						"error in EventStoreQuery response: %s (code %d)",
+						doc.Error, doc.ErrorCode,
+					)
+					return nil, err
+				}
+				events := []model.Event{}
+				if len(doc.Result) == 0 {
+					return events, nil
+				}
+				err = json.Unmarshal(doc.Result, &events)
+				if err != nil {
+					err = errors.Wrap(err, "Error unmarshalling events from Query-Response")
+					return nil, err
+				}
+				return events, nil
+			}
+		}
+	}
+
+	err := fmt.Errorf(
+		"no response from EventStore-Query after %d attempts", rebuildQueryAttempts,
+	)
+	return nil, err
+}
+
+// applyRebuildEvent processes the event using the regular event-handlers.
+func applyRebuildEvent(
+	etcd *clientv3.Client,
+	coll *mongo.Collection,
+	event *model.Event,
+	stats *rebuildStats,
+) {
+	if event.Version > stats.Version {
+		stats.Version = event.Version
+	}
+
+	// Replayed events have no deadline, since skipping an event
+	// would leave the projection inconsistent
+	ctx := context.Background()
+	ctx, _, doc := inventory.DecodeEventContent(ctx, event)
+	if doc != nil {
+		stats.Failed++
+		log.Printf("Rebuild: Event %s content failed: %s", event.UUID, doc.Error)
+		return
+	}
+	switch event.EventAction {
+	case "insert":
+		doc = inventory.Insert(ctx, etcd, coll, event)
+	case "update":
+		doc = inventory.Update(ctx, etcd, coll, event)
+	case "delete":
+		doc = inventory.Delete(ctx, coll, event)
+	default:
+		stats.Skipped++
+		return
+	}
+
+	if doc == nil || doc.Error != "" {
+		stats.Failed++
+		if doc != nil {
+			log.Printf(
+				"Rebuild: Event %s (%s/%s) failed: %s",
+				event.UUID, event.EventAction, event.ServiceAction, doc.Error,
+			)
+		}
+		return
+	}
+	stats.Applied++
+}
+
+// resetAggregateVersion sets the Inventory aggregate-version in meta-collection.
+func resetAggregateVersion(mc *poll.MongoConfig, version int64) error {
+	c := &mongo.Collection{
+		Connection:   mc.Connection,
+		Database:     mc.MetaDatabaseName,
+		Name:         mc.MetaCollectionName,
+		SchemaStruct: &model.EventMeta{},
+	}
+	metaColl, err := mongo.EnsureCollection(c)
+	if err != nil {
+		err = errors.Wrap(err, "Error creating Meta-Collection")
+		return err
+	}
+
+	filter := map[string]interface{}{
+		"aggregateID": inventory.AggregateID,
+	}
+	update := map[string]interface{}{
+		"aggregateVersion": version,
+	}
+	updateResult, err := metaColl.UpdateMany(filter, update)
+	if err != nil {
+		err = errors.Wrap(err, "Error updating aggregate-version")
+		return err
+	}
+	if updateResult.MatchedCount > 0 {
+		return nil
+	}
+
+	_, err = metaColl.InsertOne(&model.EventMeta{
+		AggregateID:      inventory.AggregateID,
+		AggregateVersion: version,
+	})
+	if err != nil {
+		err = errors.Wrap(err, "Error inserting aggregate-version")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+// Package pb contains the Protobuf messages in inventory.proto, generated by
+// protoc-gen-go v1.2.0. After changing inventory.proto, install protoc and
+// protoc-gen-go from the Go proxy:
+//
+//	GO111MODULE=on go get github.com/golang/protobuf/protoc-gen-go@v1.2.0
+//
+// and regenerate inventory.pb.go with "go generate ./pb".
+package pb
+
+//go:generate protoc --go_out=. inventory.proto
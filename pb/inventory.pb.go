@@ -0,0 +1,1143 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: inventory.proto
+
+package pb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import field_mask "google.golang.org/genproto/protobuf/field_mask"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type Inventory struct {
+	// id is the ObjectID-hex of stored item, and is only set in results.
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ItemId               string   `protobuf:"bytes,2,opt,name=item_id,json=itemId,proto3" json:"item_id,omitempty"`
+	DateArrived          int64    `protobuf:"varint,3,opt,name=date_arrived,json=dateArrived,proto3" json:"date_arrived,omitempty"`
+	DateSold             int64    `protobuf:"varint,4,opt,name=date_sold,json=dateSold,proto3" json:"date_sold,omitempty"`
+	DeviceId             string   `protobuf:"bytes,5,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	DonateWeight         string   `protobuf:"bytes,6,opt,name=donate_weight,json=donateWeight,proto3" json:"donate_weight,omitempty"`
+	Location             string   `protobuf:"bytes,7,opt,name=location,proto3" json:"location,omitempty"`
+	Lot                  string   `protobuf:"bytes,8,opt,name=lot,proto3" json:"lot,omitempty"`
+	Name                 string   `protobuf:"bytes,9,opt,name=name,proto3" json:"name,omitempty"`
+	Origin               string   `protobuf:"bytes,10,opt,name=origin,proto3" json:"origin,omitempty"`
+	Price                string   `protobuf:"bytes,11,opt,name=price,proto3" json:"price,omitempty"`
+	RsCustomerId         string   `protobuf:"bytes,12,opt,name=rs_customer_id,json=rsCustomerId,proto3" json:"rs_customer_id,omitempty"`
+	FlashSaleWeight      string   `protobuf:"bytes,13,opt,name=flash_sale_weight,json=flashSaleWeight,proto3" json:"flash_sale_weight,omitempty"`
+	Gtin                 string   `protobuf:"bytes,14,opt,name=gtin,proto3" json:"gtin,omitempty"`
+	Sku                  string   `protobuf:"bytes,15,opt,name=sku,proto3" json:"sku,omitempty"`
+	SoldWeight           string   `protobuf:"bytes,16,opt,name=sold_weight,json=soldWeight,proto3" json:"sold_weight,omitempty"`
+	Timestamp            int64    `protobuf:"varint,17,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	TotalWeight          string   `protobuf:"bytes,18,opt,name=total_weight,json=totalWeight,proto3" json:"total_weight,omitempty"`
+	Unit                 string   `protobuf:"bytes,19,opt,name=unit,proto3" json:"unit,omitempty"`
+	Upc                  string   `protobuf:"bytes,20,opt,name=upc,proto3" json:"upc,omitempty"`
+	WasteWeight          string   `protobuf:"bytes,21,opt,name=waste_weight,json=wasteWeight,proto3" json:"waste_weight,omitempty"`
+	OnFlashSale          bool     `protobuf:"varint,22,opt,name=on_flash_sale,json=onFlashSale,proto3" json:"on_flash_sale,omitempty"`
+	ParentItemId         string   `protobuf:"bytes,23,opt,name=parent_item_id,json=parentItemId,proto3" json:"parent_item_id,omitempty"`
+	FlashSaleTimestamp   int64    `protobuf:"varint,24,opt,name=flash_sale_timestamp,json=flashSaleTimestamp,proto3" json:"flash_sale_timestamp,omitempty"`
+	ProjectedDate        int64    `protobuf:"varint,25,opt,name=projected_date,json=projectedDate,proto3" json:"projected_date,omitempty"`
+	SchemaVersion        int64    `protobuf:"varint,26,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Inventory) Reset()         { *m = Inventory{} }
+func (m *Inventory) String() string { return proto.CompactTextString(m) }
+func (*Inventory) ProtoMessage()    {}
+func (*Inventory) Descriptor() ([]byte, []int) {
+	return fileDescriptor_inventory_e9e20ed81d4d0671, []int{0}
+}
+func (m *Inventory) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Inventory.Unmarshal(m, b)
+}
+func (m *Inventory) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Inventory.Marshal(b, m, deterministic)
+}
+func (dst *Inventory) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Inventory.Merge(dst, src)
+}
+func (m *Inventory) XXX_Size() int {
+	return xxx_messageInfo_Inventory.Size(m)
+}
+func (m *Inventory) XXX_DiscardUnknown() {
+	xxx_messageInfo_Inventory.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Inventory proto.InternalMessageInfo
+
+func (m *Inventory) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Inventory) GetItemId() string {
+	if m != nil {
+		return m.ItemId
+	}
+	return ""
+}
+
+func (m *Inventory) GetDateArrived() int64 {
+	if m != nil {
+		return m.DateArrived
+	}
+	return 0
+}
+
+func (m *Inventory) GetDateSold() int64 {
+	if m != nil {
+		return m.DateSold
+	}
+	return 0
+}
+
+func (m *Inventory) GetDeviceId() string {
+	if m != nil {
+		return m.DeviceId
+	}
+	return ""
+}
+
+func (m *Inventory) GetDonateWeight() string {
+	if m != nil {
+		return m.DonateWeight
+	}
+	return ""
+}
+
+func (m *Inventory) GetLocation() string {
+	if m != nil {
+		return m.Location
+	}
+	return ""
+}
+
+func (m *Inventory) GetLot() string {
+	if m != nil {
+		return m.Lot
+	}
+	return ""
+}
+
+func (m *Inventory) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Inventory) GetOrigin() string {
+	if m != nil {
+		return m.Origin
+	}
+	return ""
+}
+
+func (m *Inventory) GetPrice() string {
+	if m != nil {
+		return m.Price
+	}
+	return ""
+}
+
+func (m *Inventory) GetRsCustomerId() string {
+	if m != nil {
+		return m.RsCustomerId
+	}
+	return ""
+}
+
+func (m *Inventory) GetFlashSaleWeight() string {
+	if m != nil {
+		return m.FlashSaleWeight
+	}
+	return ""
+}
+
+func (m *Inventory) GetGtin() string {
+	if m != nil {
+		return m.Gtin
+	}
+	return ""
+}
+
+func (m *Inventory) GetSku() string {
+	if m != nil {
+		return m.Sku
+	}
+	return ""
+}
+
+func (m *Inventory) GetSoldWeight() string {
+	if m != nil {
+		return m.SoldWeight
+	}
+	return ""
+}
+
+func (m *Inventory) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Inventory) GetTotalWeight() string {
+	if m != nil {
+		return m.TotalWeight
+	}
+	return ""
+}
+
+func (m *Inventory) GetUnit() string {
+	if m != nil {
+		return m.Unit
+	}
+	return ""
+}
+
+func (m *Inventory) GetUpc() string {
+	if m != nil {
+		return m.Upc
+	}
+	return ""
+}
+
+func (m *Inventory) GetWasteWeight() string {
+	if m != nil {
+		return m.WasteWeight
+	}
+	return ""
+}
+
+func (m *Inventory) GetOnFlashSale() bool {
+	if m != nil {
+		return m.OnFlashSale
+	}
+	return false
+}
+
+func (m *Inventory) GetParentItemId() string {
+	if m != nil {
+		return m.ParentItemId
+	}
+	return ""
+}
+
+func (m *Inventory) GetFlashSaleTimestamp() int64 {
+	if m != nil {
+		return m.FlashSaleTimestamp
+	}
+	return 0
+}
+
+func (m *Inventory) GetProjectedDate() int64 {
+	if m != nil {
+		return m.ProjectedDate
+	}
+	return 0
+}
+
+func (m *Inventory) GetSchemaVersion() int64 {
+	if m != nil {
+		return m.SchemaVersion
+	}
+	return 0
+}
+
+type FieldError struct {
+	Field                string   `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FieldError) Reset()         { *m = FieldError{} }
+func (m *FieldError) String() string { return proto.CompactTextString(m) }
+func (*FieldError) ProtoMessage()    {}
+func (*FieldError) Descriptor() ([]byte, []int) {
+	return fileDescriptor_inventory_e9e20ed81d4d0671, []int{1}
+}
+func (m *FieldError) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FieldError.Unmarshal(m, b)
+}
+func (m *FieldError) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FieldError.Marshal(b, m, deterministic)
+}
+func (dst *FieldError) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FieldError.Merge(dst, src)
+}
+func (m *FieldError) XXX_Size() int {
+	return xxx_messageInfo_FieldError.Size(m)
+}
+func (m *FieldError) XXX_DiscardUnknown() {
+	xxx_messageInfo_FieldError.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FieldError proto.InternalMessageInfo
+
+func (m *FieldError) GetField() string {
+	if m != nil {
+		return m.Field
+	}
+	return ""
+}
+
+func (m *FieldError) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+// ValidationResult is the Document-result for ValidationErrors (error-code 5).
+type ValidationResult struct {
+	FieldErrors          []*FieldError `protobuf:"bytes,1,rep,name=field_errors,json=fieldErrors,proto3" json:"field_errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *ValidationResult) Reset()         { *m = ValidationResult{} }
+func (m *ValidationResult) String() string { return proto.CompactTextString(m) }
+func (*ValidationResult) ProtoMessage()    {}
+func (*ValidationResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_inventory_e9e20ed81d4d0671, []int{2}
+}
+func (m *ValidationResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ValidationResult.Unmarshal(m, b)
+}
+func (m *ValidationResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ValidationResult.Marshal(b, m, deterministic)
+}
+func (dst *ValidationResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValidationResult.Merge(dst, src)
+}
+func (m *ValidationResult) XXX_Size() int {
+	return xxx_messageInfo_ValidationResult.Size(m)
+}
+func (m *ValidationResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValidationResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ValidationResult proto.InternalMessageInfo
+
+func (m *ValidationResult) GetFieldErrors() []*FieldError {
+	if m != nil {
+		return m.FieldErrors
+	}
+	return nil
+}
+
+// InsertCommand is the event-data for "insert". Either item is set for
+// single inserts, or items for bulk-inserts.
+type InsertCommand struct {
+	Item  *Inventory   `protobuf:"bytes,1,opt,name=item,proto3" json:"item,omitempty"`
+	Items []*Inventory `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	// unordered bulk-inserts continue inserting after an item fails.
+	Unordered            bool     `protobuf:"varint,3,opt,name=unordered,proto3" json:"unordered,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InsertCommand) Reset()         { *m = InsertCommand{} }
+func (m *InsertCommand) String() string { return proto.CompactTextString(m) }
+func (*InsertCommand) ProtoMessage()    {}
+func (*InsertCommand) Descriptor() ([]byte, []int) {
+	return fileDescriptor_inventory_e9e20ed81d4d0671, []int{3}
+}
+func (m *InsertCommand) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InsertCommand.Unmarshal(m, b)
+}
+func (m *InsertCommand) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InsertCommand.Marshal(b, m, deterministic)
+}
+func (dst *InsertCommand) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InsertCommand.Merge(dst, src)
+}
+func (m *InsertCommand) XXX_Size() int {
+	return xxx_messageInfo_InsertCommand.Size(m)
+}
+func (m *InsertCommand) XXX_DiscardUnknown() {
+	xxx_messageInfo_InsertCommand.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InsertCommand proto.InternalMessageInfo
+
+func (m *InsertCommand) GetItem() *Inventory {
+	if m != nil {
+		return m.Item
+	}
+	return nil
+}
+
+func (m *InsertCommand) GetItems() []*Inventory {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+func (m *InsertCommand) GetUnordered() bool {
+	if m != nil {
+		return m.Unordered
+	}
+	return false
+}
+
+type BulkInsertItemResult struct {
+	Index                int32         `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	ItemId               string        `protobuf:"bytes,2,opt,name=item_id,json=itemId,proto3" json:"item_id,omitempty"`
+	Id                   string        `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`
+	Inserted             bool          `protobuf:"varint,4,opt,name=inserted,proto3" json:"inserted,omitempty"`
+	Existing             bool          `protobuf:"varint,5,opt,name=existing,proto3" json:"existing,omitempty"`
+	Error                string        `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	ErrorCode            int32         `protobuf:"varint,7,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
+	FieldErrors          []*FieldError `protobuf:"bytes,8,rep,name=field_errors,json=fieldErrors,proto3" json:"field_errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *BulkInsertItemResult) Reset()         { *m = BulkInsertItemResult{} }
+func (m *BulkInsertItemResult) String() string { return proto.CompactTextString(m) }
+func (*BulkInsertItemResult) ProtoMessage()    {}
+func (*BulkInsertItemResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_inventory_e9e20ed81d4d0671, []int{4}
+}
+func (m *BulkInsertItemResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BulkInsertItemResult.Unmarshal(m, b)
+}
+func (m *BulkInsertItemResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BulkInsertItemResult.Marshal(b, m, deterministic)
+}
+func (dst *BulkInsertItemResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BulkInsertItemResult.Merge(dst, src)
+}
+func (m *BulkInsertItemResult) XXX_Size() int {
+	return xxx_messageInfo_BulkInsertItemResult.Size(m)
+}
+func (m *BulkInsertItemResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_BulkInsertItemResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BulkInsertItemResult proto.InternalMessageInfo
+
+func (m *BulkInsertItemResult) GetIndex() int32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *BulkInsertItemResult) GetItemId() string {
+	if m != nil {
+		return m.ItemId
+	}
+	return ""
+}
+
+func (m *BulkInsertItemResult) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *BulkInsertItemResult) GetInserted() bool {
+	if m != nil {
+		return m.Inserted
+	}
+	return false
+}
+
+func (m *BulkInsertItemResult) GetExisting() bool {
+	if m != nil {
+		return m.Existing
+	}
+	return false
+}
+
+func (m *BulkInsertItemResult) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *BulkInsertItemResult) GetErrorCode() int32 {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return 0
+}
+
+func (m *BulkInsertItemResult) GetFieldErrors() []*FieldError {
+	if m != nil {
+		return m.FieldErrors
+	}
+	return nil
+}
+
+type BulkInsertResult struct {
+	Ordered              bool                    `protobuf:"varint,1,opt,name=ordered,proto3" json:"ordered,omitempty"`
+	InsertedCount        int32                   `protobuf:"varint,2,opt,name=inserted_count,json=insertedCount,proto3" json:"inserted_count,omitempty"`
+	FailedCount          int32                   `protobuf:"varint,3,opt,name=failed_count,json=failedCount,proto3" json:"failed_count,omitempty"`
+	Items                []*BulkInsertItemResult `protobuf:"bytes,4,rep,name=items,proto3" json:"items,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
+	XXX_unrecognized     []byte                  `json:"-"`
+	XXX_sizecache        int32                   `json:"-"`
+}
+
+func (m *BulkInsertResult) Reset()         { *m = BulkInsertResult{} }
+func (m *BulkInsertResult) String() string { return proto.CompactTextString(m) }
+func (*BulkInsertResult) ProtoMessage()    {}
+func (*BulkInsertResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_inventory_e9e20ed81d4d0671, []int{5}
+}
+func (m *BulkInsertResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BulkInsertResult.Unmarshal(m, b)
+}
+func (m *BulkInsertResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BulkInsertResult.Marshal(b, m, deterministic)
+}
+func (dst *BulkInsertResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BulkInsertResult.Merge(dst, src)
+}
+func (m *BulkInsertResult) XXX_Size() int {
+	return xxx_messageInfo_BulkInsertResult.Size(m)
+}
+func (m *BulkInsertResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_BulkInsertResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BulkInsertResult proto.InternalMessageInfo
+
+func (m *BulkInsertResult) GetOrdered() bool {
+	if m != nil {
+		return m.Ordered
+	}
+	return false
+}
+
+func (m *BulkInsertResult) GetInsertedCount() int32 {
+	if m != nil {
+		return m.InsertedCount
+	}
+	return 0
+}
+
+func (m *BulkInsertResult) GetFailedCount() int32 {
+	if m != nil {
+		return m.FailedCount
+	}
+	return 0
+}
+
+func (m *BulkInsertResult) GetItems() []*BulkInsertItemResult {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+// InsertResult is the Document-result for "insert". Item is set for single
+// inserts, and bulk for bulk-inserts.
+type InsertResult struct {
+	Item                 *Inventory        `protobuf:"bytes,1,opt,name=item,proto3" json:"item,omitempty"`
+	Bulk                 *BulkInsertResult `protobuf:"bytes,2,opt,name=bulk,proto3" json:"bulk,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *InsertResult) Reset()         { *m = InsertResult{} }
+func (m *InsertResult) String() string { return proto.CompactTextString(m) }
+func (*InsertResult) ProtoMessage()    {}
+func (*InsertResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_inventory_e9e20ed81d4d0671, []int{6}
+}
+func (m *InsertResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InsertResult.Unmarshal(m, b)
+}
+func (m *InsertResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InsertResult.Marshal(b, m, deterministic)
+}
+func (dst *InsertResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InsertResult.Merge(dst, src)
+}
+func (m *InsertResult) XXX_Size() int {
+	return xxx_messageInfo_InsertResult.Size(m)
+}
+func (m *InsertResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_InsertResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InsertResult proto.InternalMessageInfo
+
+func (m *InsertResult) GetItem() *Inventory {
+	if m != nil {
+		return m.Item
+	}
+	return nil
+}
+
+func (m *InsertResult) GetBulk() *BulkInsertResult {
+	if m != nil {
+		return m.Bulk
+	}
+	return nil
+}
+
+// SaleLine is a line of "createSale", "createFlashSale", "createWaste", and
+// "createDonation". Lines need one of item_id, barcode, or sku.
+type SaleLine struct {
+	ItemId               string   `protobuf:"bytes,1,opt,name=item_id,json=itemId,proto3" json:"item_id,omitempty"`
+	Barcode              string   `protobuf:"bytes,2,opt,name=barcode,proto3" json:"barcode,omitempty"`
+	Sku                  string   `protobuf:"bytes,3,opt,name=sku,proto3" json:"sku,omitempty"`
+	Lot                  string   `protobuf:"bytes,4,opt,name=lot,proto3" json:"lot,omitempty"`
+	Weight               string   `protobuf:"bytes,5,opt,name=weight,proto3" json:"weight,omitempty"`
+	Unit                 string   `protobuf:"bytes,6,opt,name=unit,proto3" json:"unit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SaleLine) Reset()         { *m = SaleLine{} }
+func (m *SaleLine) String() string { return proto.CompactTextString(m) }
+func (*SaleLine) ProtoMessage()    {}
+func (*SaleLine) Descriptor() ([]byte, []int) {
+	return fileDescriptor_inventory_e9e20ed81d4d0671, []int{7}
+}
+func (m *SaleLine) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SaleLine.Unmarshal(m, b)
+}
+func (m *SaleLine) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SaleLine.Marshal(b, m, deterministic)
+}
+func (dst *SaleLine) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SaleLine.Merge(dst, src)
+}
+func (m *SaleLine) XXX_Size() int {
+	return xxx_messageInfo_SaleLine.Size(m)
+}
+func (m *SaleLine) XXX_DiscardUnknown() {
+	xxx_messageInfo_SaleLine.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SaleLine proto.InternalMessageInfo
+
+func (m *SaleLine) GetItemId() string {
+	if m != nil {
+		return m.ItemId
+	}
+	return ""
+}
+
+func (m *SaleLine) GetBarcode() string {
+	if m != nil {
+		return m.Barcode
+	}
+	return ""
+}
+
+func (m *SaleLine) GetSku() string {
+	if m != nil {
+		return m.Sku
+	}
+	return ""
+}
+
+func (m *SaleLine) GetLot() string {
+	if m != nil {
+		return m.Lot
+	}
+	return ""
+}
+
+func (m *SaleLine) GetWeight() string {
+	if m != nil {
+		return m.Weight
+	}
+	return ""
+}
+
+func (m *SaleLine) GetUnit() string {
+	if m != nil {
+		return m.Unit
+	}
+	return ""
+}
+
+// SaleCommand is the event-data for sales, waste, and donations.
+type SaleCommand struct {
+	Items                []*SaleLine `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	ReservationId        string      `protobuf:"bytes,2,opt,name=reservation_id,json=reservationId,proto3" json:"reservation_id,omitempty"`
+	PartialFulfillment   bool        `protobuf:"varint,3,opt,name=partial_fulfillment,json=partialFulfillment,proto3" json:"partial_fulfillment,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *SaleCommand) Reset()         { *m = SaleCommand{} }
+func (m *SaleCommand) String() string { return proto.CompactTextString(m) }
+func (*SaleCommand) ProtoMessage()    {}
+func (*SaleCommand) Descriptor() ([]byte, []int) {
+	return fileDescriptor_inventory_e9e20ed81d4d0671, []int{8}
+}
+func (m *SaleCommand) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SaleCommand.Unmarshal(m, b)
+}
+func (m *SaleCommand) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SaleCommand.Marshal(b, m, deterministic)
+}
+func (dst *SaleCommand) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SaleCommand.Merge(dst, src)
+}
+func (m *SaleCommand) XXX_Size() int {
+	return xxx_messageInfo_SaleCommand.Size(m)
+}
+func (m *SaleCommand) XXX_DiscardUnknown() {
+	xxx_messageInfo_SaleCommand.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SaleCommand proto.InternalMessageInfo
+
+func (m *SaleCommand) GetItems() []*SaleLine {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+func (m *SaleCommand) GetReservationId() string {
+	if m != nil {
+		return m.ReservationId
+	}
+	return ""
+}
+
+func (m *SaleCommand) GetPartialFulfillment() bool {
+	if m != nil {
+		return m.PartialFulfillment
+	}
+	return false
+}
+
+type SaleItemResult struct {
+	ItemId               string   `protobuf:"bytes,1,opt,name=item_id,json=itemId,proto3" json:"item_id,omitempty"`
+	Barcode              string   `protobuf:"bytes,2,opt,name=barcode,proto3" json:"barcode,omitempty"`
+	Sku                  string   `protobuf:"bytes,3,opt,name=sku,proto3" json:"sku,omitempty"`
+	Error                string   `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	ErrorCode            int32    `protobuf:"varint,5,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
+	TotalSoldWeight      string   `protobuf:"bytes,6,opt,name=total_sold_weight,json=totalSoldWeight,proto3" json:"total_sold_weight,omitempty"`
+	TotalWasteWeight     string   `protobuf:"bytes,7,opt,name=total_waste_weight,json=totalWasteWeight,proto3" json:"total_waste_weight,omitempty"`
+	TotalDonateWeight    string   `protobuf:"bytes,8,opt,name=total_donate_weight,json=totalDonateWeight,proto3" json:"total_donate_weight,omitempty"`
+	TotalWeight          string   `protobuf:"bytes,9,opt,name=total_weight,json=totalWeight,proto3" json:"total_weight,omitempty"`
+	AllocatedWeight      string   `protobuf:"bytes,10,opt,name=allocated_weight,json=allocatedWeight,proto3" json:"allocated_weight,omitempty"`
+	RequestedWeight      string   `protobuf:"bytes,11,opt,name=requested_weight,json=requestedWeight,proto3" json:"requested_weight,omitempty"`
+	FulfilledWeight      string   `protobuf:"bytes,12,opt,name=fulfilled_weight,json=fulfilledWeight,proto3" json:"fulfilled_weight,omitempty"`
+	ShortWeight          string   `protobuf:"bytes,13,opt,name=short_weight,json=shortWeight,proto3" json:"short_weight,omitempty"`
+	ReservationConsumed  bool     `protobuf:"varint,14,opt,name=reservation_consumed,json=reservationConsumed,proto3" json:"reservation_consumed,omitempty"`
+	Unit                 string   `protobuf:"bytes,15,opt,name=unit,proto3" json:"unit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SaleItemResult) Reset()         { *m = SaleItemResult{} }
+func (m *SaleItemResult) String() string { return proto.CompactTextString(m) }
+func (*SaleItemResult) ProtoMessage()    {}
+func (*SaleItemResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_inventory_e9e20ed81d4d0671, []int{9}
+}
+func (m *SaleItemResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SaleItemResult.Unmarshal(m, b)
+}
+func (m *SaleItemResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SaleItemResult.Marshal(b, m, deterministic)
+}
+func (dst *SaleItemResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SaleItemResult.Merge(dst, src)
+}
+func (m *SaleItemResult) XXX_Size() int {
+	return xxx_messageInfo_SaleItemResult.Size(m)
+}
+func (m *SaleItemResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_SaleItemResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SaleItemResult proto.InternalMessageInfo
+
+func (m *SaleItemResult) GetItemId() string {
+	if m != nil {
+		return m.ItemId
+	}
+	return ""
+}
+
+func (m *SaleItemResult) GetBarcode() string {
+	if m != nil {
+		return m.Barcode
+	}
+	return ""
+}
+
+func (m *SaleItemResult) GetSku() string {
+	if m != nil {
+		return m.Sku
+	}
+	return ""
+}
+
+func (m *SaleItemResult) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *SaleItemResult) GetErrorCode() int32 {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return 0
+}
+
+func (m *SaleItemResult) GetTotalSoldWeight() string {
+	if m != nil {
+		return m.TotalSoldWeight
+	}
+	return ""
+}
+
+func (m *SaleItemResult) GetTotalWasteWeight() string {
+	if m != nil {
+		return m.TotalWasteWeight
+	}
+	return ""
+}
+
+func (m *SaleItemResult) GetTotalDonateWeight() string {
+	if m != nil {
+		return m.TotalDonateWeight
+	}
+	return ""
+}
+
+func (m *SaleItemResult) GetTotalWeight() string {
+	if m != nil {
+		return m.TotalWeight
+	}
+	return ""
+}
+
+func (m *SaleItemResult) GetAllocatedWeight() string {
+	if m != nil {
+		return m.AllocatedWeight
+	}
+	return ""
+}
+
+func (m *SaleItemResult) GetRequestedWeight() string {
+	if m != nil {
+		return m.RequestedWeight
+	}
+	return ""
+}
+
+func (m *SaleItemResult) GetFulfilledWeight() string {
+	if m != nil {
+		return m.FulfilledWeight
+	}
+	return ""
+}
+
+func (m *SaleItemResult) GetShortWeight() string {
+	if m != nil {
+		return m.ShortWeight
+	}
+	return ""
+}
+
+func (m *SaleItemResult) GetReservationConsumed() bool {
+	if m != nil {
+		return m.ReservationConsumed
+	}
+	return false
+}
+
+func (m *SaleItemResult) GetUnit() string {
+	if m != nil {
+		return m.Unit
+	}
+	return ""
+}
+
+// SaleResult is the Document-result for sales, waste, and donations.
+type SaleResult struct {
+	OriginalRequest      *SaleCommand      `protobuf:"bytes,1,opt,name=original_request,json=originalRequest,proto3" json:"original_request,omitempty"`
+	Result               []*SaleItemResult `protobuf:"bytes,2,rep,name=result,proto3" json:"result,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *SaleResult) Reset()         { *m = SaleResult{} }
+func (m *SaleResult) String() string { return proto.CompactTextString(m) }
+func (*SaleResult) ProtoMessage()    {}
+func (*SaleResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_inventory_e9e20ed81d4d0671, []int{10}
+}
+func (m *SaleResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SaleResult.Unmarshal(m, b)
+}
+func (m *SaleResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SaleResult.Marshal(b, m, deterministic)
+}
+func (dst *SaleResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SaleResult.Merge(dst, src)
+}
+func (m *SaleResult) XXX_Size() int {
+	return xxx_messageInfo_SaleResult.Size(m)
+}
+func (m *SaleResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_SaleResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SaleResult proto.InternalMessageInfo
+
+func (m *SaleResult) GetOriginalRequest() *SaleCommand {
+	if m != nil {
+		return m.OriginalRequest
+	}
+	return nil
+}
+
+func (m *SaleResult) GetResult() []*SaleItemResult {
+	if m != nil {
+		return m.Result
+	}
+	return nil
+}
+
+// UpdateCommand is the event-data for "update" without a service-action
+// listed above. It sets the fields of update on items matching filter.
+type UpdateCommand struct {
+	// filter matches items by the fields that are set.
+	Filter *Inventory `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	Update *Inventory `protobuf:"bytes,2,opt,name=update,proto3" json:"update,omitempty"`
+	// update_mask names the fields of update that are set, such as
+	// "total_weight". Fields in the mask are set even if blank or zero,
+	// and fields not in the mask are not updated.
+	UpdateMask           *field_mask.FieldMask `protobuf:"bytes,3,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *UpdateCommand) Reset()         { *m = UpdateCommand{} }
+func (m *UpdateCommand) String() string { return proto.CompactTextString(m) }
+func (*UpdateCommand) ProtoMessage()    {}
+func (*UpdateCommand) Descriptor() ([]byte, []int) {
+	return fileDescriptor_inventory_e9e20ed81d4d0671, []int{11}
+}
+func (m *UpdateCommand) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateCommand.Unmarshal(m, b)
+}
+func (m *UpdateCommand) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateCommand.Marshal(b, m, deterministic)
+}
+func (dst *UpdateCommand) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateCommand.Merge(dst, src)
+}
+func (m *UpdateCommand) XXX_Size() int {
+	return xxx_messageInfo_UpdateCommand.Size(m)
+}
+func (m *UpdateCommand) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateCommand.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateCommand proto.InternalMessageInfo
+
+func (m *UpdateCommand) GetFilter() *Inventory {
+	if m != nil {
+		return m.Filter
+	}
+	return nil
+}
+
+func (m *UpdateCommand) GetUpdate() *Inventory {
+	if m != nil {
+		return m.Update
+	}
+	return nil
+}
+
+func (m *UpdateCommand) GetUpdateMask() *field_mask.FieldMask {
+	if m != nil {
+		return m.UpdateMask
+	}
+	return nil
+}
+
+// UpdateResult is the Document-result for UpdateCommand.
+type UpdateResult struct {
+	MatchedCount         int64    `protobuf:"varint,1,opt,name=matched_count,json=matchedCount,proto3" json:"matched_count,omitempty"`
+	ModifiedCount        int64    `protobuf:"varint,2,opt,name=modified_count,json=modifiedCount,proto3" json:"modified_count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateResult) Reset()         { *m = UpdateResult{} }
+func (m *UpdateResult) String() string { return proto.CompactTextString(m) }
+func (*UpdateResult) ProtoMessage()    {}
+func (*UpdateResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_inventory_e9e20ed81d4d0671, []int{12}
+}
+func (m *UpdateResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateResult.Unmarshal(m, b)
+}
+func (m *UpdateResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateResult.Marshal(b, m, deterministic)
+}
+func (dst *UpdateResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateResult.Merge(dst, src)
+}
+func (m *UpdateResult) XXX_Size() int {
+	return xxx_messageInfo_UpdateResult.Size(m)
+}
+func (m *UpdateResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateResult proto.InternalMessageInfo
+
+func (m *UpdateResult) GetMatchedCount() int64 {
+	if m != nil {
+		return m.MatchedCount
+	}
+	return 0
+}
+
+func (m *UpdateResult) GetModifiedCount() int64 {
+	if m != nil {
+		return m.ModifiedCount
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*Inventory)(nil), "inventory.Inventory")
+	proto.RegisterType((*FieldError)(nil), "inventory.FieldError")
+	proto.RegisterType((*ValidationResult)(nil), "inventory.ValidationResult")
+	proto.RegisterType((*InsertCommand)(nil), "inventory.InsertCommand")
+	proto.RegisterType((*BulkInsertItemResult)(nil), "inventory.BulkInsertItemResult")
+	proto.RegisterType((*BulkInsertResult)(nil), "inventory.BulkInsertResult")
+	proto.RegisterType((*InsertResult)(nil), "inventory.InsertResult")
+	proto.RegisterType((*SaleLine)(nil), "inventory.SaleLine")
+	proto.RegisterType((*SaleCommand)(nil), "inventory.SaleCommand")
+	proto.RegisterType((*SaleItemResult)(nil), "inventory.SaleItemResult")
+	proto.RegisterType((*SaleResult)(nil), "inventory.SaleResult")
+	proto.RegisterType((*UpdateCommand)(nil), "inventory.UpdateCommand")
+	proto.RegisterType((*UpdateResult)(nil), "inventory.UpdateResult")
+}
+
+func init() { proto.RegisterFile("inventory.proto", fileDescriptor_inventory_e9e20ed81d4d0671) }
+
+var fileDescriptor_inventory_e9e20ed81d4d0671 = []byte{
+	// 1219 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x4b, 0x6f, 0xdc, 0x36,
+	0x17, 0x85, 0x3c, 0x0f, 0x6b, 0xae, 0xe6, 0x15, 0x7a, 0xe2, 0x28, 0xce, 0xf7, 0x21, 0xee, 0xa4,
+	0x01, 0x9c, 0x20, 0xb0, 0x1b, 0x17, 0x05, 0x0a, 0xb4, 0x9b, 0xc4, 0x69, 0x80, 0x01, 0xd2, 0x8d,
+	0xd2, 0xa6, 0x40, 0x36, 0x82, 0x3c, 0xe2, 0x8c, 0x59, 0x4b, 0xe2, 0x94, 0xa4, 0x9c, 0x74, 0xdb,
+	0xae, 0x0b, 0x14, 0xe8, 0x3f, 0xe8, 0xaa, 0x9b, 0xfe, 0xc3, 0x2e, 0x0a, 0xde, 0x4b, 0x3d, 0xec,
+	0xc4, 0x41, 0x16, 0x5d, 0x0d, 0x79, 0x78, 0x44, 0x9e, 0xfb, 0x1e, 0x98, 0x88, 0xe2, 0x82, 0x17,
+	0x46, 0xaa, 0x9f, 0x0f, 0x37, 0x4a, 0x1a, 0xc9, 0x06, 0x35, 0xb0, 0xb7, 0xbf, 0x96, 0x72, 0x9d,
+	0xf1, 0x23, 0x3c, 0x38, 0x2d, 0x57, 0x47, 0x2b, 0xc1, 0xb3, 0x34, 0xce, 0x13, 0x7d, 0x4e, 0xe4,
+	0xf9, 0x1f, 0x7d, 0x18, 0x2c, 0x2a, 0x3e, 0x1b, 0xc3, 0x96, 0x48, 0x43, 0x6f, 0xdf, 0x3b, 0x18,
+	0x44, 0x5b, 0x22, 0x65, 0xb7, 0x60, 0x5b, 0x18, 0x9e, 0xc7, 0x22, 0x0d, 0xb7, 0x10, 0xec, 0xdb,
+	0xed, 0x22, 0x65, 0x9f, 0xc0, 0x30, 0x4d, 0x0c, 0x8f, 0x13, 0xa5, 0xc4, 0x05, 0x4f, 0xc3, 0xce,
+	0xbe, 0x77, 0xd0, 0x89, 0x02, 0x8b, 0x3d, 0x21, 0x88, 0xdd, 0x81, 0x01, 0x52, 0xb4, 0xcc, 0xd2,
+	0xb0, 0x8b, 0xe7, 0xbe, 0x05, 0x5e, 0xca, 0x8c, 0x0e, 0xf9, 0x85, 0x58, 0x72, 0x7b, 0x75, 0x0f,
+	0xaf, 0xf6, 0x09, 0x58, 0xa4, 0xec, 0x1e, 0x8c, 0x52, 0x59, 0xd8, 0x6f, 0xdf, 0x70, 0xb1, 0x3e,
+	0x33, 0x61, 0x1f, 0x09, 0x43, 0x02, 0x7f, 0x40, 0x8c, 0xed, 0x81, 0x9f, 0xc9, 0x65, 0x62, 0x84,
+	0x2c, 0xc2, 0x6d, 0xba, 0xa0, 0xda, 0xb3, 0x29, 0x74, 0x32, 0x69, 0x42, 0x1f, 0x61, 0xbb, 0x64,
+	0x0c, 0xba, 0x45, 0x92, 0xf3, 0x70, 0x80, 0x10, 0xae, 0xd9, 0x2e, 0xf4, 0xa5, 0x12, 0x6b, 0x51,
+	0x84, 0x40, 0xb6, 0xd1, 0x8e, 0xcd, 0xa0, 0xb7, 0x51, 0x62, 0xc9, 0xc3, 0x00, 0x61, 0xda, 0xb0,
+	0x4f, 0x61, 0xac, 0x74, 0xbc, 0x2c, 0xb5, 0x91, 0x39, 0x57, 0x56, 0xf6, 0x90, 0x54, 0x29, 0x7d,
+	0xe2, 0xc0, 0x45, 0xca, 0x1e, 0xc2, 0x8d, 0x55, 0x96, 0xe8, 0xb3, 0x58, 0x27, 0x59, 0x2d, 0x7f,
+	0x84, 0xc4, 0x09, 0x1e, 0xbc, 0x4c, 0xb2, 0xca, 0x02, 0x06, 0xdd, 0xb5, 0x11, 0x45, 0x38, 0x26,
+	0x4d, 0x76, 0x6d, 0x95, 0xeb, 0xf3, 0x32, 0x9c, 0x90, 0x72, 0x7d, 0x5e, 0xb2, 0xbb, 0x10, 0x58,
+	0x0f, 0x56, 0x77, 0x4d, 0xf1, 0x04, 0x2c, 0xe4, 0xae, 0xf9, 0x1f, 0x0c, 0x8c, 0xc8, 0xb9, 0x36,
+	0x49, 0xbe, 0x09, 0x6f, 0xa0, 0x9f, 0x1b, 0xc0, 0x06, 0xca, 0x48, 0x93, 0x64, 0xd5, 0xf7, 0x0c,
+	0xbf, 0x0f, 0x10, 0x6b, 0x74, 0x94, 0x85, 0x30, 0xe1, 0x0e, 0xe9, 0xb0, 0x6b, 0xab, 0xa3, 0xdc,
+	0x2c, 0xc3, 0x19, 0xe9, 0x28, 0x37, 0x4b, 0x7b, 0xd1, 0x9b, 0x44, 0x37, 0x31, 0xb9, 0x49, 0x17,
+	0x21, 0xe6, 0x2e, 0x9a, 0xc3, 0x48, 0x16, 0x71, 0x63, 0x7f, 0xb8, 0xbb, 0xef, 0x1d, 0xf8, 0x51,
+	0x20, 0x8b, 0xe7, 0x95, 0xe9, 0xd6, 0x8d, 0x9b, 0x44, 0xf1, 0xc2, 0xc4, 0x55, 0x62, 0xdd, 0x22,
+	0x37, 0x12, 0xba, 0xa0, 0xf4, 0xfa, 0x0c, 0x66, 0x2d, 0x37, 0x36, 0xe6, 0x85, 0x68, 0x1e, 0xab,
+	0x3d, 0xf9, 0x5d, 0x6d, 0xe7, 0x7d, 0x18, 0x6f, 0x94, 0xfc, 0x91, 0x2f, 0x0d, 0x4f, 0x63, 0x9b,
+	0x66, 0xe1, 0x6d, 0xe4, 0x8e, 0x6a, 0xf4, 0x59, 0x62, 0xb8, 0xa5, 0xe9, 0xe5, 0x19, 0xcf, 0x93,
+	0xf8, 0x82, 0x2b, 0x6d, 0x73, 0x67, 0x8f, 0x68, 0x84, 0xbe, 0x22, 0x70, 0xfe, 0x35, 0xc0, 0x73,
+	0x5b, 0x29, 0xdf, 0x28, 0x25, 0x95, 0x4d, 0x08, 0xac, 0x1b, 0x57, 0x18, 0xb4, 0x61, 0x21, 0x6c,
+	0xe7, 0x5c, 0xeb, 0x64, 0xcd, 0x5d, 0x6d, 0x54, 0xdb, 0xf9, 0x0b, 0x98, 0xbe, 0x4a, 0x32, 0x91,
+	0x62, 0x32, 0x46, 0x5c, 0x97, 0x99, 0x61, 0x5f, 0xc2, 0x90, 0x6a, 0x8f, 0xdb, 0x2b, 0x75, 0xe8,
+	0xed, 0x77, 0x0e, 0x82, 0xe3, 0x9b, 0x87, 0x4d, 0xf1, 0x36, 0x0f, 0x46, 0xc1, 0xaa, 0x5e, 0xeb,
+	0xf9, 0xaf, 0x1e, 0x8c, 0x16, 0x85, 0xe6, 0xca, 0x9c, 0xc8, 0x3c, 0x4f, 0x8a, 0x94, 0x1d, 0x40,
+	0xd7, 0x3a, 0x0f, 0xe5, 0x04, 0xc7, 0xb3, 0xd6, 0x1d, 0x75, 0x25, 0x47, 0xc8, 0x60, 0x0f, 0xa1,
+	0x67, 0x7f, 0x75, 0xb8, 0xb5, 0xdf, 0xb9, 0x96, 0x4a, 0x14, 0x9b, 0x47, 0x65, 0x21, 0x55, 0xca,
+	0x95, 0xab, 0x67, 0x3f, 0x6a, 0x80, 0xf9, 0x3f, 0x1e, 0xcc, 0x9e, 0x96, 0xd9, 0x39, 0x29, 0xb1,
+	0x61, 0x72, 0x86, 0xcd, 0xa0, 0x27, 0x8a, 0x94, 0xbf, 0x45, 0x35, 0xbd, 0x88, 0x36, 0xd7, 0x37,
+	0x0e, 0xea, 0x30, 0x9d, 0xba, 0xc3, 0xec, 0x81, 0x2f, 0xf0, 0x4a, 0x4e, 0x4d, 0xc2, 0x8f, 0xea,
+	0xbd, 0x3d, 0xe3, 0x6f, 0x85, 0x36, 0xa2, 0x58, 0x63, 0x8f, 0xf0, 0xa3, 0x7a, 0x6f, 0x9f, 0x45,
+	0x4f, 0xba, 0xde, 0x40, 0x1b, 0xf6, 0x7f, 0x00, 0x5c, 0xc4, 0x4b, 0x99, 0x72, 0x6c, 0x0b, 0xbd,
+	0x68, 0x80, 0xc8, 0x89, 0x4c, 0xf9, 0x3b, 0x41, 0xf0, 0x3f, 0x3a, 0x08, 0x7f, 0x7b, 0x30, 0x6d,
+	0xcc, 0x77, 0xa6, 0x87, 0xb0, 0x5d, 0xf9, 0xcb, 0x43, 0x79, 0xd5, 0xd6, 0xa6, 0x59, 0x65, 0x45,
+	0xbc, 0x94, 0x65, 0x61, 0xd0, 0x0b, 0xbd, 0x68, 0x54, 0xa1, 0x27, 0x16, 0xb4, 0x35, 0xb5, 0x4a,
+	0x44, 0x56, 0x93, 0x3a, 0x48, 0x0a, 0x08, 0x23, 0xca, 0x17, 0x55, 0x04, 0xbb, 0xa8, 0xf5, 0x6e,
+	0x4b, 0xeb, 0xfb, 0xc2, 0xe1, 0x82, 0x39, 0x17, 0x30, 0xbc, 0x24, 0xf5, 0xe3, 0x53, 0xe6, 0x08,
+	0xba, 0xa7, 0x65, 0x76, 0x8e, 0x82, 0x83, 0xe3, 0x3b, 0xef, 0x7d, 0xcf, 0xbd, 0x85, 0xc4, 0xf9,
+	0x6f, 0x1e, 0xf8, 0xb6, 0x16, 0x5f, 0x88, 0x82, 0xb7, 0xe3, 0xee, 0x5d, 0x8a, 0x7b, 0x08, 0xdb,
+	0xa7, 0x89, 0xc2, 0xb0, 0xb8, 0x6a, 0x71, 0xdb, 0xaa, 0xe5, 0x75, 0x9a, 0x96, 0xe7, 0xda, 0x77,
+	0xb7, 0x69, 0xdf, 0xbb, 0xd0, 0x77, 0x6d, 0x87, 0x66, 0x45, 0xff, 0xcd, 0xe5, 0xd6, 0xd5, 0x6f,
+	0x5a, 0xd7, 0xfc, 0x77, 0x0f, 0x02, 0xab, 0xa7, 0xaa, 0x96, 0x07, 0x95, 0x07, 0xa9, 0xe4, 0x76,
+	0x5a, 0x16, 0x55, 0xb2, 0xab, 0x12, 0xb8, 0x0f, 0x63, 0xc5, 0x35, 0x57, 0x17, 0x58, 0xb9, 0x4d,
+	0xf2, 0x8e, 0x5a, 0xe8, 0x22, 0x65, 0x47, 0xb0, 0xb3, 0x49, 0x94, 0x11, 0x49, 0x16, 0xaf, 0xca,
+	0x6c, 0x25, 0xb2, 0x2c, 0xe7, 0x2e, 0x7a, 0x7e, 0xc4, 0xdc, 0xd1, 0xf3, 0xe6, 0x64, 0xfe, 0x67,
+	0x17, 0xc6, 0xf6, 0xad, 0x56, 0xd9, 0xfc, 0x27, 0x8e, 0xaa, 0x8b, 0xa0, 0x7b, 0x7d, 0x11, 0xf4,
+	0xae, 0x16, 0xc1, 0x43, 0xb8, 0x41, 0x13, 0xa1, 0x3d, 0x56, 0xc8, 0x81, 0x13, 0x3c, 0x78, 0xd9,
+	0xcc, 0x96, 0x47, 0xc0, 0xdc, 0xf4, 0x68, 0xb7, 0x7e, 0x1a, 0xb7, 0x53, 0x9a, 0x21, 0xad, 0xfe,
+	0x7f, 0x08, 0x3b, 0xc4, 0xbe, 0x3c, 0xbd, 0x69, 0x0c, 0xd3, 0xa3, 0xcf, 0xda, 0x23, 0xfc, 0xea,
+	0x6c, 0x1a, 0xbc, 0x3b, 0x9b, 0x1e, 0xc0, 0x34, 0xc9, 0x70, 0xae, 0xf3, 0x5a, 0x2b, 0x4d, 0xeb,
+	0x49, 0x8d, 0x37, 0x54, 0xc5, 0x7f, 0x2a, 0xb9, 0x6e, 0x51, 0x69, 0x82, 0x4f, 0x6a, 0xbc, 0xa1,
+	0xba, 0xc0, 0x35, 0xd4, 0xa1, 0x1b, 0xd2, 0x15, 0xde, 0x68, 0xd4, 0x67, 0x52, 0x99, 0xcb, 0xb3,
+	0x3c, 0x40, 0xcc, 0x51, 0x1e, 0xc3, 0xac, 0x9d, 0x35, 0x4b, 0x59, 0xe8, 0x32, 0xe7, 0x29, 0xce,
+	0x75, 0x3f, 0xda, 0x69, 0x9d, 0x9d, 0xb8, 0xa3, 0x3a, 0x6f, 0x27, 0xad, 0xbc, 0xfd, 0xc5, 0x03,
+	0xb0, 0x49, 0xe2, 0x12, 0xe4, 0x09, 0x4c, 0xe9, 0xff, 0x48, 0x92, 0xc5, 0x4e, 0xbf, 0xab, 0xde,
+	0xdd, 0x2b, 0x19, 0xec, 0x12, 0x3d, 0x9a, 0x54, 0xfc, 0x88, 0xe8, 0xec, 0x31, 0xf4, 0x15, 0x5e,
+	0xe6, 0xda, 0xff, 0xed, 0x2b, 0x1f, 0xb6, 0xda, 0x86, 0x23, 0xce, 0xff, 0xf2, 0x60, 0xf4, 0xfd,
+	0xc6, 0xce, 0xcf, 0xaa, 0x7c, 0x1e, 0x41, 0x7f, 0x25, 0x32, 0xc3, 0xd5, 0x07, 0x7b, 0x87, 0xe3,
+	0x58, 0x76, 0x89, 0x9f, 0x87, 0x5b, 0x1f, 0x62, 0x13, 0x87, 0x7d, 0x05, 0x01, 0xad, 0xf0, 0x1f,
+	0x29, 0x66, 0x76, 0x70, 0xbc, 0x77, 0x48, 0x7f, 0x5a, 0x0f, 0xab, 0x3f, 0xad, 0xd4, 0x94, 0xbf,
+	0x4d, 0xf4, 0x79, 0x04, 0x44, 0xb7, 0xeb, 0xf9, 0x6b, 0x18, 0x92, 0x52, 0xe7, 0xb0, 0x7b, 0x30,
+	0xca, 0x13, 0xb3, 0x3c, 0xab, 0xbb, 0xa9, 0x87, 0x93, 0x7d, 0xe8, 0x40, 0x6a, 0xa7, 0xf7, 0x61,
+	0x9c, 0xcb, 0x54, 0xac, 0xc4, 0xa5, 0xc6, 0xdc, 0x89, 0x46, 0x15, 0x8a, 0xb4, 0xa7, 0xdd, 0xd7,
+	0x5b, 0x9b, 0xd3, 0xd3, 0x3e, 0x2a, 0xf8, 0xfc, 0xdf, 0x01, 0x00, 0x84, 0x39, 0xa8, 0xed, 0x62,
+	0x0b, 0x00, 0x00,
+}
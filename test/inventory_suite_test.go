@@ -26,6 +26,24 @@ func Byf(s string, args ...interface{}) {
 	By(fmt.Sprintf(s, args...))
 }
 
+// decimalInt returns the Decimal for test-values known to be in range.
+func decimalInt(i int64) inventory.Decimal {
+	d, err := inventory.DecimalFromInt(i)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// decimalFloat returns the Decimal for test-values known to be in range.
+func decimalFloat(f float64) inventory.Decimal {
+	d, err := inventory.DecimalFromFloat(f)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
 func TestInventory(t *testing.T) {
 	log.Println("Reading environment file")
 	err := godotenv.Load("../.env")
@@ -99,20 +117,23 @@ var _ = Describe("InventoryAggregate", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		mockInv = &inventory.Inventory{
-			ItemID:          itemID,
-			DateArrived:     time.Now().Unix(),
-			DeviceID:        deviceID,
-			Lot:             "test-lot",
-			Name:            "test-name",
-			Origin:          "test-origin",
-			Price:           13.4,
-			RSCustomerID:    rsCustomerID,
-			FlashSaleWeight: 0,
-			SKU:             "test-sku",
-			Timestamp:       time.Now().Unix(),
-			TotalWeight:     300,
-			UPC:             "test-upc",
-			WasteWeight:     12,
+			ItemID:       itemID,
+			DateArrived:  time.Now().Unix(),
+			DeviceID:     deviceID,
+			Lot:          "test-lot",
+			Name:         "test-name",
+			Origin:       "test-origin",
+			Price:        decimalFloat(13.4),
+			RSCustomerID: rsCustomerID,
+			GTIN:         "00036000291452",
+			SKU:          "test-sku",
+			Timestamp:    time.Now().Unix(),
+			TotalWeight:  decimalInt(300),
+			Unit:         inventory.Kilogram,
+			UPC:          "036000291452",
+			WasteWeight:  decimalInt(12),
+			// Inserted and decoded items always carry the current schema-version
+			SchemaVersion: inventory.CurrentSchemaVersion,
 		}
 		marshalInv, err := json.Marshal(mockInv)
 		Expect(err).ToNot(HaveOccurred())
@@ -200,7 +221,7 @@ var _ = Describe("InventoryAggregate", func() {
 				"items": []map[string]interface{}{
 					map[string]interface{}{
 						"weight": 12.24,
-						"upc":    "test-upc",
+						"upc":    "036000291452",
 						"itemID": mockInv.ItemID,
 						"lot":    "test-lot",
 						"sku":    "test-sku",
@@ -265,13 +286,77 @@ var _ = Describe("InventoryAggregate", func() {
 			consumer.Consume(context.Background(), handler)
 		})
 
+		It("should bulk-insert records and reject existing itemIDs", func(done Done) {
+			Byf("Creating bulk-insert Event")
+			items := []*inventory.Inventory{}
+			for i := 0; i < 2; i++ {
+				itemID, err := uuuid.NewV4()
+				Expect(err).ToNot(HaveOccurred())
+				inv := *mockInv
+				inv.ID = objectid.NilObjectID
+				inv.ItemID = itemID
+				items = append(items, &inv)
+			}
+			// Duplicate of already inserted item
+			items = append(items, mockInv)
+
+			marshalBulk, err := json.Marshal(map[string]interface{}{
+				"items":   items,
+				"ordered": false,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			uuid, err := uuuid.NewV4()
+			Expect(err).ToNot(HaveOccurred())
+			bulkEvent := *mockEvent
+			bulkEvent.Data = marshalBulk
+			bulkEvent.UUID = uuid
+
+			marshalEvent, err := json.Marshal(bulkEvent)
+			Expect(err).ToNot(HaveOccurred())
+			producer.Input() <- kafka.CreateMessage(eventsTopic, marshalEvent)
+
+			Byf("Consuming Result")
+			c, err := kafka.NewConsumer(&kafka.ConsumerConfig{
+				KafkaBrokers: kafkaBrokers,
+				GroupName:    "agginv.test.group.bulk",
+				Topics:       []string{producerResponseTopic},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			msgCallback := func(msg *sarama.ConsumerMessage) bool {
+				defer GinkgoRecover()
+				kr := &model.Document{}
+				err := json.Unmarshal(msg.Value, kr)
+				Expect(err).ToNot(HaveOccurred())
+
+				if kr.UUID == bulkEvent.UUID {
+					Expect(kr.Error).To(BeEmpty())
+					Expect(kr.ErrorCode).To(BeZero())
+
+					bulkResult := &inventory.BulkInsertResult{}
+					err = json.Unmarshal(kr.Result, bulkResult)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(bulkResult.InsertedCount).To(Equal(2))
+					Expect(bulkResult.FailedCount).To(Equal(1))
+					Expect(bulkResult.Items[2].ItemID).To(Equal(mockInv.ItemID.String()))
+					Expect(bulkResult.Items[2].Inserted).To(BeFalse())
+					Expect(bulkResult.Items[2].ErrorCode).To(Equal(inventory.Conflict))
+					return true
+				}
+				return false
+			}
+
+			handler := &msgHandler{msgCallback}
+			c.Consume(context.Background(), handler)
+			close(done)
+		}, 20)
+
 		It("should update record", func(done Done) {
 			Byf("Creating update args")
 			filterInv := map[string]interface{}{
 				"itemID": mockInv.ItemID,
 			}
 			mockInv.Origin = "new-origin"
-			mockInv.Price = 500
+			mockInv.Price = decimalInt(500)
 			// Remove ObjectID because this is not passed from gateway
 			mockID := mockInv.ID
 			mockInv.ID = objectid.NilObjectID